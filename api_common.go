@@ -33,6 +33,8 @@ type CursorListApiOptions struct {
 	ExtraParams map[string]string
 	// For multivalue params: {"key2[]": ["val2", "val3"]}
 	MultiValueParams map[string][]string
+	// used only by All* iterators: fetch next page concurrently while current page is being iterated
+	Prefetch bool
 }
 
 func (o *CursorListApiOptions) BuildQuery() string {
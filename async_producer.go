@@ -0,0 +1,390 @@
+package suprsend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// default max time a record waits in producer's buffer before its batch is flushed
+	ASYNC_PRODUCER_DEFAULT_LINGER = 1 * time.Second
+	// default max records that can wait in producer's buffer before Send* calls start blocking
+	ASYNC_PRODUCER_DEFAULT_BUFFER_SIZE = 10000
+)
+
+type AsyncProducerOptions struct {
+	// max time a record waits before the batch containing it is sent. Default: 1s
+	Linger time.Duration
+	// max records buffered in memory. When buffer is full, Send* calls block (backpressure). Default: 10000
+	BufferSize int
+	// if true, successfully sent messages are delivered on Successes() channel.
+	// Caller must consume the channel, otherwise producer gets blocked.
+	ReturnSuccesses bool
+	// if true, failed messages are delivered on Errors() channel.
+	// Caller must consume the channel, otherwise producer gets blocked.
+	ReturnErrors bool
+	// called (from producer's goroutine) for every successfully sent message
+	OnSuccess func(*ProducerMessage)
+	// called (from producer's goroutine) for every failed message
+	OnError func(*ProducerError)
+}
+
+func (o *AsyncProducerOptions) cleanParams() {
+	if o.Linger <= 0 {
+		o.Linger = ASYNC_PRODUCER_DEFAULT_LINGER
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = ASYNC_PRODUCER_DEFAULT_BUFFER_SIZE
+	}
+}
+
+// ProducerMessage wraps a record sent via AsyncProducer. Exactly one of Workflow/Event is set.
+type ProducerMessage struct {
+	Workflow *WorkflowTriggerRequest
+	Event    *Event
+	// set once the message has been sent
+	StatusCode int
+	MessageId  string
+}
+
+type ProducerError struct {
+	Msg *ProducerMessage
+	Err error
+}
+
+func (pe *ProducerError) Error() string {
+	return fmt.Sprintf("suprsend: failed to send message: %v", pe.Err)
+}
+
+func (pe *ProducerError) Unwrap() error {
+	return pe.Err
+}
+
+/*
+AsyncProducer batches workflow triggers and events sent from any number of goroutines
+and sends them using bulk apis. A batch is sent when it reaches max records/apparent-size
+allowed in a bulk api call, or when Linger duration has passed.
+*/
+type AsyncProducer interface {
+	// validates the workflow and adds it to buffer. Blocks if buffer is full.
+	SendWorkflow(context.Context, *WorkflowTriggerRequest) error
+	// validates the event and adds it to buffer. Blocks if buffer is full.
+	SendEvent(context.Context, *Event) error
+	// channel of successfully sent messages. Enabled by AsyncProducerOptions.ReturnSuccesses
+	Successes() <-chan *ProducerMessage
+	// channel of failed messages. Enabled by AsyncProducerOptions.ReturnErrors
+	Errors() <-chan *ProducerError
+	// sends all messages buffered so far and waits till their results are delivered.
+	Flush(context.Context) error
+	/*
+		flushes all buffered messages and stops the producer. Result channels are closed after that.
+		If ctx is done before buffered messages are sent, in-flight and remaining sends are cancelled
+		(their messages are delivered as errors) and ctx's error is returned.
+	*/
+	Close(context.Context) error
+}
+
+var _ AsyncProducer = &asyncProducer{}
+
+func (c *Client) NewAsyncProducer(opts *AsyncProducerOptions) (AsyncProducer, error) {
+	if opts == nil {
+		opts = &AsyncProducerOptions{}
+	}
+	optsCopy := *opts
+	optsCopy.cleanParams()
+	p := &asyncProducer{
+		client:  c,
+		opts:    optsCopy,
+		input:   make(chan *producerInput, optsCopy.BufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	if optsCopy.ReturnSuccesses {
+		p.successes = make(chan *ProducerMessage, optsCopy.BufferSize)
+	}
+	if optsCopy.ReturnErrors {
+		p.errors = make(chan *ProducerError, optsCopy.BufferSize)
+	}
+	p.workflowBatch = &producerBatch{
+		kind: producerKindWorkflow, maxRecords: c.bulkLimits.MaxWorkflowsInBulk,
+		maxSize: c.bulkLimits.MaxApparentSizeInBytes,
+	}
+	p.eventBatch = &producerBatch{
+		kind: producerKindEvent, maxRecords: c.bulkLimits.MaxEventsInBulk,
+		maxSize: c.bulkLimits.MaxApparentSizeInBytes,
+	}
+	go p.run()
+	return p, nil
+}
+
+const (
+	producerKindWorkflow = "workflow"
+	producerKindEvent    = "event"
+)
+
+type producerInput struct {
+	msg    *ProducerMessage
+	record pendingBulkRecord
+	// set for flush marker. closed once everything received before the marker has been sent
+	flushed chan struct{}
+}
+
+type producerBatch struct {
+	kind       string
+	maxRecords int
+	maxSize    int
+	//
+	msgs        []*ProducerMessage
+	records     []pendingBulkRecord
+	runningSize int
+}
+
+func (b *producerBatch) canAdd(recordSize int) bool {
+	if len(b.records) == 0 {
+		return true
+	}
+	return len(b.records) < b.maxRecords && (b.runningSize+recordSize) <= b.maxSize
+}
+
+func (b *producerBatch) add(in *producerInput) {
+	b.runningSize += in.record.recordSize
+	b.msgs = append(b.msgs, in.msg)
+	b.records = append(b.records, in.record)
+}
+
+func (b *producerBatch) isFull() bool {
+	return len(b.records) >= b.maxRecords || b.runningSize >= b.maxSize
+}
+
+func (b *producerBatch) reset() {
+	b.msgs, b.records, b.runningSize = nil, nil, 0
+}
+
+type asyncProducer struct {
+	client *Client
+	opts   AsyncProducerOptions
+	//
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	input     chan *producerInput
+	// closed when Close is called, wakes up senders blocked on full buffer
+	closing chan struct{}
+	done    chan struct{}
+	// context of bulk api calls, cancelled when Close gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+	//
+	successes chan *ProducerMessage
+	errors    chan *ProducerError
+	// owned by run() goroutine
+	workflowBatch *producerBatch
+	eventBatch    *producerBatch
+}
+
+func (p *asyncProducer) SendWorkflow(ctx context.Context, wf *WorkflowTriggerRequest) error {
+	if wf == nil {
+		return &Error{Message: "missing workflow"}
+	}
+	wfJson, bodySize, encoded, err := wf.getFinalJson(p.client, true)
+	if err != nil {
+		return err
+	}
+	rec := pendingBulkRecord{record: wfJson, recordSize: bodySize, encoded: encoded}
+	if err = p.client.uploadRecordAttachments(ctx, &rec, "data", nil); err != nil {
+		return err
+	}
+	in := &producerInput{
+		msg:    &ProducerMessage{Workflow: wf},
+		record: rec,
+	}
+	return p.enqueue(ctx, in)
+}
+
+func (p *asyncProducer) SendEvent(ctx context.Context, ev *Event) error {
+	if ev == nil {
+		return &Error{Message: "missing event"}
+	}
+	evJson, bodySize, encoded, err := ev.getFinalJson(p.client, true)
+	if err != nil {
+		return err
+	}
+	rec := pendingBulkRecord{record: evJson, recordSize: bodySize, encoded: encoded}
+	if err = p.client.uploadRecordAttachments(ctx, &rec, "properties", nil); err != nil {
+		return err
+	}
+	in := &producerInput{
+		msg:    &ProducerMessage{Event: ev},
+		record: rec,
+	}
+	return p.enqueue(ctx, in)
+}
+
+func (p *asyncProducer) enqueue(ctx context.Context, in *producerInput) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+	select {
+	case p.input <- in:
+		return nil
+	case <-p.closing:
+		// read lock must be released for Close to proceed
+		return ErrProducerClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *asyncProducer) Successes() <-chan *ProducerMessage {
+	return p.successes
+}
+
+func (p *asyncProducer) Errors() <-chan *ProducerError {
+	return p.errors
+}
+
+func (p *asyncProducer) Flush(ctx context.Context) error {
+	marker := &producerInput{flushed: make(chan struct{})}
+	err := p.enqueue(ctx, marker)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-marker.flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *asyncProducer) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+		p.mu.Lock()
+		p.closed = true
+		close(p.input)
+		p.mu.Unlock()
+	})
+	// wait till all buffered messages are sent
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+func (p *asyncProducer) run() {
+	defer func() {
+		p.cancel()
+		if p.successes != nil {
+			close(p.successes)
+		}
+		if p.errors != nil {
+			close(p.errors)
+		}
+		close(p.done)
+	}()
+	var lingerC <-chan time.Time
+	for {
+		select {
+		case in, ok := <-p.input:
+			if !ok {
+				p.flushAll()
+				return
+			}
+			if in.flushed != nil {
+				p.flushAll()
+				lingerC = nil
+				close(in.flushed)
+				continue
+			}
+			batch := p.workflowBatch
+			if in.msg.Event != nil {
+				batch = p.eventBatch
+			}
+			if !batch.canAdd(in.record.recordSize) {
+				p.flush(batch)
+			}
+			batch.add(in)
+			if batch.isFull() {
+				p.flush(batch)
+			}
+			// start linger timer when first record gets buffered
+			if lingerC == nil && p.hasPending() {
+				lingerC = time.After(p.opts.Linger)
+			}
+		case <-lingerC:
+			p.flushAll()
+			lingerC = nil
+		}
+	}
+}
+
+func (p *asyncProducer) hasPending() bool {
+	return len(p.workflowBatch.records) > 0 || len(p.eventBatch.records) > 0
+}
+
+func (p *asyncProducer) flushAll() {
+	p.flush(p.workflowBatch)
+	p.flush(p.eventBatch)
+}
+
+func (p *asyncProducer) flush(batch *producerBatch) {
+	if len(batch.records) == 0 {
+		return
+	}
+	if p.client.debug {
+		log.Printf("DEBUG: async producer sending %s batch of %d records", batch.kind, len(batch.records))
+	}
+	var ch *bulkChunk
+	if batch.kind == producerKindWorkflow {
+		ch = workflowTriggersBulkKind.newChunk(p.client)
+	} else {
+		ch = eventsBulkKind.newChunk(p.client)
+	}
+	for i, rec := range batch.records {
+		// record index in the chunk is its index in batch
+		rec.index = i
+		ch.addToChunk(rec)
+	}
+	triggerChunkWithSplit(p.ctx, ch, func(resp *chunkResponse) {
+		p.deliver(batch, resp)
+	})
+	batch.reset()
+}
+
+func (p *asyncProducer) deliver(batch *producerBatch, resp *chunkResponse) {
+	// record index in the chunk is same as message index in batch
+	for _, r := range resp.results {
+		if r.Index < 0 || r.Index >= len(batch.msgs) {
+			continue
+		}
+		msg := batch.msgs[r.Index]
+		msg.StatusCode, msg.MessageId = r.StatusCode, r.MessageId
+		if r.Status != "success" {
+			pe := &ProducerError{Msg: msg, Err: &Error{Code: r.StatusCode, Message: r.Error}}
+			if p.opts.OnError != nil {
+				p.opts.OnError(pe)
+			}
+			if p.errors != nil {
+				p.errors <- pe
+			}
+		} else {
+			if p.opts.OnSuccess != nil {
+				p.opts.OnSuccess(msg)
+			}
+			if p.successes != nil {
+				p.successes <- msg
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncProducerBatchesRecordsFromGoroutines(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL, WithBulkLimits(BulkLimits{MaxEventsInBulk: 10}))
+	p, err := c.NewAsyncProducer(&AsyncProducerOptions{Linger: time.Hour, ReturnSuccesses: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 5; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				if err := p.SendEvent(context.Background(), testEvent(fmt.Sprintf("u-%d-%d", g, i), nil)); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err = p.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	successes := 0
+	for msg := range p.Successes() {
+		if msg.MessageId == "" || msg.Event == nil {
+			t.Errorf("success message without message id/event: %+v", msg)
+		}
+		successes++
+	}
+	if successes != 25 {
+		t.Errorf("successes = %d, want 25", successes)
+	}
+	calls := ts.requestsTo("/v2/bulk/event/")
+	// 25 records with at most 10 per call
+	if len(calls) != 3 {
+		t.Errorf("bulk calls = %d, want 3", len(calls))
+	}
+	for _, call := range calls {
+		if n := len(call.jsonBody(t).([]any)); n > 10 {
+			t.Errorf("bulk call has %d records, max is 10", n)
+		}
+	}
+}
+
+func TestAsyncProducerSendsAfterLinger(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	sent := make(chan *ProducerMessage, 2)
+	p, _ := c.NewAsyncProducer(&AsyncProducerOptions{
+		Linger:    20 * time.Millisecond,
+		OnSuccess: func(m *ProducerMessage) { sent <- m },
+	})
+	defer p.Close(context.Background())
+	if err := p.SendWorkflow(context.Background(), testWorkflow("wf", "u1")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-sent:
+		if msg.Workflow == nil || msg.StatusCode != 202 {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch was not sent after linger")
+	}
+	if n := len(ts.requestsTo("/trigger/")); n != 1 {
+		t.Errorf("trigger calls = %d, want 1", n)
+	}
+}
+
+func TestAsyncProducerDeliversRecordErrors(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		var records []map[string]any
+		json.Unmarshal(body, &records)
+		results := []map[string]any{}
+		for _, rec := range records {
+			if rec["distinct_id"] == "bad" {
+				results = append(results, map[string]any{"status": "error", "status_code": 400,
+					"error": map[string]any{"message": "invalid", "type": "bad_request"}})
+			} else {
+				results = append(results, map[string]any{"status": "success", "status_code": 202, "message_id": "m"})
+			}
+		}
+		writeJson(w, 207, map[string]any{"status": "partial", "records": results})
+	})
+	c := newTestClient(t, ts.URL)
+	p, _ := c.NewAsyncProducer(&AsyncProducerOptions{ReturnErrors: true})
+	p.SendEvent(context.Background(), testEvent("good", nil))
+	p.SendEvent(context.Background(), testEvent("bad", nil))
+	p.Close(context.Background())
+	var errs []*ProducerError
+	for pe := range p.Errors() {
+		errs = append(errs, pe)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors = %d, want 1", len(errs))
+	}
+	if errs[0].Msg.Event.DistinctId != "bad" || errs[0].Msg.StatusCode != 400 {
+		t.Errorf("unexpected error %+v, msg %+v", errs[0], errs[0].Msg)
+	}
+}
+
+func TestAsyncProducerRejectsAfterClose(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	p, _ := c.NewAsyncProducer(nil)
+	p.Close(context.Background())
+	if err := p.SendEvent(context.Background(), testEvent("u1", nil)); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("SendEvent after Close = %v, want ErrProducerClosed", err)
+	}
+	// second close must not block or panic
+	p.Close(context.Background())
+}
+
+func TestAsyncProducerRejectsInvalidRecord(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	p, _ := c.NewAsyncProducer(nil)
+	defer p.Close(context.Background())
+	if err := p.SendEvent(context.Background(), &Event{DistinctId: "u1"}); err == nil {
+		t.Error("event without name must be rejected by SendEvent")
+	}
+}
+
+func TestAsyncProducerCloseDoesNotWaitForBlockedSenders(t *testing.T) {
+	inFlight := make(chan struct{}, 1)
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		inFlight <- struct{}{}
+		// hangs till client gives up
+		<-r.Context().Done()
+	})
+	c := newTestClient(t, ts.URL, WithBulkLimits(BulkLimits{MaxEventsInBulk: 1}))
+	var failed atomic.Int32
+	p, _ := c.NewAsyncProducer(&AsyncProducerOptions{
+		Linger: time.Hour, BufferSize: 1,
+		OnError: func(*ProducerError) { failed.Add(1) },
+	})
+	// first event is in flight, second fills buffer, third blocks
+	p.SendEvent(context.Background(), testEvent("u1", nil))
+	<-inFlight
+	p.SendEvent(context.Background(), testEvent("u2", nil))
+	blockedErr := make(chan error, 1)
+	go func() { blockedErr <- p.SendEvent(context.Background(), testEvent("u3", nil)) }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- p.Close(ctx) }()
+	select {
+	case err := <-blockedErr:
+		if !errors.Is(err, ErrProducerClosed) {
+			t.Errorf("blocked SendEvent = %v, want ErrProducerClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked sender not released by Close")
+	}
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Close = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after its ctx was done")
+	}
+	// in-flight and buffered messages are delivered as errors
+	if n := failed.Load(); n != 2 {
+		t.Errorf("failed messages = %d, want 2", n)
+	}
+}
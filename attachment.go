@@ -1,7 +1,10 @@
 package suprsend
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,20 +18,30 @@ type AttachmentOption struct {
 	FileName string
 	// ignore attachment if there is issue while accessing/downloading attachment from url
 	// applicable when filepath is a url.
+	// Also applies to attachment violating Policy: it's skipped with a warning instead of returning error
 	IgnoreIfError bool
+	// checked on attachment in addition to client's attachment policy (see WithAttachmentPolicy)
+	Policy *AttachmentPolicy
 }
 
 func GetAttachmentJson(filePath string, ao *AttachmentOption) (map[string]any, error) {
 	fileName, ignoreIfError := "", false
+	var policy *AttachmentPolicy
 	if ao != nil {
-		fileName, ignoreIfError = ao.FileName, ao.IgnoreIfError
+		fileName, ignoreIfError, policy = ao.FileName, ao.IgnoreIfError, ao.Policy
 	}
 	//
+	var attachment map[string]any
+	var err error
 	if checkIsUrl(filePath) {
-		return getAttachmentJsonForUrl(filePath, fileName, ignoreIfError)
+		attachment, err = getAttachmentJsonForUrl(filePath, fileName, ignoreIfError)
 	} else {
-		return getAttachmentJsonForFile(filePath, fileName, ignoreIfError)
+		attachment, err = getAttachmentJsonForFile(filePath, fileName, ignoreIfError, policy)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return policy.apply(attachment, 0)
 }
 
 func checkIsUrl(filePath string) bool {
@@ -48,7 +61,8 @@ func getAttachmentJsonForUrl(fileUrl string, fileName string, ignoreIfError bool
 	}, nil
 }
 
-func getAttachmentJsonForFile(filePath string, fileName string, ignoreIfError bool) (map[string]any, error) {
+func getAttachmentJsonForFile(filePath string, fileName string, ignoreIfError bool, policy *AttachmentPolicy,
+) (map[string]any, error) {
 	// Get absolute path
 	absPath, err := expandHomeDir(filePath)
 	if err != nil {
@@ -65,7 +79,7 @@ func getAttachmentJsonForFile(filePath string, fileName string, ignoreIfError bo
 		finalFileName = fileName
 	}
 	// extract content and mime-type
-	content, err := os.ReadFile(absPath)
+	f, err := os.Open(absPath)
 	if err != nil {
 		if ignoreIfError {
 			log.Println("WARNING: ignoring error while processing attachment file.", err)
@@ -73,17 +87,100 @@ func getAttachmentJsonForFile(filePath string, fileName string, ignoreIfError bo
 		}
 		return nil, &Error{Err: err}
 	}
-	b64Str := base64.StdEncoding.EncodeToString(content)
-	mimeType := mimetype.Detect(content).String()
+	defer f.Close()
+	sizeHint := 0
+	if fi, err := f.Stat(); err == nil {
+		sizeHint = int(fi.Size())
+	}
+	return getAttachmentJsonForReader(finalFileName, "", f, sizeHint, ignoreIfError, policy)
+}
+
+/*
+GetAttachmentJsonFromReader returns attachment json for content read from r (e.g. a file generated in memory).
+Content is base64-encoded while it's being read, so it's never held both raw and encoded.
+contentType is detected from content if not passed. ao.FileName (if set) overrides fileName.
+*/
+func GetAttachmentJsonFromReader(fileName string, contentType string, r io.Reader, ao *AttachmentOption) (map[string]any, error) {
+	ignoreIfError := false
+	var policy *AttachmentPolicy
+	if ao != nil {
+		ignoreIfError, policy = ao.IgnoreIfError, ao.Policy
+		if strings.TrimSpace(ao.FileName) != "" {
+			fileName = ao.FileName
+		}
+	}
+	fileName = strings.TrimSpace(fileName)
+	if fileName == "" {
+		return nil, &Error{Message: "attachment filename missing"}
+	}
+	if r == nil {
+		return nil, &Error{Message: "attachment reader missing"}
+	}
+	attachment, err := getAttachmentJsonForReader(fileName, contentType, r, 0, ignoreIfError, policy)
+	if err != nil {
+		return nil, err
+	}
+	return policy.apply(attachment, 0)
+}
+
+// GetAttachmentJsonFromBytes returns attachment json for in-memory content. See GetAttachmentJsonFromReader
+func GetAttachmentJsonFromBytes(fileName string, contentType string, content []byte, ao *AttachmentOption) (map[string]any, error) {
+	if content == nil {
+		return nil, &Error{Message: "attachment content missing"}
+	}
+	return GetAttachmentJsonFromReader(fileName, contentType, bytes.NewReader(content), ao)
+}
+
+/*
+sizeHint (if known) is size of content in bytes, used to allocate encoded string upfront.
+If policy limits file size, reading stops just after the limit, so that a large file isn't read fully only to be rejected.
+*/
+func getAttachmentJsonForReader(fileName string, contentType string, r io.Reader, sizeHint int, ignoreIfError bool,
+	policy *AttachmentPolicy,
+) (map[string]any, error) {
+	if policy != nil && policy.MaxFileBytes > 0 {
+		r = io.LimitReader(r, int64(policy.MaxFileBytes)+1)
+		sizeHint = min(sizeHint, policy.MaxFileBytes+1)
+	}
+	// only the head of content is needed to detect mime-type
+	head := make([]byte, ATTACHMENT_MIME_DETECTION_READ_LIMIT)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return attachmentReadError(err, ignoreIfError)
+	}
+	head = head[:n]
+	contentType = strings.TrimSpace(contentType)
+	if contentType == "" {
+		contentType = mimetype.Detect(head).String()
+	}
+	// stream rest of the content through base64 encoder
+	var b64 strings.Builder
+	if sizeHint > 0 {
+		b64.Grow(base64.StdEncoding.EncodedLen(sizeHint))
+	}
+	encoder := base64.NewEncoder(base64.StdEncoding, &b64)
+	encoder.Write(head)
+	if _, err = io.Copy(encoder, r); err != nil {
+		return attachmentReadError(err, ignoreIfError)
+	}
+	encoder.Close()
 	//
 	return map[string]any{
-		"filename":        finalFileName,
-		"contentType":     mimeType,
-		"data":            b64Str,
+		"filename":        fileName,
+		"contentType":     contentType,
+		"data":            b64.String(),
 		"ignore_if_error": ignoreIfError,
 	}, nil
 }
 
+func attachmentReadError(err error, ignoreIfError bool) (map[string]any, error) {
+	if ignoreIfError {
+		log.Println("WARNING: ignoring error while processing attachment file.", err)
+		return nil, nil
+	}
+	return nil, &Error{Err: err}
+}
+
 // expandHomeDir expands file paths relative to the user's home directory (~) into absolute paths.
 func expandHomeDir(path string) (string, error) {
 	if !strings.HasPrefix(path, "~") {
@@ -0,0 +1,80 @@
+package suprsend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+)
+
+// Attachment content which is present in more than one record of a bulk instance
+type SharedAttachment struct {
+	FileName string
+	// sha256 (hex) of attachment content
+	Hash        string
+	SizeInBytes int
+	// index of each record which has this attachment, in the order it was appended
+	RecordIndexes []int
+}
+
+// identifies attachment content. Same content always has same base64 string, so it's hashed as-is
+func attachmentContentHash(b64Data string) string {
+	h := sha256.Sum256([]byte(b64Data))
+	return hex.EncodeToString(h[:])
+}
+
+// returns inline attachments whose content is present in more than one record, in order of first occurrence
+func (k *bulkRecordKind[T]) sharedAttachments(records []pendingBulkRecord) []SharedAttachment {
+	if k.attachmentsKey == "" {
+		return nil
+	}
+	byHash := map[string]*SharedAttachment{}
+	order := []string{}
+	for _, rec := range records {
+		d, _ := rec.record[k.attachmentsKey].(map[string]any)
+		attachs, _ := d["$attachments"].([]map[string]any)
+		for _, a := range attachs {
+			b64Data, isInline := a["data"].(string)
+			if !isInline {
+				continue
+			}
+			contentHash := attachmentContentHash(b64Data)
+			sa, found := byHash[contentHash]
+			if !found {
+				fileName, _ := a["filename"].(string)
+				sa = &SharedAttachment{FileName: fileName, Hash: contentHash, SizeInBytes: inlineAttachmentSize(b64Data)}
+				byHash[contentHash] = sa
+				order = append(order, contentHash)
+			}
+			// same content attached twice in a record is counted once
+			if !slices.Contains(sa.RecordIndexes, rec.index) {
+				sa.RecordIndexes = append(sa.RecordIndexes, rec.index)
+			}
+		}
+	}
+	shared := []SharedAttachment{}
+	for _, contentHash := range order {
+		if sa := byHash[contentHash]; len(sa.RecordIndexes) > 1 {
+			shared = append(shared, *sa)
+		}
+	}
+	return shared
+}
+
+/*
+Finds attachments shared by records to be sent. In upload mode, shared content is uploaded only once
+(see uploadAttachments), otherwise it has to be sent inline with every record, so a warning is added.
+*/
+func (e *bulkEngine[T]) checkSharedAttachments() []SharedAttachment {
+	shared := e.kind.sharedAttachments(e.pendingRecords)
+	if e.client.attachmentUploadEnabled() {
+		return shared
+	}
+	for _, sa := range shared {
+		e.response.Warnings = append(e.response.Warnings, fmt.Sprintf(
+			"attachment '%s' (%d bytes) is present in %d records, its content is sent with every record. "+
+				"Enable attachment upload (WithAttachmentUpload) to upload it only once",
+			sa.FileName, sa.SizeInBytes, len(sa.RecordIndexes)))
+	}
+	return shared
+}
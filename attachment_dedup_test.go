@@ -0,0 +1,72 @@
+package suprsend
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func appendEventsWithAttachments(b BulkEvents) {
+	contents := []string{"shared terms", "own content", "shared terms", "shared terms"}
+	for i, content := range contents {
+		ev := testEvent("u"+itoa(i), nil)
+		ev.AddAttachmentBytes("terms.txt", "text/plain", []byte(content), nil)
+		if i == 3 {
+			// same content twice in one record is counted once for it
+			ev.AddAttachmentBytes("copy.txt", "text/plain", []byte(content), nil)
+		}
+		b.Append(ev)
+	}
+}
+
+func TestBulkSharedAttachments(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ClientOption
+		wantBytes int
+	}{
+		// shared content (12 bytes) is sent with each of its 4 attachments + own content (11 bytes)
+		{"inline", nil, 12*4 + 11},
+		// shared content is uploaded once
+		{"upload mode", []ClientOption{WithAttachmentUpload(AttachmentUploadOptions{PresignPath: "v1/presign/"})}, 12 + 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, "http://localhost/", tt.opts...)
+			bulkIns := c.BulkEvents.NewInstance()
+			appendEventsWithAttachments(bulkIns)
+			plan := bulkIns.Plan()
+			if len(plan.SharedAttachments) != 1 {
+				t.Fatalf("shared attachments = %+v", plan.SharedAttachments)
+			}
+			sa := plan.SharedAttachments[0]
+			if sa.FileName != "terms.txt" || sa.SizeInBytes != 12 || !slices.Equal(sa.RecordIndexes, []int{0, 2, 3}) ||
+				sa.Hash != attachmentContentHash(base64.StdEncoding.EncodeToString([]byte("shared terms"))) {
+				t.Errorf("shared attachment = %+v", sa)
+			}
+			if plan.AttachmentBytes != tt.wantBytes {
+				t.Errorf("attachment bytes = %d, want %d", plan.AttachmentBytes, tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestBulkSharedAttachmentsWarning(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	bulkIns := c.BulkEvents.NewInstance()
+	appendEventsWithAttachments(bulkIns)
+	resp, _ := bulkIns.Trigger()
+	if resp.Success != 4 || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "'terms.txt' (12 bytes) is present in 3 records") {
+		t.Errorf("unexpected response %v, warnings %v", resp, resp.Warnings)
+	}
+	// content is still sent inline with every record
+	for _, rec := range ts.Requests()[0].jsonBody(t).([]any) {
+		for _, a := range sentAttachments(t, rec.(map[string]any)) {
+			if a.(map[string]any)["data"] == nil {
+				t.Error("inline attachment content missing")
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package suprsend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"maps"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+/*
+AttachmentPolicy restricts attachments which can be sent. Set it client-wide via WithAttachmentPolicy,
+or per attachment via AttachmentOption.Policy. Size and mime-type checks apply to attachment content
+(inline/base64 attachments), mime-type is detected from content irrespective of passed contentType.
+Attachment with IgnoreIfError is skipped (with a warning) on violation, otherwise *AttachmentPolicyError is returned.
+*/
+type AttachmentPolicy struct {
+	// max size of a single file in bytes. 0: no limit
+	MaxFileBytes int
+	// max size of all attachments of a request in bytes. 0: no limit
+	MaxTotalBytes int
+	// if non-empty, only these mime-types are allowed. Supports wildcard subtype e.g "image/*"
+	AllowedMimeTypes []string
+	// mime-types which are never allowed. Supports wildcard subtype e.g "video/*"
+	BlockedMimeTypes []string
+	// if set, filename of each attachment is passed through it. See SanitizeAttachmentFileName
+	FileNameSanitizer func(fileName string) string
+}
+
+type AttachmentPolicyViolation string
+
+const (
+	AttachmentFileTooLarge        AttachmentPolicyViolation = "file_too_large"
+	AttachmentsTotalTooLarge      AttachmentPolicyViolation = "total_too_large"
+	AttachmentMimeTypeNotAllowed  AttachmentPolicyViolation = "mime_type_not_allowed"
+	AttachmentMimeTypeBlocked     AttachmentPolicyViolation = "mime_type_blocked"
+	AttachmentFileNameUnavailable AttachmentPolicyViolation = "filename_empty"
+)
+
+// Returned when an attachment violates AttachmentPolicy
+type AttachmentPolicyError struct {
+	FileName  string
+	Violation AttachmentPolicyViolation
+	Message   string
+}
+
+func (e *AttachmentPolicyError) Error() string {
+	return fmt.Sprintf("SuprsendAttachmentError: attachment '%s': %s", e.FileName, e.Message)
+}
+
+/*
+SanitizeAttachmentFileName can be used as AttachmentPolicy.FileNameSanitizer. It strips directory part
+and control characters, replaces characters not allowed in filenames (on common OS) with '_',
+and limits filename to 255 bytes, keeping its extension.
+*/
+func SanitizeAttachmentFileName(fileName string) string {
+	fileName = strings.ReplaceAll(fileName, "\\", "/")
+	fileName = filepath.Base(strings.TrimSpace(fileName))
+	if fileName == "." || fileName == "/" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, r := range fileName {
+		switch {
+		case unicode.IsControl(r):
+			continue
+		case strings.ContainsRune(`<>:"|?*`, r):
+			sb.WriteRune('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	fileName = strings.Trim(sb.String(), " .")
+	if len(fileName) > 255 {
+		ext := filepath.Ext(fileName)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		stem := strings.ToValidUTF8(fileName[:255-len(ext)], "")
+		fileName = stem + ext
+	}
+	return fileName
+}
+
+func mimeTypeMatches(mimeType string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == mimeType {
+			return true
+		}
+		if prefix, found := strings.CutSuffix(p, "/*"); found && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checks a single file against policy. contentHead is used to detect mime-type
+func (p *AttachmentPolicy) checkFile(fileName string, size int, contentHead []byte) error {
+	if p.MaxFileBytes > 0 && size > p.MaxFileBytes {
+		return &AttachmentPolicyError{FileName: fileName, Violation: AttachmentFileTooLarge,
+			Message: fmt.Sprintf("file is larger than %d bytes", p.MaxFileBytes)}
+	}
+	if len(p.AllowedMimeTypes) == 0 && len(p.BlockedMimeTypes) == 0 {
+		return nil
+	}
+	// parameters (e.g charset) are not part of match
+	mimeType, _, _ := strings.Cut(mimetype.Detect(contentHead).String(), ";")
+	if mimeTypeMatches(mimeType, p.BlockedMimeTypes) {
+		return &AttachmentPolicyError{FileName: fileName, Violation: AttachmentMimeTypeBlocked,
+			Message: fmt.Sprintf("mime-type %s is blocked", mimeType)}
+	}
+	if len(p.AllowedMimeTypes) > 0 && !mimeTypeMatches(mimeType, p.AllowedMimeTypes) {
+		return &AttachmentPolicyError{FileName: fileName, Violation: AttachmentMimeTypeNotAllowed,
+			Message: fmt.Sprintf("mime-type %s is not allowed", mimeType)}
+	}
+	return nil
+}
+
+func (p *AttachmentPolicy) sanitizeFileName(fileName string) (string, error) {
+	if p.FileNameSanitizer == nil {
+		return fileName, nil
+	}
+	sanitized := p.FileNameSanitizer(fileName)
+	if strings.TrimSpace(sanitized) == "" {
+		return "", &AttachmentPolicyError{FileName: fileName, Violation: AttachmentFileNameUnavailable,
+			Message: "filename is empty after sanitizing"}
+	}
+	return sanitized, nil
+}
+
+// size of content of inline attachment, computed from its base64 string without decoding it
+func inlineAttachmentSize(b64Data string) int {
+	size := len(b64Data) / 4 * 3
+	return size - (len(b64Data) - len(strings.TrimRight(b64Data, "=")))
+}
+
+// decodes only the head of base64 content, enough to detect mime-type
+func inlineAttachmentHead(b64Data string) []byte {
+	headLen := base64.StdEncoding.EncodedLen(ATTACHMENT_MIME_DETECTION_READ_LIMIT)
+	if len(b64Data) < headLen {
+		headLen = len(b64Data)
+	}
+	head, _ := base64.StdEncoding.DecodeString(b64Data[:headLen])
+	return head
+}
+
+/*
+checks attachment json against policy and sanitizes its filename. Returns attachment (a copy if it's modified),
+or nil if attachment violates policy and must be ignored. existingBytes is size of other attachments of same request.
+*/
+func (p *AttachmentPolicy) apply(attachment map[string]any, existingBytes int) (map[string]any, error) {
+	if p == nil || attachment == nil {
+		return attachment, nil
+	}
+	fileName, _ := attachment["filename"].(string)
+	err := func() error {
+		if b64Data, isInline := attachment["data"].(string); isInline {
+			if err := p.checkFile(fileName, inlineAttachmentSize(b64Data), inlineAttachmentHead(b64Data)); err != nil {
+				return err
+			}
+		}
+		if err := p.checkTotal(attachment, existingBytes); err != nil {
+			return err
+		}
+		if fileName != "" {
+			sanitized, err := p.sanitizeFileName(fileName)
+			if err != nil {
+				return err
+			}
+			if sanitized != fileName {
+				attachment = maps.Clone(attachment)
+				attachment["filename"] = sanitized
+			}
+		}
+		return nil
+	}()
+	return ignoreAttachmentIfError(attachment, err)
+}
+
+// checks that attachment, added to existing ones (of existingBytes size), doesn't cross MaxTotalBytes
+func (p *AttachmentPolicy) checkTotal(attachment map[string]any, existingBytes int) error {
+	if p.MaxTotalBytes <= 0 {
+		return nil
+	}
+	b64Data, isInline := attachment["data"].(string)
+	if !isInline {
+		return nil
+	}
+	total := existingBytes + inlineAttachmentSize(b64Data)
+	if total <= p.MaxTotalBytes {
+		return nil
+	}
+	fileName, _ := attachment["filename"].(string)
+	return &AttachmentPolicyError{FileName: fileName, Violation: AttachmentsTotalTooLarge,
+		Message: fmt.Sprintf("total size of attachments %d bytes, must not cross %d bytes", total, p.MaxTotalBytes)}
+}
+
+// attachment with ignore_if_error is dropped (nil) with a warning, else error is returned
+func ignoreAttachmentIfError(attachment map[string]any, err error) (map[string]any, error) {
+	if err == nil {
+		return attachment, nil
+	}
+	if ignore, _ := attachment["ignore_if_error"].(bool); ignore {
+		log.Printf("WARNING: ignoring attachment. %v", err)
+		return nil, nil
+	}
+	return nil, err
+}
+
+// total size of content of inline attachments
+func inlineAttachmentsSize(attachs []map[string]any) int {
+	total := 0
+	for _, a := range attachs {
+		if b64Data, isInline := a["data"].(string); isInline {
+			total += inlineAttachmentSize(b64Data)
+		}
+	}
+	return total
+}
+
+// applies per-attachment policy (if any) to an attachment being added to existing attachments of a request
+func applyAttachmentOptionPolicy(attachment map[string]any, existing []map[string]any, ao *AttachmentOption) (map[string]any, error) {
+	if ao == nil || ao.Policy == nil || attachment == nil {
+		return attachment, nil
+	}
+	return ignoreAttachmentIfError(attachment, ao.Policy.checkTotal(attachment, inlineAttachmentsSize(existing)))
+}
+
+/*
+Applies client's attachment policy on body[key]["$attachments"]. Returns body as-is if nothing changes,
+otherwise a copy of body (caller's maps are never modified) with attachments ignored/sanitized.
+*/
+func (c *Client) applyAttachmentPolicy(body map[string]any, key string) (map[string]any, error) {
+	if c.attachmentPolicy == nil {
+		return body, nil
+	}
+	d, _ := body[key].(map[string]any)
+	attachs, _ := d["$attachments"].([]map[string]any)
+	if len(attachs) == 0 {
+		return body, nil
+	}
+	changed, totalBytes := false, 0
+	checked := make([]map[string]any, 0, len(attachs))
+	for _, a := range attachs {
+		ca, err := c.attachmentPolicy.apply(a, totalBytes)
+		if err != nil {
+			return nil, err
+		}
+		if ca == nil {
+			changed = true
+			continue
+		}
+		if ca["filename"] != a["filename"] {
+			changed = true
+		}
+		if b64Data, isInline := ca["data"].(string); isInline {
+			totalBytes += inlineAttachmentSize(b64Data)
+		}
+		checked = append(checked, ca)
+	}
+	if !changed {
+		return body, nil
+	}
+	d = maps.Clone(d)
+	d["$attachments"] = checked
+	body = maps.Clone(body)
+	body[key] = d
+	return body, nil
+}
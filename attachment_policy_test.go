@@ -0,0 +1,97 @@
+package suprsend
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentPolicyPerAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n<<>>\nendobj\n")
+	tests := []struct {
+		name          string
+		policy        AttachmentPolicy
+		fileName      string
+		content       []byte
+		wantViolation AttachmentPolicyViolation
+		wantName      string
+	}{
+		{"within size", AttachmentPolicy{MaxFileBytes: 10}, "a.txt", []byte("0123456789"), "", "a.txt"},
+		{"too large", AttachmentPolicy{MaxFileBytes: 10}, "a.txt", []byte("01234567890"), AttachmentFileTooLarge, ""},
+		{"allowed by wildcard", AttachmentPolicy{AllowedMimeTypes: []string{"image/*", "text/*"}}, "a.txt", []byte("text"), "", "a.txt"},
+		{"not allowed", AttachmentPolicy{AllowedMimeTypes: []string{"image/*"}}, "a.txt", []byte("text"), AttachmentMimeTypeNotAllowed, ""},
+		// mime-type is detected from content, not from passed name/content-type
+		{"blocked by content", AttachmentPolicy{BlockedMimeTypes: []string{"application/pdf"}}, "a.txt", pdf, AttachmentMimeTypeBlocked, ""},
+		{"sanitized name", AttachmentPolicy{FileNameSanitizer: SanitizeAttachmentFileName}, "../x/in:voice?.pdf", pdf, "", "in_voice_.pdf"},
+		{"empty after sanitizing", AttachmentPolicy{FileNameSanitizer: SanitizeAttachmentFileName}, "..", pdf, AttachmentFileNameUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			a, err := GetAttachmentJsonFromBytes(tt.fileName, "text/plain", tt.content, &AttachmentOption{Policy: &policy})
+			var pErr *AttachmentPolicyError
+			if tt.wantViolation != "" {
+				if !errors.As(err, &pErr) || pErr.Violation != tt.wantViolation {
+					t.Fatalf("err = %v, want violation %s", err, tt.wantViolation)
+				}
+				// same violation is skipped with IgnoreIfError
+				a, err = GetAttachmentJsonFromBytes(tt.fileName, "", tt.content, &AttachmentOption{Policy: &policy, IgnoreIfError: true})
+				if a != nil || err != nil {
+					t.Errorf("with IgnoreIfError: attachment = %v, err = %v", a, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a["filename"] != tt.wantName {
+				t.Errorf("filename = %v, want %s", a["filename"], tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSanitizeAttachmentFileName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"report.pdf", "report.pdf"},
+		{`C:\Users\me\report.pdf`, "report.pdf"},
+		{"/etc/passwd", "passwd"},
+		{"a\x00b\tc.txt", "abc.txt"},
+		{` "quoted" .txt. `, "_quoted_ .txt"},
+		{"/", ""},
+		{strings.Repeat("n", 300) + ".pdf", strings.Repeat("n", 251) + ".pdf"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeAttachmentFileName(tt.in); got != tt.want {
+			t.Errorf("SanitizeAttachmentFileName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClientAttachmentPolicyTotalBudget(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	c := newTestClient(t, ts.URL, WithAttachmentPolicy(AttachmentPolicy{MaxTotalBytes: 25}))
+	ev := testEvent("u1", nil)
+	ev.AddAttachmentBytes("a.txt", "", bytes.Repeat([]byte("a"), 10), nil)
+	ev.AddAttachmentBytes("b.txt", "", bytes.Repeat([]byte("b"), 10), nil)
+	ev.AddAttachmentBytes("c.txt", "", bytes.Repeat([]byte("c"), 10), &AttachmentOption{IgnoreIfError: true})
+	if _, err := c.TrackEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	sent := ts.Requests()[0].jsonBody(t).(map[string]any)["properties"].(map[string]any)["$attachments"].([]any)
+	if len(sent) != 2 {
+		t.Errorf("sent %d attachments, want 2 (c.txt crosses budget and is ignored)", len(sent))
+	}
+	if n := len(ev.Properties["$attachments"].([]map[string]any)); n != 3 {
+		t.Errorf("caller's event has %d attachments, want 3", n)
+	}
+	ev.AddAttachmentBytes("d.txt", "", bytes.Repeat([]byte("d"), 10), nil)
+	var pErr *AttachmentPolicyError
+	if _, err := c.TrackEvent(ev); !errors.As(err, &pErr) || pErr.Violation != AttachmentsTotalTooLarge {
+		t.Errorf("err = %v, want total_too_large", err)
+	}
+}
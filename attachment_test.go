@@ -0,0 +1,112 @@
+package suprsend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reader which fails after returning some content
+type failingReader struct{ n int }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n > 0 {
+		n := min(r.n, len(p))
+		r.n -= n
+		return n, nil
+	}
+	return 0, errors.New("read failed")
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestAttachmentJsonFromReaderAndBytes(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789"), 10000)
+	tests := []struct {
+		name        string
+		fileName    string
+		contentType string
+		content     []byte
+		ao          *AttachmentOption
+		wantName    string
+		wantType    string
+	}{
+		{"detects text", "notes.txt", "", []byte("plain text content"), nil, "notes.txt", "text/plain; charset=utf-8"},
+		{"detects png", "img.png", "", pngHeader, nil, "img.png", "image/png"},
+		{"explicit type", "data.bin", " application/x-custom ", []byte{1, 2, 3}, nil, "data.bin", "application/x-custom"},
+		{"filename from option", "a.txt", "text/csv", []byte("a,b"), &AttachmentOption{FileName: "report.csv"}, "report.csv", "text/csv"},
+		{"content larger than detection limit", "big.txt", "", large, nil, "big.txt", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromReader, err := GetAttachmentJsonFromReader(tt.fileName, tt.contentType, bytes.NewReader(tt.content), tt.ao)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromBytes, err := GetAttachmentJsonFromBytes(tt.fileName, tt.contentType, tt.content, tt.ao)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range []map[string]any{fromReader, fromBytes} {
+				if a["filename"] != tt.wantName || a["contentType"] != tt.wantType {
+					t.Errorf("filename = %v, contentType = %v", a["filename"], a["contentType"])
+				}
+				if a["data"] != base64.StdEncoding.EncodeToString(tt.content) {
+					t.Error("data is not base64 of content")
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentJsonFromReaderErrors(t *testing.T) {
+	if _, err := GetAttachmentJsonFromReader(" ", "", strings.NewReader("x"), nil); err == nil {
+		t.Error("empty filename must be rejected")
+	}
+	if _, err := GetAttachmentJsonFromReader("a.txt", "", nil, nil); err == nil {
+		t.Error("nil reader must be rejected")
+	}
+	if _, err := GetAttachmentJsonFromBytes("a.txt", "", nil, nil); err == nil {
+		t.Error("nil content must be rejected")
+	}
+	if _, err := GetAttachmentJsonFromReader("a.txt", "", &failingReader{n: 5000}, nil); err == nil {
+		t.Error("read error must be returned")
+	}
+	a, err := GetAttachmentJsonFromReader("a.txt", "", &failingReader{n: 10}, &AttachmentOption{IgnoreIfError: true})
+	if a != nil || err != nil {
+		t.Errorf("read error with IgnoreIfError: attachment = %v, err = %v", a, err)
+	}
+}
+
+func TestAddAttachmentFromReaderBytesAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.txt")
+	os.WriteFile(path, []byte("invoice"), 0o644)
+	ev := testEvent("u1", nil)
+	if err := ev.AddAttachment(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ev.AddAttachmentReader("r.txt", "", strings.NewReader("from reader"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ev.AddAttachmentBytes("b.txt", "", []byte("from bytes"), nil); err != nil {
+		t.Fatal(err)
+	}
+	wf := testWorkflow("wf", "u1")
+	if err := wf.AddAttachmentBytes("b.txt", "", []byte("from bytes"), nil); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, a := range ev.Properties["$attachments"].([]map[string]any) {
+		names = append(names, a["filename"].(string))
+	}
+	if strings.Join(names, ",") != "invoice.txt,r.txt,b.txt" {
+		t.Errorf("event attachments = %v", names)
+	}
+	if n := len(wf.Body["data"].(map[string]any)["$attachments"].([]map[string]any)); n != 1 {
+		t.Errorf("workflow attachments = %d, want 1", n)
+	}
+}
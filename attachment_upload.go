@@ -0,0 +1,214 @@
+package suprsend
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+/*
+Options of attachment upload mode. See WithAttachmentUpload. One of PresignPath/Presign is mandatory.
+*/
+type AttachmentUploadOptions struct {
+	/*
+		endpoint which returns presigned upload url for an attachment. Relative to client's base url,
+		an absolute url is used as-is. It's called (signed, same as other api calls) with
+			POST {"filename": "a.pdf", "content_type": "application/pdf", "size": 1024}
+		and must respond with
+			{"upload_url": "<url to PUT file content to>", "url": "<url of uploaded file, sent in $attachments>"}
+	*/
+	PresignPath string
+	// if set, called instead of PresignPath endpoint to get upload url and file url of an attachment
+	Presign func(ctx context.Context, fileName string, contentType string, size int) (uploadUrl string, fileUrl string, err error)
+}
+
+func (o *AttachmentUploadOptions) cleanParams() error {
+	o.PresignPath = strings.TrimSpace(o.PresignPath)
+	if o.PresignPath == "" && o.Presign == nil {
+		return &Error{Message: "attachment upload: PresignPath or Presign is mandatory"}
+	}
+	return nil
+}
+
+// request/response of presign endpoint
+type attachmentPresignRequest struct {
+	FileName    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+}
+
+type attachmentPresignResponse struct {
+	// url to PUT the file to
+	UploadUrl string `json:"upload_url"`
+	// url of uploaded file, passed in $attachments
+	Url string `json:"url"`
+}
+
+func (c *Client) attachmentUploadEnabled() bool {
+	return c.attachmentUpload != nil
+}
+
+/*
+If upload mode is enabled, uploads inline (base64) attachments under body[key]["$attachments"]
+and returns a copy of body, in which data of each uploaded attachment is replaced by its url.
+body is returned as-is if there's nothing to upload. Caller's maps are never modified.
+uploaded (if not nil) maps content hash to url of already uploaded content, so same content is uploaded only once.
+*/
+func (c *Client) uploadAttachmentsInBody(ctx context.Context, body map[string]any, key string, uploaded map[string]string,
+) (map[string]any, bool, error) {
+	if !c.attachmentUploadEnabled() {
+		return body, false, nil
+	}
+	d, _ := body[key].(map[string]any)
+	attachs, _ := d["$attachments"].([]map[string]any)
+	if !hasInlineAttachment(attachs) {
+		return body, false, nil
+	}
+	newAttachs := make([]map[string]any, 0, len(attachs))
+	for _, a := range attachs {
+		b64Data, isInline := a["data"].(string)
+		if !isInline {
+			newAttachs = append(newAttachs, a)
+			continue
+		}
+		contentHash := ""
+		if uploaded != nil {
+			contentHash = attachmentContentHash(b64Data)
+		}
+		fileUrl, found := uploaded[contentHash]
+		var err error
+		if !found {
+			fileUrl, err = c.uploadAttachment(ctx, a)
+			if err == nil && uploaded != nil {
+				uploaded[contentHash] = fileUrl
+			}
+		}
+		if err != nil {
+			if ignore, _ := a["ignore_if_error"].(bool); ignore {
+				log.Printf("WARNING: ignoring attachment %v, upload failed: %v", a["filename"], err)
+				continue
+			}
+			return nil, false, err
+		}
+		ua := maps.Clone(a)
+		delete(ua, "data")
+		ua["url"] = fileUrl
+		newAttachs = append(newAttachs, ua)
+	}
+	d = maps.Clone(d)
+	d["$attachments"] = newAttachs
+	body = maps.Clone(body)
+	body[key] = d
+	return body, true, nil
+}
+
+func hasInlineAttachment(attachs []map[string]any) bool {
+	for _, a := range attachs {
+		if _, isInline := a["data"].(string); isInline {
+			return true
+		}
+	}
+	return false
+}
+
+// uploads content of attachment to a presigned url, returns url of uploaded file
+func (c *Client) uploadAttachment(ctx context.Context, attachment map[string]any) (string, error) {
+	content, err := base64.StdEncoding.DecodeString(attachment["data"].(string))
+	if err != nil {
+		return "", &Error{Err: err}
+	}
+	fileName, _ := attachment["filename"].(string)
+	contentType, _ := attachment["contentType"].(string)
+	// get presigned url
+	presign, err := c.presignAttachment(ctx, fileName, contentType, len(content))
+	if err != nil {
+		return "", err
+	}
+	// upload file
+	request, err := http.NewRequestWithContext(ctx, "PUT", presign.UploadUrl, bytes.NewReader(content))
+	if err != nil {
+		return "", &Error{Err: err}
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	httpRes, err := c.httpClient.Do(request)
+	if err != nil {
+		return "", &Error{Err: err}
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode >= 300 {
+		respBody, _ := readResponseBody(httpRes)
+		return "", &Error{Code: httpRes.StatusCode,
+			Message: fmt.Sprintf("attachment upload failed for %s: %s", fileName, string(respBody))}
+	}
+	if c.debug {
+		log.Printf("DEBUG: uploaded attachment %s (%d bytes) to %s", fileName, len(content), presign.Url)
+	}
+	return presign.Url, nil
+}
+
+func (c *Client) presignAttachment(ctx context.Context, fileName string, contentType string, size int,
+) (*attachmentPresignResponse, error) {
+	presign := &attachmentPresignResponse{}
+	var err error
+	if c.attachmentUpload.Presign != nil {
+		presign.UploadUrl, presign.Url, err = c.attachmentUpload.Presign(ctx, fileName, contentType, size)
+		if err != nil {
+			return nil, &Error{Message: fmt.Sprintf("attachment upload: presign failed for %s: %v", fileName, err), Err: err}
+		}
+	} else {
+		err = c.Do(ctx, "POST", c.attachmentUpload.PresignPath,
+			attachmentPresignRequest{FileName: fileName, ContentType: contentType, Size: size}, presign)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if presign.UploadUrl == "" || presign.Url == "" {
+		return nil, &Error{Message: "attachment upload: presign response must have upload_url and url"}
+	}
+	return presign, nil
+}
+
+/*
+uploads attachments of a bulk record (if any), so that record carries urls instead of content.
+Apparent size of record, which was estimated with ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES per attachment,
+is corrected with actual size of urls, so that records get packed as tightly as possible.
+*/
+func (c *Client) uploadRecordAttachments(ctx context.Context, rec *pendingBulkRecord, key string, uploaded map[string]string) error {
+	if key == "" {
+		return nil
+	}
+	body, changed, err := c.uploadAttachmentsInBody(ctx, rec.record, key, uploaded)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	d, _ := body[key].(map[string]any)
+	attachs, _ := d["$attachments"].([]map[string]any)
+	origD, _ := rec.record[key].(map[string]any)
+	origAttachs, _ := origD["$attachments"].([]map[string]any)
+	recordSize := rec.recordSize - len(origAttachs)*ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES
+	for _, a := range attachs {
+		if _, hasUrl := a["url"]; hasUrl {
+			urlJson, err := c.marshal(a["url"])
+			if err != nil {
+				return err
+			}
+			// ,"url":<url-json>
+			recordSize += len(urlJson) + 7
+		}
+	}
+	rec.record = body
+	rec.recordSize = max(recordSize, 0)
+	// record changed, so its encoding can't be used anymore
+	rec.encoded = nil
+	return nil
+}
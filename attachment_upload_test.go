@@ -0,0 +1,136 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stand-in for presign endpoint and file storage. Uploaded content is kept by upload path
+type uploadServer struct {
+	*testServer
+	mu      sync.Mutex
+	uploads map[string]string
+}
+
+func newUploadServer(t *testing.T) *uploadServer {
+	us := &uploadServer{uploads: map[string]string{}}
+	us.testServer = newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch {
+		case r.URL.Path == "/v1/presign/":
+			req := recordedRequest{Body: body}.jsonBody(t).(map[string]any)
+			name := req["filename"].(string)
+			writeJson(w, 200, map[string]any{
+				"upload_url": us.URL + "/upload/" + name, "url": "https://files.example.com/" + name,
+			})
+		case strings.HasPrefix(r.URL.Path, "/upload/"):
+			if r.Method != "PUT" {
+				w.WriteHeader(405)
+				return
+			}
+			us.mu.Lock()
+			us.uploads[r.URL.Path] = string(body)
+			us.mu.Unlock()
+			w.WriteHeader(200)
+		case r.URL.Path == "/v2/event/":
+			writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+		default:
+			acceptAllBulkHandler(w, r, body)
+		}
+	})
+	return us
+}
+
+func sentAttachments(t *testing.T, event map[string]any) []any {
+	t.Helper()
+	return event["properties"].(map[string]any)["$attachments"].([]any)
+}
+
+func TestAttachmentUploadSingleEvent(t *testing.T) {
+	us := newUploadServer(t)
+	c := newTestClient(t, us.URL, WithAttachmentUpload(AttachmentUploadOptions{PresignPath: "v1/presign/"}))
+	ev := testEvent("u1", nil)
+	ev.AddAttachmentBytes("invoice.txt", "text/plain", []byte("invoice content"), nil)
+	if _, err := c.TrackEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	if us.uploads["/upload/invoice.txt"] != "invoice content" {
+		t.Errorf("uploads = %v", us.uploads)
+	}
+	presign := us.requestsTo("/v1/presign/")[0]
+	req := presign.jsonBody(t).(map[string]any)
+	if req["filename"] != "invoice.txt" || req["content_type"] != "text/plain" || req["size"] != json.Number("15") {
+		t.Errorf("presign request = %v", req)
+	}
+	if presign.Header.Get("Authorization") == "" {
+		t.Error("presign request must be signed")
+	}
+	attachs := sentAttachments(t, us.requestsTo("/v2/event/")[0].jsonBody(t).(map[string]any))
+	a := attachs[0].(map[string]any)
+	if a["url"] != "https://files.example.com/invoice.txt" || a["data"] != nil {
+		t.Errorf("sent attachment = %v", a)
+	}
+	// caller's event is not modified
+	if _, inline := ev.Properties["$attachments"].([]map[string]any)[0]["data"]; !inline {
+		t.Error("caller's attachment must keep its content")
+	}
+}
+
+func TestAttachmentUploadBulkUploadsSharedContentOnce(t *testing.T) {
+	us := newUploadServer(t)
+	c := newTestClient(t, us.URL, WithAttachmentUpload(AttachmentUploadOptions{PresignPath: us.URL + "/v1/presign/"}))
+	bulkIns := c.BulkEvents.NewInstance()
+	for _, id := range []string{"u1", "u2", "u3"} {
+		ev := testEvent(id, nil)
+		ev.AddAttachmentBytes("terms.txt", "text/plain", []byte("same terms"), nil)
+		bulkIns.Append(ev)
+	}
+	resp, _ := bulkIns.Trigger()
+	if resp.Success != 3 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	if n := len(us.requestsTo("/v1/presign/")); n != 1 {
+		t.Errorf("presign calls = %d, want 1", n)
+	}
+	for _, rec := range us.requestsTo("/v2/bulk/event/")[0].jsonBody(t).([]any) {
+		a := sentAttachments(t, rec.(map[string]any))[0].(map[string]any)
+		if a["url"] != "https://files.example.com/terms.txt" || a["data"] != nil {
+			t.Errorf("sent attachment = %v", a)
+		}
+	}
+}
+
+func TestAttachmentUploadOptions(t *testing.T) {
+	if _, err := NewClient(testWorkspaceKey, testWorkspaceSecret, WithAttachmentUpload(AttachmentUploadOptions{})); err == nil {
+		t.Error("upload mode without PresignPath/Presign must be rejected")
+	}
+	us := newUploadServer(t)
+	presignErr := errors.New("quota exceeded")
+	var presigned []string
+	c := newTestClient(t, us.URL, WithAttachmentUpload(AttachmentUploadOptions{
+		Presign: func(ctx context.Context, fileName, contentType string, size int) (string, string, error) {
+			if fileName == "fail.txt" {
+				return "", "", presignErr
+			}
+			presigned = append(presigned, fileName)
+			return us.URL + "/upload/custom", "https://cdn.example.com/custom", nil
+		},
+	}))
+	ev := testEvent("u1", nil)
+	ev.AddAttachmentBytes("a.txt", "", []byte("content"), nil)
+	if _, err := c.TrackEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(presigned) != 1 || us.uploads["/upload/custom"] != "content" || len(us.requestsTo("/v1/presign/")) != 0 {
+		t.Errorf("Presign func must be used instead of presign endpoint, uploads = %v", us.uploads)
+	}
+	failing := testEvent("u1", nil)
+	failing.AddAttachmentBytes("fail.txt", "", []byte("content"), nil)
+	if _, err := c.TrackEvent(failing); !errors.Is(err, presignErr) {
+		t.Errorf("err = %v, want presign error", err)
+	}
+}
@@ -38,6 +38,8 @@ type BrandList struct {
 type BrandListOptions struct {
 	Limit  int
 	Offset int
+	// used only by All iterator: fetch next page concurrently while current page is being iterated
+	Prefetch bool
 }
 
 func (b *BrandListOptions) cleanParams() {
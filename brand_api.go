@@ -3,6 +3,7 @@ package suprsend
 import (
 	"context"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 )
@@ -10,9 +11,10 @@ import (
 // Brand has been renamed to Tenant. Brand is kept for backward-compatibilty.
 // Use Tenant instead of Brand
 type BrandsService interface {
-	Get(context.Context, string) (*Brand, error)
-	Upsert(context.Context, string, *Brand) (*Brand, error)
-	List(context.Context, *BrandListOptions) (*BrandList, error)
+	Get(context.Context, string, ...RequestOption) (*Brand, error)
+	Upsert(context.Context, string, *Brand, ...RequestOption) (*Brand, error)
+	List(context.Context, *BrandListOptions, ...RequestOption) (*BrandList, error)
+	All(context.Context, *BrandListOptions, ...RequestOption) iter.Seq2[*Brand, error]
 }
 
 type brandsService struct {
@@ -41,14 +43,14 @@ func (b *brandsService) prepareQueryParams(opt *BrandListOptions) string {
 	return params.Encode()
 }
 
-func (b *brandsService) List(ctx context.Context, opts *BrandListOptions) (*BrandList, error) {
+func (b *brandsService) List(ctx context.Context, opts *BrandListOptions, reqOpts ...RequestOption) (*BrandList, error) {
 	urlStr := fmt.Sprintf("%s?%s", b._url, b.prepareQueryParams(opts))
 	// prepare http.Request object
-	request, err := b.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := b.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := b.client.httpClient.Do(request)
+	httpResponse, err := b.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -62,19 +64,37 @@ func (b *brandsService) List(ctx context.Context, opts *BrandListOptions) (*Bran
 	return resp, nil
 }
 
+// All returns iterator over all brands, starting at opts.Offset. Pages of opts.Limit brands are fetched as needed
+func (b *brandsService) All(ctx context.Context, opts *BrandListOptions, reqOpts ...RequestOption) iter.Seq2[*Brand, error] {
+	pageOpts := BrandListOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.cleanParams()
+	fetch := offsetPageFetcher(pageOpts.Limit, pageOpts.Offset,
+		func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []*Brand, error) {
+			resp, err := b.List(ctx, &BrandListOptions{Limit: limit, Offset: offset}, reqOpts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp.Meta, resp.Results, nil
+		})
+	return paginate(ctx, fetch, pageOpts.Prefetch)
+}
+
 func (b *brandsService) brandAPIUrl(brandId string) string {
 	brandId = url.PathEscape(brandId)
 	return fmt.Sprintf("%s%s/", b._url, brandId)
 }
 
-func (b *brandsService) Get(ctx context.Context, brandId string) (*Brand, error) {
+func (b *brandsService) Get(ctx context.Context, brandId string, reqOpts ...RequestOption) (*Brand, error) {
 	urlStr := b.brandAPIUrl(brandId)
 	// prepare http.Request object
-	request, err := b.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := b.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := b.client.httpClient.Do(request)
+	httpResponse, err := b.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -88,14 +108,14 @@ func (b *brandsService) Get(ctx context.Context, brandId string) (*Brand, error)
 	return resp, nil
 }
 
-func (b *brandsService) Upsert(ctx context.Context, brandId string, payload *Brand) (*Brand, error) {
+func (b *brandsService) Upsert(ctx context.Context, brandId string, payload *Brand, reqOpts ...RequestOption) (*Brand, error) {
 	urlStr := b.brandAPIUrl(brandId)
 	// prepare http.Request object
-	request, err := b.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := b.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := b.client.httpClient.Do(request)
+	httpResponse, err := b.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,479 @@
+package suprsend
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/http"
+)
+
+// Chunk limits of bulk apis. Zero value of a field means sdk default is used.
+type BulkLimits struct {
+	// max workflow-records in one bulk api call. default: MAX_WORKFLOWS_IN_BULK_API
+	MaxWorkflowsInBulk int
+	// max event-records in one bulk api call. default: MAX_EVENTS_IN_BULK_API
+	MaxEventsInBulk int
+	// max user-edit/identity records in one bulk api call. default: MAX_IDENTITY_EVENTS_IN_BULK_API
+	MaxIdentityEventsInBulk int
+	// max apparent body size of one bulk api call. default: BODY_MAX_APPARENT_SIZE_IN_BYTES
+	MaxApparentSizeInBytes int
+}
+
+func (l *BulkLimits) cleanParams() {
+	if l.MaxWorkflowsInBulk <= 0 {
+		l.MaxWorkflowsInBulk = MAX_WORKFLOWS_IN_BULK_API
+	}
+	if l.MaxEventsInBulk <= 0 {
+		l.MaxEventsInBulk = MAX_EVENTS_IN_BULK_API
+	}
+	if l.MaxIdentityEventsInBulk <= 0 {
+		l.MaxIdentityEventsInBulk = MAX_IDENTITY_EVENTS_IN_BULK_API
+	}
+	if l.MaxApparentSizeInBytes <= 0 {
+		l.MaxApparentSizeInBytes = BODY_MAX_APPARENT_SIZE_IN_BYTES
+	}
+}
+
+/*
+bulkRecordKind holds everything that differs between bulk apis (events, workflow triggers, user edits etc.)
+T is the type of record appended to the bulk instance.
+*/
+type bulkRecordKind[T any] struct {
+	// api url (relative to client's base url)
+	url func(c *Client) string
+	// max records in one api call
+	maxRecords func(c *Client) int
+	// validates the record and returns its final json, apparent size (and encoding, if reusable) and warnings (if any)
+	validate func(c *Client, rec *T) (pendingBulkRecord, []string, error)
+	// json of record which failed validation. Reported in BulkResponse.FailedRecords
+	asJson func(rec *T) map[string]any
+	// key under which $attachments are present in final json. Empty if records can't have attachments
+	attachmentsKey string
+	// kind used to namespace dedup keys (see DedupCache). Empty if records of this kind are not deduped
+	dedupKind string
+	// parses api response of a chunk
+	parseResponse chunkResponseParser
+}
+
+type chunkResponseParser func(httpRes *http.Response, err error, _chunk []map[string]any, _indexes []int) *chunkResponse
+
+type pendingBulkRecord struct {
+	// index of record in the order it was appended
+	index      int
+	record     map[string]any
+	recordSize int
+	// json encoding of record, if it can be sent as-is. nil otherwise
+	encoded encodedJson
+}
+
+/*
+bulkEngine runs the pipeline shared by all bulk apis:
+validate records -> pack them into chunks -> trigger each chunk -> merge chunk responses
+*/
+type bulkEngine[T any] struct {
+	client *Client
+	kind   *bulkRecordKind[T]
+	//
+	records        []T
+	pendingRecords []pendingBulkRecord
+	chunks         []*bulkChunk
+	//
+	response *BulkResponse
+	// invalid_record json: {"record": record-json, "error": error_str, "code": 500}
+	invalidRecords []map[string]any
+	// index (in records) of each invalid record
+	invalidIndexes []int
+	// dedup key of each record to be sent, and index of first record with a dedup key
+	dedupKeys  map[int]string
+	dedupIndex map[string]int
+	// records skipped as duplicates: of an already sent record (result known from cache),
+	// or of another record in this instance (index of that record)
+	duplicateResults []BulkRecordResult
+	duplicateOf      map[int]int
+	// per-call options, applied to every chunk api call
+	requestOpts []RequestOption
+	// content hash -> url of attachments uploaded for this instance (in attachment upload mode)
+	uploadedAttachments map[string]string
+}
+
+func newBulkEngine[T any](client *Client, kind *bulkRecordKind[T]) *bulkEngine[T] {
+	return &bulkEngine[T]{
+		client:      client,
+		kind:        kind,
+		response:    &BulkResponse{},
+		dedupKeys:   map[int]string{},
+		dedupIndex:  map[string]int{},
+		duplicateOf: map[int]int{},
+		//
+		uploadedAttachments: map[string]string{},
+	}
+}
+
+func (e *bulkEngine[T]) append(rec T) {
+	e.records = append(e.records, rec)
+}
+
+func (e *bulkEngine[T]) validate() {
+	for idx := range e.records {
+		rec, warnings, err := e.kind.validate(e.client, &e.records[idx])
+		if len(warnings) > 0 {
+			e.response.Warnings = append(e.response.Warnings, warnings...)
+		}
+		if err != nil {
+			invRec := invalidRecordJson(e.kind.asJson(&e.records[idx]), err)
+			e.invalidRecords = append(e.invalidRecords, invRec)
+			e.invalidIndexes = append(e.invalidIndexes, idx)
+		} else if e.checkDuplicate(idx, rec.record) {
+			continue
+		} else {
+			rec.index = idx
+			e.pendingRecords = append(e.pendingRecords, rec)
+		}
+	}
+}
+
+/*
+In attachment upload mode, uploads attachments of records to be sent, so that chunks carry urls instead of content.
+Records are updated in place, so retries don't upload again. Content shared by records is uploaded only once.
+Record whose upload fails becomes invalid.
+*/
+func (e *bulkEngine[T]) uploadAttachments(ctx context.Context) {
+	if !e.client.attachmentUploadEnabled() || e.kind.attachmentsKey == "" {
+		return
+	}
+	pendingRecords := e.pendingRecords[:0]
+	for _, rec := range e.pendingRecords {
+		if err := e.client.uploadRecordAttachments(ctx, &rec, e.kind.attachmentsKey, e.uploadedAttachments); err != nil {
+			e.invalidRecords = append(e.invalidRecords, invalidRecordJson(e.kind.asJson(&e.records[rec.index]), err))
+			e.invalidIndexes = append(e.invalidIndexes, rec.index)
+			continue
+		}
+		pendingRecords = append(pendingRecords, rec)
+	}
+	e.pendingRecords = pendingRecords
+}
+
+// returns true if record is a duplicate and must not be sent
+func (e *bulkEngine[T]) checkDuplicate(idx int, recJson map[string]any) bool {
+	key := e.client.dedupKey(e.kind.dedupKind, recJson)
+	if key == "" {
+		return false
+	}
+	if cachedEntry, found := e.client.dedupCache.Get(key); found {
+		e.duplicateResults = append(e.duplicateResults, BulkRecordResult{
+			Index: idx, Status: "success", StatusCode: cachedEntry.StatusCode, MessageId: cachedEntry.MessageId,
+			Record: recJson, Duplicate: true,
+		})
+		return true
+	}
+	if otherIdx, found := e.dedupIndex[key]; found {
+		e.duplicateOf[idx] = otherIdx
+		return true
+	}
+	e.dedupKeys[idx] = key
+	e.dedupIndex[key] = idx
+	return false
+}
+
+// merges results of duplicate records and caches responses of successfully sent records
+func (e *bulkEngine[T]) resolveDuplicates() {
+	if len(e.dedupKeys) == 0 && len(e.duplicateResults) == 0 {
+		return
+	}
+	resultByIndex := map[int]BulkRecordResult{}
+	for _, r := range e.response.Results {
+		resultByIndex[r.Index] = r
+		if key := e.dedupKeys[r.Index]; key != "" && r.Status == "success" {
+			e.client.dedupCache.Set(key, DedupEntry{StatusCode: r.StatusCode, MessageId: r.MessageId})
+		}
+	}
+	results := e.duplicateResults
+	for idx, otherIdx := range e.duplicateOf {
+		r := resultByIndex[otherIdx]
+		r.Index, r.Duplicate = idx, true
+		results = append(results, r)
+	}
+	if len(results) > 0 {
+		e.response.mergeChunkResponse(chunkResponseFromResults(results))
+	}
+	e.duplicateResults, e.duplicateOf = nil, map[int]int{}
+}
+
+func (k *bulkRecordKind[T]) newChunk(c *Client) *bulkChunk {
+	return newBulkChunk(c, k.url(c), k.maxRecords(c), k.parseResponse)
+}
+
+// packs records into chunks, in order, without crossing chunk limits
+func (e *bulkEngine[T]) pack(records []pendingBulkRecord) []*bulkChunk {
+	chunks := []*bulkChunk{}
+	var currChunk *bulkChunk
+	for _, rec := range records {
+		if !ALLOW_ATTACHMENTS_IN_BULK_API && e.kind.attachmentsKey != "" {
+			if d, ok := rec.record[e.kind.attachmentsKey].(map[string]any); ok {
+				// record may share nested maps with appended copy, so copy before modifying
+				d = maps.Clone(d)
+				delete(d, "$attachments")
+				rec.record[e.kind.attachmentsKey] = d
+				// record changed, so its encoding can't be used anymore
+				rec.encoded = nil
+			}
+		}
+		if currChunk == nil || !currChunk.tryToAddIntoChunk(rec) {
+			currChunk = e.kind.newChunk(e.client)
+			currChunk.requestOpts = e.requestOpts
+			chunks = append(chunks, currChunk)
+			currChunk.addToChunk(rec)
+		}
+	}
+	return chunks
+}
+
+func (e *bulkEngine[T]) trigger(opts ...RequestOption) (*BulkResponse, error) {
+	e.requestOpts = opts
+	ctx := newRequestOptions(opts).callContext()
+	e.validate()
+	e.checkSharedAttachments()
+	e.uploadAttachments(ctx)
+	if len(e.invalidRecords) > 0 {
+		chResponse := invalidRecordsChunkResponse(e.invalidRecords, e.invalidIndexes)
+		e.response.mergeChunkResponse(chResponse)
+	}
+	if len(e.pendingRecords) > 0 {
+		e.chunks = e.pack(e.pendingRecords)
+		for cIdx, ch := range e.chunks {
+			if e.client.debug {
+				log.Printf("DEBUG: triggering api call for chunk: %d", cIdx)
+			}
+			// do api call. Chunk gets split if it's rejected as a whole
+			triggerChunkWithSplit(ctx, ch, e.response.mergeChunkResponse)
+		}
+	} else {
+		if len(e.invalidRecords) == 0 && len(e.duplicateResults) == 0 && len(e.duplicateOf) == 0 {
+			e.response.mergeChunkResponse(emptyChunkSuccessResponse())
+		}
+	}
+	e.resolveDuplicates()
+	e.response.sortResults()
+	return e.response, nil
+}
+
+/*
+Resends records which failed with a retryable error in the last response (see BulkResponse.RetryableRecords).
+Records are resent as-is, so their idempotency_key remains same. Returns a fresh response, which has
+results of all records (retried or not) against their original index.
+*/
+func (e *bulkEngine[T]) retryFailed(ctx context.Context, opts ...RequestOption) (*BulkResponse, error) {
+	e.requestOpts = opts
+	toRetry := retryableIndexes(e.response)
+	pendingRecords := []pendingBulkRecord{}
+	for _, rec := range e.pendingRecords {
+		if toRetry[rec.index] {
+			pendingRecords = append(pendingRecords, rec)
+		}
+	}
+	retried := []BulkRecordResult{}
+	var err error
+	if len(pendingRecords) > 0 {
+		e.chunks = e.pack(pendingRecords)
+		for cIdx, ch := range e.chunks {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			if e.client.debug {
+				log.Printf("DEBUG: retrying api call for chunk: %d", cIdx)
+			}
+			// do api call
+			triggerChunkWithSplit(ctx, ch, func(resp *chunkResponse) {
+				retried = append(retried, resp.results...)
+			})
+		}
+	}
+	e.response = mergeRetryResponse(e.response, retried)
+	e.resolveDuplicates()
+	return e.response, err
+}
+
+// ==========================================================
+
+type bulkChunk struct {
+	_chunkApparentSizeInBytes int
+	_maxRecordsInChunk        int
+	//
+	client        *Client
+	_url          string
+	parseResponse chunkResponseParser
+	requestOpts   []RequestOption
+	//
+	_chunk         []map[string]any
+	_indexes       []int
+	_encoded       []encodedJson
+	_sizes         []int
+	_runningSize   int
+	_runningLength int
+	response       *chunkResponse
+}
+
+func newBulkChunk(client *Client, url string, maxRecords int, parser chunkResponseParser) *bulkChunk {
+	return &bulkChunk{
+		_chunkApparentSizeInBytes: client.bulkLimits.MaxApparentSizeInBytes,
+		_maxRecordsInChunk:        maxRecords,
+		//
+		client:        client,
+		_url:          url,
+		parseResponse: parser,
+		_chunk:        []map[string]any{},
+	}
+}
+
+func (b *bulkChunk) addToChunk(rec pendingBulkRecord) {
+	// First add size, then record to reduce effects of race condition
+	b._runningSize += rec.recordSize
+	b._chunk = append(b._chunk, rec.record)
+	b._indexes = append(b._indexes, rec.index)
+	b._encoded = append(b._encoded, rec.encoded)
+	b._sizes = append(b._sizes, rec.recordSize)
+	b._runningLength += 1
+}
+
+func (b *bulkChunk) _checkLimitReached() bool {
+	return b._runningLength >= b._maxRecordsInChunk || b._runningSize >= b._chunkApparentSizeInBytes
+}
+
+/*
+returns whether passed record was able to get added to this chunk or not,
+if true, record gets added to chunk
+*/
+func (b *bulkChunk) tryToAddIntoChunk(rec pendingBulkRecord) bool {
+	if rec.record == nil {
+		return true
+	}
+	if b._checkLimitReached() {
+		return false
+	}
+	// if apparent_size of record crosses limit
+	if (b._runningSize + rec.recordSize) > b._chunkApparentSizeInBytes {
+		return false
+	}
+	b.addToChunk(rec)
+	return true
+}
+
+// body of chunk api call. Joins encoded records if every record has one, else chunk gets encoded while sending
+func (b *bulkChunk) body() any {
+	encoded := make([][]byte, 0, len(b._encoded))
+	for _, e := range b._encoded {
+		if e == nil {
+			return b._chunk
+		}
+		encoded = append(encoded, e)
+	}
+	return joinEncodedRecords(encoded)
+}
+
+func (b *bulkChunk) trigger(ctx context.Context) {
+	// prepare http.Request object
+	request, err := b.client.prepareHttpRequest("POST", b._url, b.body(), b.requestOpts...)
+	if err != nil {
+		b.response = b.parseResponse(nil, err, b._chunk, b._indexes)
+		return
+	}
+	httpResponse, err := b.client.send(ctx, request, b.requestOpts...)
+	if err != nil {
+		b.response = b.parseResponse(nil, err, b._chunk, b._indexes)
+	} else {
+		defer httpResponse.Body.Close()
+		b.response = b.parseResponse(httpResponse, nil, b._chunk, b._indexes)
+	}
+}
+
+// splits records of this chunk in two halves
+func (b *bulkChunk) split() (*bulkChunk, *bulkChunk) {
+	mid := len(b._chunk) / 2
+	first := newBulkChunk(b.client, b._url, b._maxRecordsInChunk, b.parseResponse)
+	second := newBulkChunk(b.client, b._url, b._maxRecordsInChunk, b.parseResponse)
+	first.requestOpts, second.requestOpts = b.requestOpts, b.requestOpts
+	for i, rec := range b._chunk {
+		target := first
+		if i >= mid {
+			target = second
+		}
+		target.addToChunk(pendingBulkRecord{
+			index: b._indexes[i], record: rec, recordSize: b._sizes[i], encoded: b._encoded[i],
+		})
+	}
+	return first, second
+}
+
+/*
+Chunk is worth splitting only if every record of it failed because of the chunk itself i.e. 413 (too large).
+If server reported a result against each record, those results are final and chunk is not resent.
+Any other 4xx/5xx (e.g. malformed request, invalid credentials) would fail the same way for every half.
+*/
+func isChunkRejectedAsWhole(resp *chunkResponse) bool {
+	if resp == nil || resp.status != "fail" || resp.failure != resp.total || resp.hasRecordResults {
+		return false
+	}
+	return resp.statusCode == http.StatusRequestEntityTooLarge
+}
+
+/*
+Triggers the chunk. If whole chunk gets rejected (e.g 413 because apparent-size estimate was off),
+chunk is split in two halves and each half is resent, recursively down to single records.
+So only records which fail on their own get reported as failed.
+onResponse is called for every chunk response which is final (i.e. not split further).
+*/
+func triggerChunkWithSplit(ctx context.Context, ch *bulkChunk, onResponse func(*chunkResponse)) {
+	ch.trigger(ctx)
+	resp := ch.response
+	if len(ch._chunk) <= 1 || !isChunkRejectedAsWhole(resp) {
+		onResponse(resp)
+		return
+	}
+	first, second := ch.split()
+	log.Printf("WARNING: bulk chunk of %d records rejected with status %d, resending as chunks of %d and %d records",
+		len(ch._chunk), resp.statusCode, len(first._chunk), len(second._chunk))
+	triggerChunkWithSplit(ctx, first, onResponse)
+	triggerChunkWithSplit(ctx, second, onResponse)
+}
+
+// Used by bulk apis which return a plain response for whole chunk: /event/ and legacy workflow trigger endpoint
+func parseBulkChunkResponse(httpRes *http.Response, err error, _chunk []map[string]any, _indexes []int) *chunkResponse {
+	bulkRespFunc := func(statusCode int, errMsg string, errType string) *chunkResponse {
+		failedRecords := []map[string]any{}
+		if statusCode >= 400 {
+			for _, c := range _chunk {
+				failedRecords = append(failedRecords,
+					map[string]any{
+						"record": c,
+						"error":  errMsg,
+						"code":   statusCode,
+					})
+			}
+			return &chunkResponse{
+				status: "fail", statusCode: statusCode,
+				total: len(_chunk), success: 0, failure: len(_chunk),
+				failedRecords: failedRecords,
+				results:       chunkRecordResults(_chunk, _indexes, statusCode, errMsg, errType),
+			}
+		} else {
+			return &chunkResponse{
+				status: "success", statusCode: statusCode,
+				total: len(_chunk), success: len(_chunk), failure: 0,
+				failedRecords: failedRecords,
+				results:       chunkRecordResults(_chunk, _indexes, statusCode, "", ""),
+			}
+		}
+	}
+	if err != nil {
+		return bulkRespFunc(500, err.Error(), BULK_RECORD_ERROR_TYPE_NETWORK)
+
+	} else if httpRes != nil {
+		respBody, err := readResponseBody(httpRes)
+		if err != nil {
+			return bulkRespFunc(500, err.Error(), BULK_RECORD_ERROR_TYPE_NETWORK)
+		}
+		//
+		return bulkRespFunc(httpRes.StatusCode, string(respBody), "")
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// handler which rejects (with statusCode and body) every chunk of more than maxRecords records
+func rejectLargeChunksHandler(maxRecords int, statusCode int, body func(n int) any) testHandler {
+	return func(w http.ResponseWriter, r *http.Request, reqBody []byte) {
+		var records []map[string]any
+		json.Unmarshal(reqBody, &records)
+		if len(records) > maxRecords {
+			writeJson(w, statusCode, body(len(records)))
+			return
+		}
+		writeJson(w, 202, bulkSuccessBody(len(records)))
+	}
+}
+
+func chunkErrorBody(n int) any {
+	return map[string]any{"status": "error", "error": map[string]any{"message": "rejected", "type": "test_error"}}
+}
+
+func recordErrorsBody(n int) any {
+	recs := []map[string]any{}
+	for i := 0; i < n; i++ {
+		recs = append(recs, map[string]any{"status": "error", "status_code": 400,
+			"error": map[string]any{"message": "batch rejected", "type": "test_error"}})
+	}
+	return map[string]any{"status": "error", "records": recs}
+}
+
+func TestBulkChunkSplitOnRejection(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       func(n int) any
+		wantCalls  int
+		wantOk     int
+	}{
+		// 8 -> 4+4 -> 2+2+2+2
+		{"413 is split", 413, chunkErrorBody, 7, 8},
+		{"400 with per-record errors is not split", 400, recordErrorsBody, 1, 0},
+		{"422 with per-record errors is not split", 422, recordErrorsBody, 1, 0},
+		{"413 with per-record errors is not split", 413, recordErrorsBody, 1, 0},
+		{"400 for whole chunk is not split", 400, chunkErrorBody, 1, 0},
+		{"500 is not split", 500, chunkErrorBody, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, rejectLargeChunksHandler(2, tt.statusCode, tt.body))
+			c := newTestClient(t, ts.URL)
+			bulkIns := c.BulkEvents.NewInstance()
+			for i := 0; i < 8; i++ {
+				bulkIns.Append(testEvent("u"+itoa(i), nil))
+			}
+			resp, _ := bulkIns.Trigger()
+			if n := len(ts.requestsTo("/v2/bulk/event/")); n != tt.wantCalls {
+				t.Errorf("bulk calls = %d, want %d", n, tt.wantCalls)
+			}
+			if resp.Total != 8 || resp.Success != tt.wantOk || len(resp.Results) != 8 {
+				t.Errorf("unexpected response %v", resp)
+			}
+			for i, r := range resp.Results {
+				if r.Index != i || r.Record["distinct_id"] != "u"+itoa(i) {
+					t.Errorf("result %d is of record %d (%v)", i, r.Index, r.Record["distinct_id"])
+				}
+			}
+		})
+	}
+}
+
+func TestBulkChunkSplitKeepsRecordSizes(t *testing.T) {
+	c := newTestClient(t, "http://localhost/")
+	ch := newBulkChunk(c, "http://localhost/bulk/", 10, parseBulkChunkResponse)
+	sizes := []int{100, 200, 300, 400, 500}
+	for i, size := range sizes {
+		ch.addToChunk(pendingBulkRecord{index: i, record: map[string]any{"i": i}, recordSize: size})
+	}
+	first, second := ch.split()
+	if first._runningSize != 300 || second._runningSize != 1200 {
+		t.Errorf("sizes of halves = %d, %d, want 300, 1200", first._runningSize, second._runningSize)
+	}
+	if len(first._chunk) != 2 || len(second._chunk) != 3 || second._indexes[0] != 2 {
+		t.Errorf("unexpected halves: %v, %v", first._indexes, second._indexes)
+	}
+	// halves must be split further using their real size
+	a, b := second.split()
+	if a._runningSize != 300 || b._runningSize != 900 {
+		t.Errorf("sizes of quarters = %d, %d, want 300, 900", a._runningSize, b._runningSize)
+	}
+}
+
+func TestBulkLimitsDefaults(t *testing.T) {
+	limits := BulkLimits{MaxEventsInBulk: 7}
+	limits.cleanParams()
+	want := BulkLimits{
+		MaxWorkflowsInBulk:      MAX_WORKFLOWS_IN_BULK_API,
+		MaxEventsInBulk:         7,
+		MaxIdentityEventsInBulk: MAX_IDENTITY_EVENTS_IN_BULK_API,
+		MaxApparentSizeInBytes:  BODY_MAX_APPARENT_SIZE_IN_BYTES,
+	}
+	if limits != want {
+		t.Errorf("cleanParams() = %+v, want %+v", limits, want)
+	}
+}
+
+func TestBulkEngineAppliesClientLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits BulkLimits
+		path   string
+		send   func(c *Client, n int)
+	}{
+		{"events", BulkLimits{MaxEventsInBulk: 3}, "/v2/bulk/event/", func(c *Client, n int) {
+			b := c.BulkEvents.NewInstance()
+			for i := 0; i < n; i++ {
+				b.Append(testEvent("u"+itoa(i), nil))
+			}
+			b.Trigger()
+		}},
+		{"workflow triggers", BulkLimits{MaxWorkflowsInBulk: 3}, "/trigger/", func(c *Client, n int) {
+			b := c.Workflows.BulkTriggerInstance()
+			for i := 0; i < n; i++ {
+				b.Append(testWorkflow("wf", "u"+itoa(i)))
+			}
+			b.Trigger()
+		}},
+		{"user edits", BulkLimits{MaxIdentityEventsInBulk: 3}, "/event/", func(c *Client, n int) {
+			b := c.Users.GetBulkEditInstance()
+			for i := 0; i < n; i++ {
+				ue := c.Users.GetEditInstance("u" + itoa(i))
+				ue.Set(map[string]any{"name": "user"})
+				b.Append(ue)
+			}
+			b.Save()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, acceptAllBulkHandler)
+			tt.send(newTestClient(t, ts.URL, WithBulkLimits(tt.limits)), 7)
+			reqs := ts.requestsTo(tt.path)
+			// 7 records with at most 3 per call
+			if len(reqs) != 3 {
+				t.Fatalf("calls to %s = %d, want 3", tt.path, len(reqs))
+			}
+			for _, r := range reqs {
+				if n := len(r.jsonBody(t).([]any)); n > 3 {
+					t.Errorf("call has %d records, max is 3", n)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkEngineChunksBySize(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL, WithBulkLimits(BulkLimits{MaxApparentSizeInBytes: 1500}))
+	b := c.BulkEvents.NewInstance()
+	big := strings.Repeat("x", 1000)
+	for i := 0; i < 4; i++ {
+		b.Append(testEvent("u"+itoa(i), map[string]any{"payload": big}))
+	}
+	resp, _ := b.Trigger()
+	// every record is above half the limit, so each one goes in its own chunk
+	if n := len(ts.requestsTo("/v2/bulk/event/")); n != 4 || resp.Success != 4 {
+		t.Errorf("bulk calls = %d, want 4; response %v", n, resp)
+	}
+}
+
+func TestBulkAppendCopiesNestedValues(t *testing.T) {
+	ts := newTestServer(t, perRecordBulkHandler(func(rec map[string]any) int { return 202 }))
+	c := newTestClient(t, ts.URL)
+	millis := 1700000000000
+
+	// events: same nested map is reused and modified between Appends
+	items := map[string]any{"sku": "a"}
+	bulkEvents := c.BulkEvents.NewInstance()
+	ev := testEvent("u1", map[string]any{"items": items})
+	ev.Timestamp = time.UnixMilli(int64(millis))
+	bulkEvents.Append(ev)
+	items["sku"] = "b"
+	bulkEvents.Append(testEvent("u2", map[string]any{"items": items}))
+	items["sku"] = "c"
+	if _, err := bulkEvents.Trigger(); err != nil {
+		t.Fatal(err)
+	}
+	events := sentBulkEvents(t, ts)
+	if len(events) != 2 {
+		t.Fatalf("sent = %v", events)
+	}
+	for i, want := range []string{"a", "b"} {
+		if got := events[i]["properties"].(map[string]any)["items"].(map[string]any)["sku"]; got != want {
+			t.Errorf("event %d: sku = %v, want %s", i, got, want)
+		}
+	}
+	if events[0]["$time"] != json.Number(itoa(millis)) {
+		t.Errorf("$time = %v, want %d", events[0]["$time"], millis)
+	}
+
+	// workflows: same request is reused and its data modified between Appends
+	bulkWorkflows := c.Workflows.BulkTriggerInstance()
+	wf := testWorkflow("wf", "u1")
+	bulkWorkflows.Append(wf)
+	wf.Body["data"].(map[string]any)["k"] = "v2"
+	wf.Body["recipients"].([]any)[0] = "u2"
+	bulkWorkflows.Append(wf)
+	wf.Body["data"].(map[string]any)["k"] = "v3"
+	if _, err := bulkWorkflows.Trigger(); err != nil {
+		t.Fatal(err)
+	}
+	sent := ts.requestsTo("/trigger/")[0].jsonBody(t).([]any)
+	for i, want := range []string{"v", "v2"} {
+		if got := sent[i].(map[string]any)["data"].(map[string]any)["k"]; got != want {
+			t.Errorf("workflow %d: data.k = %v, want %s", i, got, want)
+		}
+	}
+	if got := sent[0].(map[string]any)["recipients"].([]any)[0]; got != "u1" {
+		t.Errorf("workflow 0: recipient = %v, want u1", got)
+	}
+}
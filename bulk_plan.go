@@ -0,0 +1,111 @@
+package suprsend
+
+import (
+	"fmt"
+	"slices"
+)
+
+// BulkPlan is how records of a bulk instance would be sent, computed without making any api call.
+type BulkPlan struct {
+	// total records appended to bulk instance
+	Total int
+	// chunks (api calls), in the order they would be sent
+	Chunks []BulkPlanChunk
+	// records which fail validation, these won't be sent
+	InvalidRecords []BulkRecordResult
+	// indexes of records which won't be sent as they are duplicates (see WithDedupCache)
+	DuplicateIndexes []int
+	// attachment content present in more than one record
+	SharedAttachments []SharedAttachment
+	// size of attachment content to be sent. In attachment upload mode, shared content is counted once,
+	// as it's uploaded once. Otherwise it's counted for every record, as it's sent inline with each one.
+	AttachmentBytes int
+	Warnings        []string
+}
+
+type BulkPlanChunk struct {
+	// index of each record in the order it was appended
+	RecordIndexes []int
+	// apparent size of chunk body, as used for chunking
+	ApparentSizeInBytes int
+	NumAttachments      int
+}
+
+func (p *BulkPlan) String() string {
+	return fmt.Sprintf("BulkPlan{Total: %v, Chunks: %v, InvalidRecords: %v, Duplicates: %v, SharedAttachments: %v, Warnings: %v}",
+		p.Total, len(p.Chunks), len(p.InvalidRecords), len(p.DuplicateIndexes), len(p.SharedAttachments), len(p.Warnings))
+}
+
+// validates and packs records same way as trigger does, but doesn't send them or change state of this engine
+func (e *bulkEngine[T]) plan() *BulkPlan {
+	dryRun := newBulkEngine(e.client, e.kind)
+	dryRun.records = e.records
+	dryRun.validate()
+	sharedAttachments := dryRun.checkSharedAttachments()
+	plan := &BulkPlan{
+		Total:             len(e.records),
+		Chunks:            []BulkPlanChunk{},
+		InvalidRecords:    []BulkRecordResult{},
+		SharedAttachments: sharedAttachments,
+		Warnings:          dryRun.response.Warnings,
+	}
+	if len(dryRun.invalidRecords) > 0 {
+		plan.InvalidRecords = invalidRecordsChunkResponse(dryRun.invalidRecords, dryRun.invalidIndexes).results
+	}
+	for _, r := range dryRun.duplicateResults {
+		plan.DuplicateIndexes = append(plan.DuplicateIndexes, r.Index)
+	}
+	for idx := range dryRun.duplicateOf {
+		plan.DuplicateIndexes = append(plan.DuplicateIndexes, idx)
+	}
+	slices.Sort(plan.DuplicateIndexes)
+	uploadEnabled := e.client.attachmentUploadEnabled()
+	countedHashes := map[string]bool{}
+	for _, ch := range dryRun.pack(dryRun.pendingRecords) {
+		numAttachments := 0
+		for _, rec := range ch._chunk {
+			numAttachments += e.kind.attachmentCount(rec)
+			for _, b64Data := range e.kind.inlineAttachmentsData(rec) {
+				if uploadEnabled {
+					contentHash := attachmentContentHash(b64Data)
+					if countedHashes[contentHash] {
+						continue
+					}
+					countedHashes[contentHash] = true
+				}
+				plan.AttachmentBytes += inlineAttachmentSize(b64Data)
+			}
+		}
+		plan.Chunks = append(plan.Chunks, BulkPlanChunk{
+			RecordIndexes:       ch._indexes,
+			ApparentSizeInBytes: ch._runningSize,
+			NumAttachments:      numAttachments,
+		})
+	}
+	return plan
+}
+
+// base64 content of inline attachments of record
+func (k *bulkRecordKind[T]) inlineAttachmentsData(record map[string]any) []string {
+	if k.attachmentsKey == "" {
+		return nil
+	}
+	d, _ := record[k.attachmentsKey].(map[string]any)
+	attachments, _ := d["$attachments"].([]map[string]any)
+	data := []string{}
+	for _, a := range attachments {
+		if b64Data, isInline := a["data"].(string); isInline {
+			data = append(data, b64Data)
+		}
+	}
+	return data
+}
+
+func (k *bulkRecordKind[T]) attachmentCount(record map[string]any) int {
+	if k.attachmentsKey == "" {
+		return 0
+	}
+	d, _ := record[k.attachmentsKey].(map[string]any)
+	attachments, _ := d["$attachments"].([]map[string]any)
+	return len(attachments)
+}
@@ -0,0 +1,68 @@
+package suprsend
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBulkEventsPlan(t *testing.T) {
+	c := newTestClient(t, "http://localhost/", WithBulkLimits(BulkLimits{MaxEventsInBulk: 2}),
+		WithDedupCache(NewLRUDedupCache(10, time.Minute), nil))
+	dup := testEvent("u0", nil)
+	dup.IdempotencyKey = "k0"
+	bulkIns := c.BulkEvents.NewInstance()
+	bulkIns.Append(dup, testEvent("u1", nil), &Event{DistinctId: "u2"}, testEvent("u3", nil), dup)
+	plan := bulkIns.Plan()
+	if plan.Total != 5 || len(plan.Chunks) != 2 {
+		t.Fatalf("unexpected plan %v", plan)
+	}
+	if !slices.Equal(plan.Chunks[0].RecordIndexes, []int{0, 1}) || !slices.Equal(plan.Chunks[1].RecordIndexes, []int{3}) {
+		t.Errorf("chunks = %+v", plan.Chunks)
+	}
+	for _, ch := range plan.Chunks {
+		if ch.ApparentSizeInBytes <= 0 {
+			t.Errorf("chunk %v has no size", ch.RecordIndexes)
+		}
+	}
+	if len(plan.InvalidRecords) != 1 || plan.InvalidRecords[0].Index != 2 {
+		t.Errorf("invalid records = %+v", plan.InvalidRecords)
+	}
+	if !slices.Equal(plan.DuplicateIndexes, []int{4}) {
+		t.Errorf("duplicate indexes = %v", plan.DuplicateIndexes)
+	}
+	// plan must not change what Trigger sends
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c.baseUrl = ts.URL + "/"
+	resp, _ := bulkIns.Trigger()
+	if resp.Total != 5 || resp.Success != 4 || len(ts.requestsTo("/v2/bulk/event/")) != 2 {
+		t.Errorf("trigger after plan: %v", resp)
+	}
+}
+
+func TestBulkWorkflowsAndSubscribersPlan(t *testing.T) {
+	c := newTestClient(t, "http://localhost/", WithBulkLimits(BulkLimits{MaxWorkflowsInBulk: 2, MaxIdentityEventsInBulk: 2}))
+	workflows := c.BulkWorkflows.NewInstance()
+	for i := 0; i < 3; i++ {
+		workflows.Append(&Workflow{Body: map[string]any{
+			"name": "wf", "template": "tmpl", "notification_category": "transactional",
+			"users": []map[string]any{{"distinct_id": "u" + itoa(i)}}, "data": map[string]any{},
+		}})
+	}
+	workflows.Append(&Workflow{Body: map[string]any{"name": "wf"}})
+	plan := workflows.Plan()
+	if plan.Total != 4 || len(plan.Chunks) != 2 || len(plan.InvalidRecords) != 1 || plan.InvalidRecords[0].Index != 3 {
+		t.Errorf("workflows plan = %v, %+v", plan, plan.Chunks)
+	}
+
+	subscribers := c.BulkUsers.NewInstance()
+	for i := 0; i < 3; i++ {
+		sub := c.Users.GetInstance("u" + itoa(i))
+		sub.SetKV("name", "user")
+		subscribers.Append(sub)
+	}
+	plan = subscribers.Plan()
+	if plan.Total != 3 || len(plan.Chunks) != 2 || len(plan.InvalidRecords) != 0 {
+		t.Errorf("subscribers plan = %v, %+v", plan, plan.Chunks)
+	}
+}
@@ -2,9 +2,7 @@ package suprsend
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -44,6 +42,25 @@ type Client struct {
 	timeout  int
 	proxyUrl *url.URL
 	//
+	bulkLimits   BulkLimits
+	dedupCache   DedupCache
+	dedupKeyFunc DedupKeyFunc
+	// if set, idempotency_key is derived for triggers/events sent without one
+	autoIdempotency *IdempotencyKeyOptions
+	validationMode  ValidationMode
+	jsonCodec       JSONCodec
+	// gzip request bodies larger than compressionThreshold bytes
+	compressRequests     bool
+	compressionThreshold int
+	// used by Client.Do
+	retryPolicy RetryPolicy
+	middlewares []Middleware
+	// if set, inline attachments are uploaded and sent as urls
+	attachmentUpload *AttachmentUploadOptions
+	attachmentPolicy *AttachmentPolicy
+	// schemas of workflow data, registered by workflow slug
+	workflowSchemas *workflowSchemaRegistry
+	//
 	sdkVersion string
 	userAgent  string
 	//
@@ -81,6 +98,11 @@ func (c *Client) init(opts ...ClientOption) error {
 	if c.timeout <= 0 {
 		c.timeout = 30
 	}
+	c.bulkLimits.cleanParams()
+	if c.jsonCodec == nil {
+		c.jsonCodec = stdJSONCodec{}
+	}
+	c.workflowSchemas = newWorkflowSchemaRegistry()
 	c.setDerivedBaseUrl()
 	err = c.basicValidation()
 	if err != nil {
@@ -178,23 +200,41 @@ func (c *Client) TriggerWorkflow(wf *Workflow) (*Response, error) {
 	return c.workflowTrigger.Trigger(wf)
 }
 
-func (c *Client) TrackEvent(event *Event) (*Response, error) {
-	return c.eventCollector.Collect(event)
+func (c *Client) TrackEvent(event *Event, reqOpts ...RequestOption) (*Response, error) {
+	return c.eventCollector.Collect(event, reqOpts...)
 }
 
-func (c *Client) prepareHttpRequest(httpMethod string, httpUrl string, httpBody any,
+func (c *Client) prepareHttpRequest(httpMethod string, httpUrl string, httpBody any, opts ...RequestOption,
+) (*http.Request, error) {
+	return c.prepareHttpRequestWithOptions(httpMethod, httpUrl, httpBody, newRequestOptions(opts))
+}
+
+func (c *Client) prepareHttpRequestWithOptions(httpMethod string, httpUrl string, httpBody any, ro *requestOptions,
 ) (*http.Request, error) {
 	// Headers
 	headers := maps.Clone(c.commonHeaders)
+	httpUrl = ro.apply(httpUrl, headers)
 	//
 	var request *http.Request
 	if c.AuthMethod == AuthMethod_WsKeySecret {
 		headers["Date"] = CurrentTimeFormatted()
-		contentBody, sig, err := signature.GetRequestSignature(httpUrl, httpMethod, httpBody, headers, c.ApiSecret)
+		contentBody, err := c.encodeRequestBody(httpMethod, httpBody)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := signature.GetRequestSignatureForBody(httpUrl, httpMethod, contentBody, headers, c.ApiSecret)
 		if err != nil {
 			return nil, &Error{Err: err}
 		}
 		headers["Authorization"] = fmt.Sprintf("%s:%s", c.ApiKey, sig)
+		// signature is always computed on uncompressed body, hub verifies it after decompressing
+		contentBody, compressed, err := c.compressRequestBody(contentBody)
+		if err != nil {
+			return nil, err
+		}
+		if compressed {
+			headers["Content-Encoding"] = "gzip"
+		}
 		//
 		request, err = http.NewRequest(httpMethod, httpUrl, bytes.NewBuffer(contentBody))
 		if err != nil {
@@ -210,14 +250,25 @@ func (c *Client) prepareHttpRequest(httpMethod string, httpUrl string, httpBody
 	return request, nil
 }
 
+// returns body bytes to be sent. Already encoded body (encodedJson) is used as-is
+func (c *Client) encodeRequestBody(httpMethod string, httpBody any) ([]byte, error) {
+	if httpMethod == "GET" || signature.SafeCheckNil(httpBody) {
+		return []byte(""), nil
+	}
+	if encoded, ok := httpBody.(encodedJson); ok {
+		return encoded, nil
+	}
+	return c.marshal(httpBody)
+}
+
 func (c *Client) parseApiResponse(httpResponse *http.Response, respPtr any) error {
-	responseBody, err := io.ReadAll(httpResponse.Body)
+	responseBody, err := readResponseBody(httpResponse)
 	if err != nil {
 		return &Error{Err: err}
 	}
 	if httpResponse.StatusCode >= 400 {
 		var serr Error
-		err = json.Unmarshal(responseBody, &serr)
+		err = c.jsonCodec.Unmarshal(responseBody, &serr)
 		if err != nil {
 			return &Error{Code: httpResponse.StatusCode, Message: string(responseBody)}
 		}
@@ -228,7 +279,7 @@ func (c *Client) parseApiResponse(httpResponse *http.Response, respPtr any) erro
 	if respPtr == nil {
 		return nil
 	} else {
-		err = json.Unmarshal(responseBody, respPtr)
+		err = c.jsonCodec.Unmarshal(responseBody, respPtr)
 		if err != nil {
 			return &Error{Err: err}
 		}
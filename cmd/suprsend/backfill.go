@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	suprsend "github.com/suprsend/suprsend-go"
+)
+
+func runBackfillEvents(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill-events", flag.ExitOnError)
+	file := fs.String("file", "", "jsonl/csv file with events (mandatory)")
+	format := fs.String("format", "", "jsonl or csv. default: from file extension")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file, backfill resumes from it if it exists")
+	rejects := fs.String("rejects", "", "file to which rejected records are appended")
+	batchSize := fs.Int("batch-size", 0, "events per bulk api call")
+	colDistinctId := fs.String("col-distinct-id", "", "column/key of distinct_id (default: distinct_id)")
+	colEvent := fs.String("col-event", "", "column/key of event name (default: event)")
+	colProperties := fs.String("col-properties", "", "column/key of properties json object (default: properties)")
+	colTimestamp := fs.String("col-timestamp", "", "column/key of event time (default: timestamp)")
+	colIdempotencyKey := fs.String("col-idempotency-key", "", "column/key of idempotency key (default: idempotency_key)")
+	colTenantId := fs.String("col-tenant-id", "", "column/key of tenant id (default: tenant_id)")
+	debug := fs.Bool("debug", false, "log api requests")
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is mandatory")
+	}
+	client, err := newClient(*debug)
+	if err != nil {
+		return err
+	}
+	opts := &suprsend.BackfillOptions{
+		Format: suprsend.BackfillFormat(*format),
+		Columns: suprsend.BackfillColumnMapping{
+			DistinctId:     *colDistinctId,
+			EventName:      *colEvent,
+			Properties:     *colProperties,
+			Timestamp:      *colTimestamp,
+			IdempotencyKey: *colIdempotencyKey,
+			TenantId:       *colTenantId,
+		},
+		BatchSize:      *batchSize,
+		CheckpointFile: *checkpoint,
+		RejectsFile:    *rejects,
+		OnProgress: func(r suprsend.BackfillReport) {
+			fmt.Fprintf(os.Stderr, "\rread: %d, skipped: %d, success: %d, failure: %d", r.Total, r.Skipped, r.Success, r.Failure)
+		},
+	}
+	report, err := client.BulkEvents.BackfillFile(ctx, *file, opts)
+	fmt.Fprintln(os.Stderr)
+	if report != nil {
+		fmt.Println(report)
+	}
+	return err
+}
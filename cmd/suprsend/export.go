@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	suprsend "github.com/suprsend/suprsend-go"
+)
+
+func runExportUsers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export-users", flag.ExitOnError)
+	file := fs.String("file", "", "output jsonl/csv file (mandatory)")
+	format := fs.String("format", "", "jsonl or csv. default: from file extension")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file, export resumes from it (appending to output file) if it exists")
+	columns := fs.String("columns", "", "comma separated csv columns (default: all user fields)")
+	pageSize := fs.Int("page-size", 0, "users per list api call")
+	preferences := fs.Bool("preferences", false, "add full preference of each user")
+	lists := fs.Bool("lists", false, "add lists each user is subscribed to")
+	concurrency := fs.Int("concurrency", 0, "max concurrent api calls while adding preferences/lists")
+	debug := fs.Bool("debug", false, "log api requests")
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is mandatory")
+	}
+	if *format == "" {
+		*format = string(suprsend.UserExportFormatJSONL)
+		if strings.ToLower(filepath.Ext(*file)) == ".csv" {
+			*format = string(suprsend.UserExportFormatCSV)
+		}
+	}
+	client, err := newClient(*debug)
+	if err != nil {
+		return err
+	}
+	// output is appended to only when resuming, else a fresh export overwrites it.
+	// Resumed output must already exist, a new file would miss users (and csv header) exported till checkpoint.
+	// Finished export (as per checkpoint) is reported by Export before anything is written.
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if *checkpoint != "" {
+		if _, serr := os.Stat(*checkpoint); serr == nil {
+			flags = os.O_WRONLY | os.O_APPEND
+		}
+	}
+	f, err := os.OpenFile(*file, flags, 0o644)
+	if errors.Is(err, os.ErrNotExist) && flags&os.O_APPEND != 0 {
+		return fmt.Errorf("checkpoint %s exists but output file %s doesn't, remove checkpoint to export again", *checkpoint, *file)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	opts := &suprsend.UserExportOptions{
+		PageSize:           *pageSize,
+		IncludePreferences: *preferences,
+		IncludeLists:       *lists,
+		Concurrency:        *concurrency,
+		CheckpointFile:     *checkpoint,
+		OnProgress: func(r suprsend.UserExportReport) {
+			fmt.Fprintf(os.Stderr, "\rpages: %d, users: %d", r.Pages, r.Users)
+		},
+	}
+	if *columns != "" {
+		for _, col := range strings.Split(*columns, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(col))
+		}
+	}
+	report, err := client.Users.Export(ctx, f, suprsend.UserExportFormat(*format), opts)
+	fmt.Fprintln(os.Stderr)
+	if report != nil {
+		fmt.Println(report)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	suprsend "github.com/suprsend/suprsend-go"
+)
+
+func runImportUsers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import-users", flag.ExitOnError)
+	file := fs.String("file", "", "jsonl/csv file with users (mandatory)")
+	format := fs.String("format", "", "jsonl or csv. default: from file extension")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file, import resumes from it if it exists")
+	report := fs.String("report", "", "file to which rows with warnings/errors are appended")
+	batchSize := fs.Int("batch-size", 0, "users per bulk api call")
+	dryRun := fs.Bool("dry-run", false, "validate rows without importing them")
+	colDistinctId := fs.String("col-distinct-id", "", "column/key of distinct_id (default: distinct_id)")
+	mapping := fs.String("map", "", "comma separated column=operation, e.g mobile=sms,plan=set_once. "+
+		"operations: set, set_once, append, set_properties, email, sms, whatsapp, androidpush, iospush, webpush, slack, "+
+		"ms_teams, timezone, preferred_language, locale, ignore")
+	unmapped := fs.String("unmapped", "", "operation of columns not in -map (default: set)")
+	debug := fs.Bool("debug", false, "log api requests")
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is mandatory")
+	}
+	columns := map[string]suprsend.UserImportOp{}
+	if *mapping != "" {
+		for _, part := range strings.Split(*mapping, ",") {
+			col, op, found := strings.Cut(part, "=")
+			if !found {
+				return fmt.Errorf("invalid -map entry %q, must be column=operation", part)
+			}
+			columns[strings.TrimSpace(col)] = suprsend.UserImportOp(strings.TrimSpace(op))
+		}
+	}
+	client, err := newClient(*debug)
+	if err != nil {
+		return err
+	}
+	opts := &suprsend.UserImportOptions{
+		Format: suprsend.UserImportFormat(*format),
+		Mapping: suprsend.UserImportMapping{
+			DistinctId: *colDistinctId,
+			Columns:    columns,
+			Unmapped:   suprsend.UserImportOp(*unmapped),
+		},
+		BatchSize:      *batchSize,
+		DryRun:         *dryRun,
+		CheckpointFile: *checkpoint,
+		ReportFile:     *report,
+		OnProgress: func(r suprsend.UserImportReport) {
+			fmt.Fprintf(os.Stderr, "\rread: %d, skipped: %d, success: %d, failure: %d, warnings: %d",
+				r.Total, r.Skipped, r.Success, r.Failure, r.Warnings)
+		},
+	}
+	importReport, err := client.Users.ImportFile(ctx, *file, opts)
+	fmt.Fprintln(os.Stderr)
+	if importReport != nil {
+		fmt.Println(importReport)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+/*
+suprsend is a command line tool for bulk operations with SuprSend api.
+
+Usage:
+
+	suprsend <command> [flags]
+
+Credentials are read from environment: SUPRSEND_API_KEY, SUPRSEND_API_SECRET
+and optionally SUPRSEND_BASE_URL.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	suprsend "github.com/suprsend/suprsend-go"
+)
+
+type command struct {
+	description string
+	run         func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"backfill-events": {
+		description: "send events from a jsonl/csv file, with checkpoint and rejects file",
+		run:         runBackfillEvents,
+	},
+	"export-users": {
+		description: "write all users to a jsonl/csv file, optionally with their preferences and lists",
+		run:         runExportUsers,
+	},
+	"import-users": {
+		description: "create/update users from a jsonl/csv file, with checkpoint and report file",
+		run:         runImportUsers,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	// stop gracefully on interrupt, so that progress till last batch is checkpointed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: suprsend <command> [flags]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", name, commands[name].description)
+	}
+}
+
+func newClient(debug bool) (*suprsend.Client, error) {
+	opts := []suprsend.ClientOption{suprsend.WithDebug(debug)}
+	if baseUrl := os.Getenv("SUPRSEND_BASE_URL"); baseUrl != "" {
+		opts = append(opts, suprsend.WithBaseUrl(baseUrl))
+	}
+	return suprsend.NewClient(os.Getenv("SUPRSEND_API_KEY"), os.Getenv("SUPRSEND_API_SECRET"), opts...)
+}
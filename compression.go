@@ -0,0 +1,47 @@
+package suprsend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzip-compresses body if request compression is enabled and body is larger than threshold.
+// returns body as-is otherwise.
+func (c *Client) compressRequestBody(body []byte) ([]byte, bool, error) {
+	if !c.compressRequests || len(body) == 0 || len(body) <= c.compressionThreshold {
+		return body, false, nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(body) / 4)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, false, &Error{Err: err}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, &Error{Err: err}
+	}
+	return buf.Bytes(), true, nil
+}
+
+/*
+Reads response body, decompressing it if it's gzip-encoded. net/http decompresses transparently only
+when it has requested gzip itself, not when Accept-Encoding is set by caller or transport has compression disabled.
+*/
+func readResponseBody(httpRes *http.Response) ([]byte, error) {
+	if httpRes.Uncompressed || !strings.EqualFold(httpRes.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(httpRes.Body)
+	}
+	zr, err := gzip.NewReader(httpRes.Body)
+	if err != nil {
+		// empty body (e.g 204) can't be a valid gzip stream
+		if err == io.EOF {
+			return []byte{}, nil
+		}
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,79 @@
+package suprsend
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/suprsend/suprsend-go/signature"
+)
+
+func TestRequestCompression(t *testing.T) {
+	var wireSizes []int64
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		wireSizes = append(wireSizes, r.ContentLength)
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	c := newTestClient(t, ts.URL, WithRequestCompression(1024))
+	big := testEvent("u1", map[string]any{"note": strings.Repeat("compressible ", 500)})
+	if _, err := c.TrackEvent(big); err != nil {
+		t.Fatalf("compressed request not accepted: %v", err)
+	}
+	if _, err := c.TrackEvent(testEvent("u1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	reqs := ts.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d, want 2", len(reqs))
+	}
+	tests := []struct {
+		name       string
+		compressed bool
+	}{
+		{"body above threshold", true},
+		{"body below threshold", false},
+	}
+	for i, tt := range tests {
+		r := reqs[i]
+		if gotGzip := r.Header.Get("Content-Encoding") == "gzip"; gotGzip != tt.compressed {
+			t.Errorf("%s: gzip = %v, want %v", tt.name, gotGzip, tt.compressed)
+		}
+		if tt.compressed && wireSizes[i] >= int64(len(r.Body)) {
+			t.Errorf("%s: %d bytes sent for %d byte body", tt.name, wireSizes[i], len(r.Body))
+		}
+		// signature must be over uncompressed body
+		headers := map[string]string{"Content-Type": r.Header.Get("Content-Type"), "Date": r.Header.Get("Date")}
+		sig, _ := signature.GetRequestSignatureForBody(ts.URL+r.Path, r.Method, r.Body, headers, testWorkspaceSecret)
+		if r.Header.Get("Authorization") != testWorkspaceKey+":"+sig {
+			t.Errorf("%s: signature is not over uncompressed body", tt.name)
+		}
+		if r.jsonBody(t).(map[string]any)["distinct_id"] != "u1" {
+			t.Errorf("%s: unexpected body %s", tt.name, r.Body)
+		}
+	}
+}
+
+func TestDebugLogShowsUncompressedBody(t *testing.T) {
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	for _, opts := range [][]ClientOption{
+		{WithRequestCompression(0), WithDebug(true)},
+		{WithRequestCompression(0)},
+	} {
+		logs.Reset()
+		c := newTestClient(t, ts.URL, opts...)
+		// WithDebugTrace logs the call even if client's debug is off
+		if _, err := c.TrackEvent(testEvent("logged-user", nil), WithDebugTrace()); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(logs.String(), `"distinct_id":"logged-user"`) {
+			t.Errorf("debug log must show uncompressed body, got:\n%s", logs.String())
+		}
+	}
+}
@@ -13,6 +13,9 @@ const (
 	// in general url-size wont exceed 2048 chars or 2048 utf-8 bytes
 	ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES = 2100
 
+	// bytes read from start of attachment content to detect its mime-type
+	ATTACHMENT_MIME_DETECTION_READ_LIMIT = 3072
+
 	// few keys added in-flight, amounting to almost 200 bytes increase per workflow-body
 	WORKFLOW_RUNTIME_KEYS_POTENTIAL_SIZE_IN_BYTES = 200
 
@@ -24,6 +27,7 @@ const (
 
 	ALLOW_ATTACHMENTS_IN_BULK_API = true
 
+	// Deprecated: attachment upload is enabled per client, see WithAttachmentUpload
 	ATTACHMENT_UPLOAD_ENABLED = false
 
 	// single Identity event limit
@@ -0,0 +1,137 @@
+package suprsend
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+/*
+DedupCache remembers requests already sent by this process, so that a request with same dedup key
+(by default, its idempotency_key) is skipped and a response built from the cached entry is returned.
+Implementations must be safe for concurrent use. Implement it to back dedup with your own store (e.g redis).
+*/
+type DedupCache interface {
+	Get(key string) (DedupEntry, bool)
+	Set(key string, entry DedupEntry)
+}
+
+// DedupEntry is what DedupCache keeps for a successfully sent request (single or bulk record)
+type DedupEntry struct {
+	StatusCode int
+	MessageId  string
+}
+
+func dedupEntryOfResponse(resp *Response) DedupEntry {
+	messageId, _ := resp.RawResponse["message_id"].(string)
+	return DedupEntry{StatusCode: resp.StatusCode, MessageId: messageId}
+}
+
+// response returned for a request which is skipped as duplicate
+func (d DedupEntry) response() *Response {
+	return &Response{Success: true, StatusCode: d.StatusCode, Message: d.MessageId}
+}
+
+/*
+Returns the dedup key of a request from its final json. Requests with empty key are not deduped.
+Default: value of $idempotency_key
+*/
+type DedupKeyFunc func(finalJson map[string]any) string
+
+func defaultDedupKey(finalJson map[string]any) string {
+	key, _ := finalJson["$idempotency_key"].(string)
+	return key
+}
+
+// returns dedup key namespaced by record kind (workflow/event). Empty if dedup is not enabled.
+func (c *Client) dedupKey(kind string, finalJson map[string]any) string {
+	if c.dedupCache == nil || kind == "" {
+		return ""
+	}
+	keyFunc := c.dedupKeyFunc
+	if keyFunc == nil {
+		keyFunc = defaultDedupKey
+	}
+	key := keyFunc(finalJson)
+	if key == "" {
+		return ""
+	}
+	return kind + ":" + key
+}
+
+const (
+	dedupKindWorkflow = "workflow"
+	dedupKindEvent    = "event"
+)
+
+var _ DedupCache = &lruDedupCache{}
+
+type lruDedupCache struct {
+	maxEntries int
+	ttl        time.Duration
+	//
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type lruDedupEntry struct {
+	key       string
+	entry     DedupEntry
+	expiresAt time.Time
+}
+
+/*
+NewLRUDedupCache returns an in-process DedupCache which holds at most maxEntries keys,
+each for ttl duration. Least recently used keys are evicted first.
+*/
+func NewLRUDedupCache(maxEntries int, ttl time.Duration) DedupCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &lruDedupCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (l *lruDedupCache) Get(key string) (DedupEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, found := l.entries[key]
+	if !found {
+		return DedupEntry{}, false
+	}
+	lruEntry := el.Value.(*lruDedupEntry)
+	if time.Now().After(lruEntry.expiresAt) {
+		l.ll.Remove(el)
+		delete(l.entries, key)
+		return DedupEntry{}, false
+	}
+	l.ll.MoveToFront(el)
+	return lruEntry.entry, true
+}
+
+func (l *lruDedupCache) Set(key string, entry DedupEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expiresAt := time.Now().Add(l.ttl)
+	if el, found := l.entries[key]; found {
+		lruEntry := el.Value.(*lruDedupEntry)
+		lruEntry.entry, lruEntry.expiresAt = entry, expiresAt
+		l.ll.MoveToFront(el)
+		return
+	}
+	el := l.ll.PushFront(&lruDedupEntry{key: key, entry: entry, expiresAt: expiresAt})
+	l.entries[key] = el
+	for l.ll.Len() > l.maxEntries {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruDedupEntry).key)
+	}
+}
@@ -0,0 +1,111 @@
+package suprsend
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLRUDedupCacheEvictsAndExpires(t *testing.T) {
+	cache := NewLRUDedupCache(2, 50*time.Millisecond)
+	cache.Set("a", DedupEntry{StatusCode: 202, MessageId: "m-a"})
+	cache.Set("b", DedupEntry{StatusCode: 202, MessageId: "m-b"})
+	// a becomes most recently used, so b gets evicted
+	if entry, found := cache.Get("a"); !found || entry.MessageId != "m-a" {
+		t.Errorf("Get(a) = %+v, %v", entry, found)
+	}
+	cache.Set("c", DedupEntry{StatusCode: 202, MessageId: "m-c"})
+	if _, found := cache.Get("b"); found {
+		t.Error("b must be evicted")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, found := cache.Get("c"); found {
+		t.Error("c must be expired")
+	}
+}
+
+func v2EventHandler(w http.ResponseWriter, r *http.Request, body []byte) {
+	writeJson(w, 202, map[string]any{"status": "success", "message_id": "msg-single"})
+}
+
+func TestDedupSkipsRepeatedEvent(t *testing.T) {
+	ts := newTestServer(t, v2EventHandler)
+	c := newTestClient(t, ts.URL, WithDedupCache(NewLRUDedupCache(100, time.Minute), nil))
+	ev := testEvent("u1", nil)
+	ev.IdempotencyKey = "k1"
+	first, err := c.TrackEvent(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.TrackEvent(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ts.requestsTo("/v2/event/")); n != 1 {
+		t.Errorf("event calls = %d, want 1", n)
+	}
+	if second.StatusCode != first.StatusCode || second.Message != "msg-single" {
+		t.Errorf("duplicate response = %v, first = %v", second, first)
+	}
+	// event without idempotency_key is never deduped
+	c.TrackEvent(testEvent("u1", nil))
+	c.TrackEvent(testEvent("u1", nil))
+	if n := len(ts.requestsTo("/v2/event/")); n != 3 {
+		t.Errorf("event calls = %d, want 3", n)
+	}
+}
+
+func TestDedupInBulk(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if r.URL.Path == "/v2/event/" {
+			v2EventHandler(w, r, body)
+			return
+		}
+		perRecordBulkHandler(func(map[string]any) int { return 202 })(w, r, body)
+	})
+	c := newTestClient(t, ts.URL, WithDedupCache(NewLRUDedupCache(100, time.Minute), nil))
+	withKey := func(distinctId, key string) *Event {
+		ev := testEvent(distinctId, nil)
+		ev.IdempotencyKey = key
+		return ev
+	}
+	// sent as single event before bulk
+	if _, err := c.TrackEvent(withKey("u0", "k0")); err != nil {
+		t.Fatal(err)
+	}
+	bulkIns := c.BulkEvents.NewInstance()
+	bulkIns.Append(withKey("u1", "k1"), withKey("u2", "k2"), withKey("u1", "k1"), withKey("u0", "k0"))
+	resp, _ := bulkIns.Trigger()
+	reqs := ts.requestsTo("/v2/bulk/event/")
+	if len(reqs) != 1 || len(reqs[0].jsonBody(t).([]any)) != 2 {
+		t.Fatalf("bulk must send only u1 and u2 once, calls: %d", len(reqs))
+	}
+	tests := []struct {
+		messageId string
+		duplicate bool
+	}{
+		{"msg-u1", false},
+		{"msg-u2", false},
+		// duplicate of record 0 of this instance
+		{"msg-u1", true},
+		// duplicate of single event, result comes from cache
+		{"msg-single", true},
+	}
+	if resp.Total != 4 || resp.Success != 4 || len(resp.Results) != 4 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	for i, tt := range tests {
+		r := resp.Results[i]
+		if r.Index != i || r.MessageId != tt.messageId || r.Duplicate != tt.duplicate || r.StatusCode != 202 {
+			t.Errorf("result %d = %+v, want %+v", i, r, tt)
+		}
+	}
+	// records sent in bulk are cached for single path as well
+	single, err := c.TrackEvent(withKey("u2", "k2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.Message != "msg-u2" || len(ts.requestsTo("/v2/event/")) != 1 {
+		t.Errorf("single event after bulk = %v", single)
+	}
+}
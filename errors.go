@@ -9,6 +9,11 @@ var (
 	ErrMissingAPIKey     = &Error{Code: 400, Message: "suprsend: missing api_key"}
 	ErrMissingAPISecret  = &Error{Code: 400, Message: "suprsend: missing api_secret"}
 	ErrMissingBaseUrl    = &Error{Code: 400, Message: "suprsend: missing base_url"}
+	//
+	ErrProducerClosed = &Error{Code: 400, Message: "suprsend: async producer is closed"}
+	ErrOutboxClosed   = &Error{Code: 400, Message: "suprsend: outbox is closed"}
+	//
+	ErrUserExportDone = &Error{Code: 400, Message: "suprsend: user export is already done as per checkpoint file"}
 )
 
 type Error struct {
@@ -20,6 +20,9 @@ var RESERVED_EVENT_NAMES = []string{
 	"$app_launched", "$user_login", "$user_logout",
 }
 
+// minimum $time (2022-01-01T00:00:00Z) accepted by SuprSend, as unix milliseconds
+const EVENT_MIN_TIMESTAMP_MILLIS = 1640995200000
+
 type Event struct {
 	DistinctId     string
 	EventName      string
@@ -29,6 +32,9 @@ type Event struct {
 	// Brand has been renamed to Tenant. Brand is kept for backward-compatibilty.
 	// Use Tenant instead of Brand
 	BrandId string
+	// time at which event happened (e.g while backfilling past events). default: time at which it's sent.
+	// Must not be before 2022-01-01T00:00:00Z (EVENT_MIN_TIMESTAMP_MILLIS)
+	Timestamp time.Time
 }
 
 func (e *Event) validateDistinctId() error {
@@ -67,53 +73,94 @@ func (e *Event) validateEventName() error {
 	return nil
 }
 
+func (e *Event) validateTimestamp() error {
+	if !e.Timestamp.IsZero() && e.Timestamp.UnixMilli() < EVENT_MIN_TIMESTAMP_MILLIS {
+		return &Error{Message: fmt.Sprintf("event timestamp %s is before 2022-01-01T00:00:00Z, the earliest time accepted",
+			e.Timestamp.UTC().Format(time.RFC3339))}
+	}
+	return nil
+}
+
 func (e *Event) AddAttachment(filePath string, ao *AttachmentOption) error {
-	e.checkProperties()
 	attachment, err := GetAttachmentJson(filePath, ao)
 	if err != nil {
 		return err
 	}
-	if attachment == nil {
-		return nil
+	return e.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentReader adds attachment with content read from r. contentType is detected if empty.
+func (e *Event) AddAttachmentReader(fileName string, contentType string, r io.Reader, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromReader(fileName, contentType, r, ao)
+	if err != nil {
+		return err
 	}
-	// add the attachment to properties->$attachments
-	if a, found := e.Properties["$attachments"]; !found || a == nil {
-		e.Properties["$attachments"] = []map[string]any{}
+	return e.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentBytes adds attachment with in-memory content. contentType is detected if empty.
+func (e *Event) AddAttachmentBytes(fileName string, contentType string, content []byte, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromBytes(fileName, contentType, content, ao)
+	if err != nil {
+		return err
 	}
-	allAttachments := e.Properties["$attachments"].([]map[string]any)
-	allAttachments = append(allAttachments, attachment)
-	e.Properties["$attachments"] = allAttachments
-	//
+	return e.addAttachmentJson(attachment, ao)
+}
+
+// adds attachment to properties->$attachments. nil attachment (ignored due to error) is skipped
+func (e *Event) addAttachmentJson(attachment map[string]any, ao *AttachmentOption) error {
+	e.checkProperties()
+	allAttachments, _ := e.Properties["$attachments"].([]map[string]any)
+	attachment, err := applyAttachmentOptionPolicy(attachment, allAttachments, ao)
+	if err != nil || attachment == nil {
+		return err
+	}
+	e.Properties["$attachments"] = append(allAttachments, attachment)
 	return nil
 }
 
-func (e *Event) getFinalJson(client *Client, isPartOfBulk bool) (map[string]any, int, error) {
+// returns final event json, its apparent size and its json encoding (nil if it couldn't be reused as request body)
+func (e *Event) getFinalJson(client *Client, isPartOfBulk bool) (map[string]any, int, encodedJson, error) {
 	var err error
 	err = e.validateDistinctId()
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	err = e.validateEventName()
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
+	}
+	err = e.validateTimestamp()
+	if err != nil {
+		return nil, 0, nil, err
 	}
 	e.checkProperties()
+	e.Properties = normalizeEventProperties(e.Properties)
+	// derive idempotency_key (before sdk props are added) if enabled on client
+	idempotencyKey := e.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = client.autoIdempotencyKey(e.GenerateIdempotencyKey)
+	}
 	//
 	suprProps := map[string]any{"$ss_sdk_version": client.userAgent}
 	// props
 	maps.Copy(e.Properties, suprProps)
 	//
+	eventTime := time.Now()
+	if !e.Timestamp.IsZero() {
+		eventTime = e.Timestamp
+	}
 	eventMap := map[string]any{
 		"$insert_id":  uuid.New().String(),
-		"$time":       time.Now().UnixMilli(),
+		"$time":       eventTime.UnixMilli(),
 		"event":       e.EventName,
 		"env":         client.getWsIdentifierValue(),
 		"distinct_id": e.DistinctId,
 		"properties":  e.Properties,
 	}
 	// Add idempotency_key if present
-	if e.IdempotencyKey != "" {
-		eventMap["$idempotency_key"] = e.IdempotencyKey
+	if idempotencyKey != "" {
+		eventMap["$idempotency_key"] = idempotencyKey
 	}
 	// Add tenant_id if present
 	if e.TenantId != "" {
@@ -122,21 +169,46 @@ func (e *Event) getFinalJson(client *Client, isPartOfBulk bool) (map[string]any,
 	if e.BrandId != "" {
 		eventMap["brand_id"] = e.BrandId
 	}
-	eventMap, err = validateTrackEventSchema(eventMap)
+	eventMap, err = validateTrackEventSchema(eventMap, client.validationMode)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
+	}
+	// check attachments against client's attachment policy
+	eventMap, err = client.applyAttachmentPolicy(eventMap, "properties")
+	if err != nil {
+		return nil, 0, nil, err
 	}
 	// Check request size
-	apparentSize, err := getApparentEventSize(eventMap, isPartOfBulk)
+	apparentSize, encoded, err := getApparentEventSize(client, eventMap, isPartOfBulk)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	if apparentSize > BODY_MAX_APPARENT_SIZE_IN_BYTES {
 		errStr := fmt.Sprintf("event size too big - %d Bytes, must not cross %s", apparentSize,
 			BODY_MAX_APPARENT_SIZE_IN_BYTES_READABLE)
-		return nil, 0, &Error{Code: 413, Message: errStr}
+		return nil, 0, nil, &Error{Code: 413, Message: errStr}
 	}
-	return eventMap, apparentSize, nil
+	return eventMap, apparentSize, encoded, nil
+}
+
+/*
+EstimateSize returns apparent size (in bytes) of event, as checked against BODY_MAX_APPARENT_SIZE_IN_BYTES
+before it's sent. Event is validated as well. Event itself is not modified.
+*/
+func (e *Event) EstimateSize(client *Client) (int, error) {
+	eCopy := e.shallowCopy()
+	_, apparentSize, _, err := eCopy.getFinalJson(client, false)
+	return apparentSize, err
+}
+
+/*
+copy used where event is only validated (e.g EstimateSize): only top-level of Properties (which sdk modifies
+while sending) is copied, nested values are shared with caller and must not be modified by sdk.
+*/
+func (e *Event) shallowCopy() Event {
+	eCopy := *e
+	eCopy.Properties = maps.Clone(e.Properties)
+	return eCopy
 }
 
 func (e *Event) asJson() map[string]any {
@@ -173,26 +245,45 @@ func newEventCollectorInstance(client *Client) *eventsCollector {
 	return ec
 }
 
-func (e *eventsCollector) Collect(event *Event) (*Response, error) {
-	eventMap, _, err := event.getFinalJson(e.client, false)
+func (e *eventsCollector) Collect(event *Event, reqOpts ...RequestOption) (*Response, error) {
+	eventMap, _, encoded, err := event.getFinalJson(e.client, false)
 	if err != nil {
 		return nil, err
 	}
-	suprResp, err := e.send(eventMap)
+	// skip if same event has already been sent
+	dedupKey := e.client.dedupKey(dedupKindEvent, eventMap)
+	if dedupKey != "" {
+		if cachedEntry, found := e.client.dedupCache.Get(dedupKey); found {
+			return cachedEntry.response(), nil
+		}
+	}
+	var reqBody any = eventMap
+	if encoded != nil {
+		reqBody = encoded
+	}
+	if uploadedBody, uploaded, err := e.client.uploadAttachmentsInBody(newRequestOptions(reqOpts).callContext(), eventMap, "properties", nil); err != nil {
+		return nil, err
+	} else if uploaded {
+		reqBody = uploadedBody
+	}
+	suprResp, err := e.send(reqBody, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
+	if dedupKey != "" {
+		e.client.dedupCache.Set(dedupKey, dedupEntryOfResponse(suprResp))
+	}
 	return suprResp, nil
 }
 
-func (e *eventsCollector) send(eventMap map[string]any) (*Response, error) {
+func (e *eventsCollector) send(eventBody any, reqOpts ...RequestOption) (*Response, error) {
 	// prepare http.Request object
-	request, err := e.client.prepareHttpRequest("POST", e._url, eventMap)
+	request, err := e.client.prepareHttpRequest("POST", e._url, eventBody, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := e.client.httpClient.Do(request)
+	httpResponse, err := e.client.send(newRequestOptions(reqOpts).callContext(), request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +305,7 @@ func parseV2EventResponse(httpRes *http.Response) (*Response, error) {
 		OR
 		{"status": "error", "error": {"message": "string", "type": "string"}} // error
 	*/
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
 		return nil, &Error{Err: err}
 	}
@@ -0,0 +1,158 @@
+package suprsend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+NewEvent returns event with properties taken from props, which can be a struct (or pointer to struct)
+with json tags, or a map. props is marshalled to json, so json tags (omitempty, "-", custom MarshalJSON) apply.
+props must marshal to a json object. nil props gives empty properties.
+Timestamp can be set on returned event for past events; it must not be before 2022-01-01 (EVENT_MIN_TIMESTAMP_MILLIS).
+*/
+func NewEvent[T any](distinctId string, eventName string, props T) (*Event, error) {
+	properties, err := propertiesFromValue(props)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		DistinctId: distinctId,
+		EventName:  eventName,
+		Properties: properties,
+	}, nil
+}
+
+func propertiesFromValue(props any) (map[string]any, error) {
+	if m, ok := props.(map[string]any); ok {
+		if m == nil {
+			return map[string]any{}, nil
+		}
+		return normalizeEventProperties(m), nil
+	}
+	propsJson, err := json.Marshal(props)
+	if err != nil {
+		return nil, &Error{Message: "event properties: " + err.Error(), Err: err}
+	}
+	if string(propsJson) == "null" {
+		return map[string]any{}, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(propsJson))
+	// numbers are decoded as json.Number, so that large integers don't lose precision as float64
+	decoder.UseNumber()
+	properties := map[string]any{}
+	if err = decoder.Decode(&properties); err != nil {
+		return nil, &Error{Message: "event properties must be a json object (struct or map)", Err: err}
+	}
+	return normalizeEventProperties(properties), nil
+}
+
+/*
+Normalizes property values to what they'd be after a json round-trip, so that properties set as map
+and as struct give same event. time.Time is formatted as RFC3339 (same as json), json.Number is converted
+to int64 (float64 if it's not an integer), and []byte is base64-encoded (same as json).
+Returns properties as-is if nothing needs to change, else a copy; caller's maps are never modified.
+*/
+func normalizeEventProperties(properties map[string]any) map[string]any {
+	normalized, changed := normalizeMap(properties)
+	if !changed {
+		return properties
+	}
+	return normalized
+}
+
+func normalizeMap(m map[string]any) (map[string]any, bool) {
+	var out map[string]any
+	for k, v := range m {
+		nv, changed := normalizePropertyValue(v)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(m)
+		}
+		out[k] = nv
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
+
+func normalizePropertyValue(v any) (any, bool) {
+	switch tv := v.(type) {
+	case time.Time:
+		return formatPropertyTime(tv), true
+	case *time.Time:
+		if tv == nil {
+			return nil, true
+		}
+		return formatPropertyTime(*tv), true
+	case json.Number:
+		return normalizeJsonNumber(tv), true
+	case []byte:
+		if tv == nil {
+			return nil, true
+		}
+		return base64.StdEncoding.EncodeToString(tv), true
+	case map[string]any:
+		return normalizeMap(tv)
+	case []any:
+		var out []any
+		for i, item := range tv {
+			nv, changed := normalizePropertyValue(item)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]any{}, tv...)
+			}
+			out[i] = nv
+		}
+		if out == nil {
+			return tv, false
+		}
+		return out, true
+	case []map[string]any:
+		// kept as []map[string]any, as sdk expects $attachments in this type
+		var out []map[string]any
+		for i, item := range tv {
+			nv, changed := normalizeMap(item)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]map[string]any{}, tv...)
+			}
+			out[i] = nv
+		}
+		if out == nil {
+			return tv, false
+		}
+		return out, true
+	}
+	return v, false
+}
+
+// same format in which encoding/json marshals time.Time
+func formatPropertyTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func normalizeJsonNumber(n json.Number) any {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
@@ -0,0 +1,156 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type orderProps struct {
+	OrderId  string    `json:"order_id"`
+	Amount   int64     `json:"amount"`
+	Note     string    `json:"note,omitempty"`
+	Secret   string    `json:"-"`
+	PlacedAt time.Time `json:"placed_at"`
+	Raw      []byte    `json:"raw"`
+}
+
+func TestNewEventProperties(t *testing.T) {
+	placedAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	order := orderProps{OrderId: "o1", Amount: 9007199254740993, Secret: "s", PlacedAt: placedAt, Raw: []byte("hi")}
+	want := map[string]any{
+		"order_id": "o1", "amount": int64(9007199254740993),
+		"placed_at": "2024-05-01T10:30:00Z", "raw": "aGk=",
+	}
+	tests := []struct {
+		name    string
+		props   any
+		want    map[string]any
+		wantErr string
+	}{
+		{"struct", order, want, ""},
+		{"pointer to struct", &order, want, ""},
+		{"map normalized like struct", map[string]any{
+			"order_id": "o1", "amount": json.Number("9007199254740993"),
+			"placed_at": placedAt, "raw": []byte("hi"),
+		}, want, ""},
+		{"nested values normalized", map[string]any{
+			"items": []any{map[string]any{"at": placedAt, "qty": json.Number("2.5")}},
+		}, map[string]any{
+			"items": []any{map[string]any{"at": "2024-05-01T10:30:00Z", "qty": 2.5}},
+		}, ""},
+		{"nil map", map[string]any(nil), map[string]any{}, ""},
+		{"nil pointer", (*orderProps)(nil), map[string]any{}, ""},
+		{"not an object", []string{"a"}, nil, "must be a json object"},
+		{"unmarshalable", struct{ C chan int }{}, nil, "event properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, err := NewEvent("u1", "order_placed", tt.props)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ev.DistinctId != "u1" || ev.EventName != "order_placed" {
+				t.Errorf("event = %+v", ev)
+			}
+			if !reflect.DeepEqual(ev.Properties, tt.want) {
+				t.Errorf("properties = %#v, want %#v", ev.Properties, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEventDoesNotModifyCallerMap(t *testing.T) {
+	placedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	props := map[string]any{"placed_at": placedAt}
+	if _, err := NewEvent("u1", "order_placed", props); err != nil {
+		t.Fatal(err)
+	}
+	if props["placed_at"] != placedAt {
+		t.Errorf("caller map modified: %v", props)
+	}
+}
+
+func TestEventTimestamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		mode      ValidationMode
+		wantTime  int64
+		wantErr   string
+	}{
+		{"past event", time.UnixMilli(1700000000123), ValidationStrict, 1700000000123, ""},
+		{"earliest accepted", time.UnixMilli(EVENT_MIN_TIMESTAMP_MILLIS), ValidationStrict, EVENT_MIN_TIMESTAMP_MILLIS, ""},
+		{"before 2022 strict", time.Date(2021, 12, 31, 23, 59, 59, 0, time.UTC), ValidationStrict, 0, "before 2022-01-01"},
+		{"before 2022 with validation off", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ValidationOff, 0, "before 2022-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, v2EventHandler)
+			c := newTestClient(t, ts.URL, WithValidation(tt.mode))
+			ev := testEvent("u1", nil)
+			ev.Timestamp = tt.timestamp
+			_, err := c.TrackEvent(ev)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				if n := len(ts.Requests()); n != 0 {
+					t.Errorf("requests = %d, want 0", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent := ts.requestsTo("/v2/event/")[0].jsonBody(t).(map[string]any)
+			if got := sent["$time"]; got != json.Number(itoa(int(tt.wantTime))) {
+				t.Errorf("$time = %v, want %d", got, tt.wantTime)
+			}
+		})
+	}
+	// without Timestamp, time of sending is used
+	ts := newTestServer(t, v2EventHandler)
+	c := newTestClient(t, ts.URL)
+	before := time.Now().UnixMilli()
+	if _, err := c.TrackEvent(testEvent("u1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	sentTime, _ := ts.requestsTo("/v2/event/")[0].jsonBody(t).(map[string]any)["$time"].(json.Number).Int64()
+	if sentTime < before || sentTime > time.Now().UnixMilli() {
+		t.Errorf("$time = %d, want time of sending", sentTime)
+	}
+}
+
+func TestEventIdempotencyKeyIncludesTimestamp(t *testing.T) {
+	key := func(ts time.Time) string {
+		t.Helper()
+		ev := testEvent("u1", map[string]any{"amount": 10})
+		ev.Timestamp = ts
+		k, err := ev.GenerateIdempotencyKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	noTime := key(time.Time{})
+	t1 := key(time.UnixMilli(1700000000000))
+	if t1 == noTime {
+		t.Error("key with timestamp must differ from key without")
+	}
+	if t2 := key(time.UnixMilli(1700000001000)); t2 == t1 {
+		t.Error("events at different times must have different keys")
+	}
+	// same instant in another location is same event
+	if same := key(time.UnixMilli(1700000000000).In(time.FixedZone("IST", 19800))); same != t1 {
+		t.Errorf("key = %s, want %s", same, t1)
+	}
+}
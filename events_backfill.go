@@ -0,0 +1,555 @@
+package suprsend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BackfillFormat string
+
+const (
+	BackfillFormatJSONL BackfillFormat = "jsonl"
+	BackfillFormatCSV   BackfillFormat = "csv"
+)
+
+/*
+Keys (jsonl) or header columns (csv) from which event fields are read.
+In csv, properties column (if present) must have a json object. Every other column which isn't mapped
+to a field is added to properties as string.
+*/
+type BackfillColumnMapping struct {
+	// default: distinct_id
+	DistinctId string
+	// default: event
+	EventName string
+	// default: properties
+	Properties string
+	// unix epoch (seconds or milliseconds) or RFC3339 time. default: timestamp
+	Timestamp string
+	// default: idempotency_key
+	IdempotencyKey string
+	// default: tenant_id
+	TenantId string
+}
+
+func (m *BackfillColumnMapping) cleanParams() {
+	defaults := []struct {
+		field *string
+		value string
+	}{
+		{&m.DistinctId, "distinct_id"},
+		{&m.EventName, "event"},
+		{&m.Properties, "properties"},
+		{&m.Timestamp, "timestamp"},
+		{&m.IdempotencyKey, "idempotency_key"},
+		{&m.TenantId, "tenant_id"},
+	}
+	for _, d := range defaults {
+		*d.field = strings.TrimSpace(*d.field)
+		if *d.field == "" {
+			*d.field = d.value
+		}
+	}
+}
+
+func (m *BackfillColumnMapping) isMapped(column string) bool {
+	switch column {
+	case m.DistinctId, m.EventName, m.Properties, m.Timestamp, m.IdempotencyKey, m.TenantId:
+		return true
+	}
+	return false
+}
+
+type BackfillOptions struct {
+	// default: jsonl. BackfillFile picks it from file extension (.csv/.jsonl/.ndjson) if not set
+	Format  BackfillFormat
+	Columns BackfillColumnMapping
+	// records sent per bulk api call. default: client's max events in bulk
+	BatchSize int
+	/*
+		progress is saved to this file after every batch. If file exists, records already done
+		as per it are skipped, so that an interrupted backfill resumes from where it stopped.
+		A batch interrupted before its checkpoint is resent, map an idempotency key column to make it safe.
+	*/
+	CheckpointFile string
+	// records which could not be sent are appended to this file (jsonl), with their line number and error
+	RejectsFile string
+	// called after every batch
+	OnProgress func(BackfillReport)
+}
+
+func (o *BackfillOptions) cleanParams(c *Client) {
+	o.Format = BackfillFormat(strings.ToLower(strings.TrimSpace(string(o.Format))))
+	if o.Format == "" {
+		o.Format = BackfillFormatJSONL
+	}
+	o.Columns.cleanParams()
+	if o.BatchSize <= 0 || o.BatchSize > c.bulkLimits.MaxEventsInBulk {
+		o.BatchSize = c.bulkLimits.MaxEventsInBulk
+	}
+}
+
+type BackfillReport struct {
+	// records read from source, including skipped ones
+	Total int
+	// records skipped as they were done before resuming (as per checkpoint file)
+	Skipped int
+	Success int
+	// records which could not be parsed or sent. These are written to rejects file
+	Failure int
+}
+
+func (r BackfillReport) String() string {
+	return fmt.Sprintf("BackfillReport{Total: %v, Skipped: %v, Success: %v, Failure: %v}",
+		r.Total, r.Skipped, r.Success, r.Failure)
+}
+
+// single line in rejects file
+type backfillReject struct {
+	Line       int    `json:"line"`
+	Record     any    `json:"record"`
+	Error      string `json:"error"`
+	ErrorType  string `json:"error_type,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// content of checkpoint file
+type backfillCheckpoint struct {
+	RecordsDone int   `json:"records_done"`
+	Success     int   `json:"success"`
+	Failure     int   `json:"failure"`
+	UpdatedAt   int64 `json:"updated_at"`
+}
+
+// record read from jsonl/csv source, before it's converted to event/user edit
+type sourceRecord struct {
+	line int
+	// raw record, written to rejects file as-is
+	raw    any
+	fields map[string]any
+	err    error
+}
+
+/*
+BackfillFile streams events from a jsonl/csv file into bulk event api, in batches.
+See Backfill for details.
+*/
+func (b *bulkEventsService) BackfillFile(ctx context.Context, path string, opts *BackfillOptions) (*BackfillReport, error) {
+	var o BackfillOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			o.Format = BackfillFormatCSV
+		default:
+			o.Format = BackfillFormatJSONL
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	defer f.Close()
+	return b.Backfill(ctx, f, &o)
+}
+
+/*
+Backfill streams events (e.g historical events while migrating) from r into bulk event api. Events are read,
+sent and forgotten one batch at a time, so memory use doesn't grow with size of source.
+Timestamp of each record is sent as time of the event. Records which can't be parsed or sent are
+written to rejects file, and progress is checkpointed after each batch (see BackfillOptions).
+Returns error only if backfill can't continue (e.g source/checkpoint file can't be read, ctx is done),
+along with the report till then.
+*/
+func (b *bulkEventsService) Backfill(ctx context.Context, r io.Reader, opts *BackfillOptions) (*BackfillReport, error) {
+	if opts == nil {
+		opts = &BackfillOptions{}
+	}
+	o := *opts
+	o.cleanParams(b.client)
+	bf := &backfill{service: b, opts: &o, report: &BackfillReport{}}
+	err := bf.run(ctx, r)
+	return bf.report, err
+}
+
+type backfill struct {
+	service    *bulkEventsService
+	opts       *BackfillOptions
+	report     *BackfillReport
+	checkpoint backfillCheckpoint
+	rejects    *os.File
+}
+
+func (bf *backfill) run(ctx context.Context, r io.Reader) error {
+	if err := bf.loadCheckpoint(); err != nil {
+		return err
+	}
+	if bf.opts.RejectsFile != "" {
+		f, err := os.OpenFile(bf.opts.RejectsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return &Error{Err: err}
+		}
+		defer f.Close()
+		bf.rejects = f
+	}
+	var next func() (*sourceRecord, error)
+	switch bf.opts.Format {
+	case BackfillFormatJSONL:
+		next = jsonlRecordReader(r)
+	case BackfillFormatCSV:
+		var err error
+		next, err = csvRecordReader(r, &bf.opts.Columns)
+		if err != nil {
+			return err
+		}
+	default:
+		return &Error{Message: fmt.Sprintf("backfill: unsupported format %s", bf.opts.Format)}
+	}
+	batch := make([]*sourceRecord, 0, bf.opts.BatchSize)
+	for {
+		rec, err := next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &Error{Err: err}
+		}
+		if rec != nil {
+			bf.report.Total++
+			if bf.report.Total <= bf.checkpoint.RecordsDone {
+				bf.report.Skipped++
+				continue
+			}
+			batch = append(batch, rec)
+		}
+		if len(batch) == bf.opts.BatchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+			if serr := bf.sendBatch(batch); serr != nil {
+				return serr
+			}
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+func (bf *backfill) sendBatch(batch []*sourceRecord) error {
+	bulkIns := bf.service.NewInstance()
+	// index in bulk instance -> record
+	sent := []*sourceRecord{}
+	for _, rec := range batch {
+		ev, err := rec.event(&bf.opts.Columns)
+		if err == nil {
+			err = rec.err
+		}
+		if err != nil {
+			bf.reject(backfillReject{Line: rec.line, Record: rec.raw, Error: err.Error(),
+				ErrorType: BULK_RECORD_ERROR_TYPE_VALIDATION})
+			continue
+		}
+		bulkIns.Append(ev)
+		sent = append(sent, rec)
+	}
+	if len(sent) > 0 {
+		bulkResponse, err := bulkIns.Trigger()
+		if err != nil {
+			return err
+		}
+		for _, res := range bulkResponse.Results {
+			if res.Status == "success" {
+				bf.report.Success++
+				continue
+			}
+			rec := sent[res.Index]
+			bf.reject(backfillReject{Line: rec.line, Record: rec.raw, Error: res.Error,
+				ErrorType: res.ErrorType, StatusCode: res.StatusCode})
+		}
+	}
+	return bf.saveCheckpoint()
+}
+
+func (bf *backfill) reject(rj backfillReject) {
+	bf.report.Failure++
+	if bf.rejects == nil {
+		return
+	}
+	line, err := json.Marshal(rj)
+	if err != nil {
+		log.Printf("WARNING: backfill: error while encoding rejected record of line %d: %v", rj.Line, err)
+		return
+	}
+	if _, err = bf.rejects.Write(append(line, '\n')); err != nil {
+		log.Printf("WARNING: backfill: error while writing rejected record of line %d: %v", rj.Line, err)
+	}
+}
+
+func (bf *backfill) loadCheckpoint() error {
+	if bf.opts.CheckpointFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(bf.opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return &Error{Err: err}
+	}
+	if err = json.Unmarshal(content, &bf.checkpoint); err != nil {
+		return &Error{Message: fmt.Sprintf("backfill: invalid checkpoint file %s: %v", bf.opts.CheckpointFile, err)}
+	}
+	// counts of earlier run(s) are carried over, so that report covers whole backfill
+	bf.report.Success, bf.report.Failure = bf.checkpoint.Success, bf.checkpoint.Failure
+	return nil
+}
+
+func (bf *backfill) saveCheckpoint() error {
+	bf.checkpoint = backfillCheckpoint{
+		RecordsDone: bf.report.Total,
+		Success:     bf.report.Success,
+		Failure:     bf.report.Failure,
+		UpdatedAt:   time.Now().UnixMilli(),
+	}
+	if bf.opts.OnProgress != nil {
+		bf.opts.OnProgress(*bf.report)
+	}
+	if bf.opts.CheckpointFile == "" {
+		return nil
+	}
+	if bf.rejects != nil {
+		// rejects of a batch must be durable before batch is marked done
+		if err := bf.rejects.Sync(); err != nil {
+			return &Error{Err: err}
+		}
+	}
+	content, err := json.Marshal(bf.checkpoint)
+	if err != nil {
+		return &Error{Err: err}
+	}
+	return writeFileAtomic(bf.opts.CheckpointFile, content)
+}
+
+// converts record to event as per column mapping
+func (rec *sourceRecord) event(cols *BackfillColumnMapping) (*Event, error) {
+	if rec.fields == nil {
+		return nil, rec.err
+	}
+	ev := &Event{
+		DistinctId:     stringField(rec.fields[cols.DistinctId]),
+		EventName:      stringField(rec.fields[cols.EventName]),
+		IdempotencyKey: stringField(rec.fields[cols.IdempotencyKey]),
+		TenantId:       stringField(rec.fields[cols.TenantId]),
+	}
+	switch props := rec.fields[cols.Properties].(type) {
+	case nil:
+		ev.Properties = map[string]any{}
+	case map[string]any:
+		ev.Properties = props
+	default:
+		return nil, fmt.Errorf("%s must be a json object", cols.Properties)
+	}
+	if ts, found := rec.fields[cols.Timestamp]; found && ts != nil && ts != "" {
+		eventTime, err := parseBackfillTimestamp(ts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cols.Timestamp, err)
+		}
+		ev.Timestamp = eventTime
+	}
+	return ev, nil
+}
+
+func stringField(v any) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	case json.Number:
+		return tv.String()
+	}
+	return fmt.Sprint(v)
+}
+
+var backfillTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// accepts unix epoch in seconds or milliseconds, or time string. Time without zone is taken as UTC
+func parseBackfillTimestamp(v any) (time.Time, error) {
+	var s string
+	switch tv := v.(type) {
+	case json.Number:
+		s = tv.String()
+	case float64:
+		s = strconv.FormatFloat(tv, 'f', -1, 64)
+	case string:
+		s = strings.TrimSpace(tv)
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp %v", v)
+	}
+	if epoch, err := strconv.ParseFloat(s, 64); err == nil {
+		// epoch in milliseconds has 13 digits for present times, in seconds only 10
+		if epoch >= 1e11 {
+			return time.UnixMilli(int64(epoch)), nil
+		}
+		return time.UnixMilli(int64(epoch * 1000)), nil
+	}
+	for _, layout := range backfillTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %s, must be unix epoch or RFC3339 time", s)
+}
+
+// returns function which reads next record from jsonl source. Blank lines are skipped.
+func jsonlRecordReader(r io.Reader) func() (*sourceRecord, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	lineNum := 0
+	return func() (*sourceRecord, error) {
+		for {
+			line, err := br.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			lineNum++
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			rec := &sourceRecord{line: lineNum, raw: json.RawMessage(line)}
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			if derr := decoder.Decode(&rec.fields); derr != nil {
+				rec.fields = nil
+				rec.raw = string(line)
+				rec.err = fmt.Errorf("invalid json: %v", derr)
+			}
+			return rec, err
+		}
+	}
+}
+
+// row of csv source
+type csvRow struct {
+	line int
+	// header, in order of columns
+	columns []string
+	// column -> value
+	values map[string]string
+	// row as read, for reporting
+	raw string
+	// set if row is malformed. values has columns read till then
+	err error
+}
+
+// returns function which reads next row from csv source. First row must be header.
+func csvRowReader(r io.Reader) (func() (*csvRow, error), error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return func() (*csvRow, error) { return nil, io.EOF }, nil
+	}
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	header = append([]string{}, header...)
+	// utf-8 BOM (added by excel) is not part of first column name
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	return func() (*csvRow, error) {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		line, _ := cr.FieldPos(0)
+		row := &csvRow{line: line, columns: header, raw: strings.Join(fields, ",")}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			row.err = err
+			return row, nil
+		} else if err != nil {
+			return nil, err
+		}
+		row.values = map[string]string{}
+		for i, value := range fields {
+			if i >= len(header) {
+				row.err = fmt.Errorf("row has %d columns, header has %d", len(fields), len(header))
+				break
+			}
+			row.values[header[i]] = value
+		}
+		return row, nil
+	}, nil
+}
+
+// returns function which reads next record from csv source. First row must be header.
+func csvRecordReader(r io.Reader, cols *BackfillColumnMapping) (func() (*sourceRecord, error), error) {
+	nextRow, err := csvRowReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return func() (*sourceRecord, error) {
+		row, err := nextRow()
+		if err != nil {
+			return nil, err
+		}
+		rec := &sourceRecord{line: row.line}
+		if row.values == nil {
+			rec.raw, rec.err = row.raw, row.err
+			return rec, nil
+		}
+		rec.fields = map[string]any{}
+		props := map[string]any{}
+		for _, col := range row.columns {
+			value, found := row.values[col]
+			if !found {
+				continue
+			}
+			switch {
+			case col == cols.Properties:
+				if strings.TrimSpace(value) == "" {
+					continue
+				}
+				decoder := json.NewDecoder(strings.NewReader(value))
+				decoder.UseNumber()
+				colProps := map[string]any{}
+				if derr := decoder.Decode(&colProps); derr != nil {
+					rec.err = fmt.Errorf("%s: invalid json object: %v", col, derr)
+					continue
+				}
+				for k, v := range colProps {
+					props[k] = v
+				}
+			case cols.isMapped(col):
+				rec.fields[col] = value
+			default:
+				props[col] = value
+			}
+		}
+		if row.err != nil {
+			rec.err = row.err
+		}
+		rec.fields[cols.Properties] = props
+		rec.raw = row.values
+		return rec, nil
+	}, nil
+}
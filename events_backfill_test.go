@@ -0,0 +1,268 @@
+package suprsend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// events sent to bulk event api across all requests, in order
+func sentBulkEvents(t *testing.T, ts *testServer) []map[string]any {
+	t.Helper()
+	events := []map[string]any{}
+	for _, req := range ts.requestsTo("/v2/bulk/event/") {
+		for _, ev := range req.jsonBody(t).([]any) {
+			events = append(events, ev.(map[string]any))
+		}
+	}
+	return events
+}
+
+func readJsonLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := []map[string]any{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := map[string]any{}
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.UseNumber()
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestBackfillJSONL(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	rejectsFile := filepath.Join(t.TempDir(), "rejects.jsonl")
+	src := strings.Join([]string{
+		`{"distinct_id": "u1", "event": "order_placed", "properties": {"amount": 10}, "timestamp": 1700000000}`,
+		``,
+		`{"distinct_id": "u2", "event": "order_placed", "timestamp": "2024-05-01T10:30:00Z", "idempotency_key": "k2"}`,
+		`{not json`,
+		`{"distinct_id": "u3", "event": "order_placed", "timestamp": 1700000000123, "tenant_id": "t1"}`,
+		`{"distinct_id": "u4", "event": "order_placed", "properties": "oops"}`,
+		`{"distinct_id": "u5", "event": "order_placed"}`,
+	}, "\n")
+	progress := []BackfillReport{}
+	report, err := c.BulkEvents.Backfill(context.Background(), strings.NewReader(src), &BackfillOptions{
+		BatchSize: 2, RejectsFile: rejectsFile,
+		OnProgress: func(r BackfillReport) { progress = append(progress, r) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BackfillReport{Total: 6, Success: 4, Failure: 2}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	if len(progress) != 3 {
+		t.Errorf("progress calls = %d, want 3 (one per batch)", len(progress))
+	}
+	if n := len(ts.requestsTo("/v2/bulk/event/")); n != 3 {
+		t.Errorf("bulk calls = %d, want 3", n)
+	}
+	events := sentBulkEvents(t, ts)
+	wantTimes := map[string]string{"u1": "1700000000000", "u2": "1714559400000", "u3": "1700000000123"}
+	gotIds := []string{}
+	for _, ev := range events {
+		id := ev["distinct_id"].(string)
+		gotIds = append(gotIds, id)
+		if want, found := wantTimes[id]; found && ev["$time"] != json.Number(want) {
+			t.Errorf("%s: $time = %v, want %s", id, ev["$time"], want)
+		}
+	}
+	if !reflect.DeepEqual(gotIds, []string{"u1", "u2", "u3", "u5"}) {
+		t.Errorf("sent = %v", gotIds)
+	}
+	if events[1]["$idempotency_key"] != "k2" || events[2]["tenant_id"] != "t1" {
+		t.Errorf("idempotency_key/tenant_id not mapped: %v, %v", events[1], events[2])
+	}
+	rejects := readJsonLines(t, rejectsFile)
+	if len(rejects) != 2 {
+		t.Fatalf("rejects = %v", rejects)
+	}
+	if rejects[0]["line"] != json.Number("4") || rejects[0]["record"] != "{not json" ||
+		!strings.Contains(rejects[0]["error"].(string), "invalid json") {
+		t.Errorf("reject = %v", rejects[0])
+	}
+	if rejects[1]["line"] != json.Number("6") || rejects[1]["error_type"] != BULK_RECORD_ERROR_TYPE_VALIDATION {
+		t.Errorf("reject = %v", rejects[1])
+	}
+}
+
+func TestBackfillCSV(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.csv")
+	rejectsFile := filepath.Join(dir, "rejects.jsonl")
+	src := "\ufeffuser,name,at,props,plan\n" +
+		"u1,signed_up,1700000000,\"{\"\"source\"\": \"\"ads\"\"}\",pro\n" +
+		"u2,signed_up,,,free,extra\n" +
+		"u3,signed_up,yesterday,,free\n" +
+		"u4,signed_up,2024-05-01 10:30:00,,\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := c.BulkEvents.BackfillFile(context.Background(), path, &BackfillOptions{
+		Columns:     BackfillColumnMapping{DistinctId: "user", EventName: "name", Timestamp: "at", Properties: "props"},
+		RejectsFile: rejectsFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BackfillReport{Total: 4, Success: 2, Failure: 2}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	events := sentBulkEvents(t, ts)
+	if len(events) != 2 {
+		t.Fatalf("sent = %v", events)
+	}
+	props := events[0]["properties"].(map[string]any)
+	if events[0]["distinct_id"] != "u1" || events[0]["event"] != "signed_up" ||
+		events[0]["$time"] != json.Number("1700000000000") || props["source"] != "ads" || props["plan"] != "pro" {
+		t.Errorf("event = %v", events[0])
+	}
+	if events[1]["distinct_id"] != "u4" || events[1]["$time"] != json.Number("1714559400000") {
+		t.Errorf("event = %v", events[1])
+	}
+	rejects := readJsonLines(t, rejectsFile)
+	if len(rejects) != 2 {
+		t.Fatalf("rejects = %v", rejects)
+	}
+	if rejects[0]["line"] != json.Number("3") || !strings.Contains(rejects[0]["error"].(string), "columns") {
+		t.Errorf("reject = %v", rejects[0])
+	}
+	if rejects[1]["line"] != json.Number("4") || !strings.Contains(rejects[1]["error"].(string), "at: invalid timestamp") {
+		t.Errorf("reject = %v", rejects[1])
+	}
+}
+
+func TestBackfillRejectsRecordsFailedByServer(t *testing.T) {
+	ts := newTestServer(t, perRecordBulkHandler(func(rec map[string]any) int {
+		if rec["distinct_id"] == "u2" {
+			return 400
+		}
+		return 202
+	}))
+	c := newTestClient(t, ts.URL)
+	rejectsFile := filepath.Join(t.TempDir(), "rejects.jsonl")
+	src := `{"distinct_id": "u1", "event": "e1"}
+{"distinct_id": "u2", "event": "e1"}
+{"distinct_id": "u3", "event": "e1"}`
+	report, err := c.BulkEvents.Backfill(context.Background(), strings.NewReader(src),
+		&BackfillOptions{RejectsFile: rejectsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BackfillReport{Total: 3, Success: 2, Failure: 1}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	rejects := readJsonLines(t, rejectsFile)
+	if len(rejects) != 1 || rejects[0]["line"] != json.Number("2") || rejects[0]["status_code"] != json.Number("400") {
+		t.Fatalf("rejects = %v", rejects)
+	}
+	if record := rejects[0]["record"].(map[string]any); record["distinct_id"] != "u2" {
+		t.Errorf("rejected record = %v, want source record as-is", record)
+	}
+}
+
+func TestBackfillResumesFromCheckpoint(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	lines := []string{}
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, `{"distinct_id": "u`+itoa(i)+`", "event": "e1"}`)
+	}
+	src := strings.Join(lines, "\n")
+	opts := &BackfillOptions{BatchSize: 2, CheckpointFile: checkpointFile}
+
+	// first run is interrupted after first batch
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.OnProgress = func(BackfillReport) { cancel() }
+	report, err := c.BulkEvents.Backfill(ctx, strings.NewReader(src), opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if report.Success != 2 {
+		t.Errorf("report = %v", report)
+	}
+	var checkpoint backfillCheckpoint
+	content, _ := os.ReadFile(checkpointFile)
+	if err = json.Unmarshal(content, &checkpoint); err != nil || checkpoint.RecordsDone != 2 || checkpoint.Success != 2 {
+		t.Fatalf("checkpoint = %s, %v", content, err)
+	}
+
+	// second run skips records done as per checkpoint, and carries over counts
+	opts.OnProgress = nil
+	report, err = c.BulkEvents.Backfill(context.Background(), strings.NewReader(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BackfillReport{Total: 5, Skipped: 2, Success: 5}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	gotIds := []string{}
+	for _, ev := range sentBulkEvents(t, ts) {
+		gotIds = append(gotIds, ev["distinct_id"].(string))
+	}
+	if !reflect.DeepEqual(gotIds, []string{"u1", "u2", "u3", "u4", "u5"}) {
+		t.Errorf("sent = %v, want each record once", gotIds)
+	}
+}
+
+func TestBackfillInvalidCheckpoint(t *testing.T) {
+	c := newTestClient(t, "http://localhost:1")
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	os.WriteFile(checkpointFile, []byte("{"), 0o644)
+	_, err := c.BulkEvents.Backfill(context.Background(), strings.NewReader(""),
+		&BackfillOptions{CheckpointFile: checkpointFile})
+	if err == nil || !strings.Contains(err.Error(), "invalid checkpoint file") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseBackfillTimestamp(t *testing.T) {
+	tests := []struct {
+		value   any
+		want    time.Time
+		wantErr bool
+	}{
+		{json.Number("1700000000"), time.UnixMilli(1700000000000), false},
+		{json.Number("1700000000.5"), time.UnixMilli(1700000000500), false},
+		{json.Number("1700000000123"), time.UnixMilli(1700000000123), false},
+		{1700000000.0, time.UnixMilli(1700000000000), false},
+		{"1700000000123", time.UnixMilli(1700000000123), false},
+		{"2024-05-01T10:30:00+05:30", time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC), false},
+		{"2024-05-01T10:30:00", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), false},
+		{" 2024-05-01 10:30:00 ", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), false},
+		{"01/05/2024", time.Time{}, true},
+		{true, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseBackfillTimestamp(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBackfillTimestamp(%v) err = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseBackfillTimestamp(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
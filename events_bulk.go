@@ -1,10 +1,9 @@
 package suprsend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 
 	"github.com/go-viper/mapstructure/v2"
@@ -17,65 +16,41 @@ type bulkEventsService struct {
 
 func (b *bulkEventsService) NewInstance() BulkEvents {
 	return &bulkEvents{
-		client:   b.client,
-		response: &BulkResponse{},
+		engine: newBulkEngine(b.client, eventsBulkKind),
 	}
 }
 
 type BulkEvents interface {
 	Append(...*Event)
-	Trigger() (*BulkResponse, error)
+	Trigger(...RequestOption) (*BulkResponse, error)
+	RetryFailed(context.Context, ...RequestOption) (*BulkResponse, error)
+	// Plan returns chunks in which appended records would be sent, without sending them
+	Plan() *BulkPlan
 }
 
 var _ BulkEvents = &bulkEvents{}
 
-type bulkEvents struct {
-	client *Client
-	//
-	_events         []Event
-	_pendingRecords []pendingEventRecord
-	chunks          []*bulkEventsChunk
-	//
-	response *BulkResponse
-	// invalid_record json: {"record": event-json, "error": error_str, "code": 500}
-	_invalidRecords []map[string]any
-}
-
-type pendingEventRecord struct {
-	record     map[string]any
-	recordSize int
-}
-
-func (b *bulkEvents) _validateEvents() {
-	for _, ev := range b._events {
-		evJson, bodySize, err := ev.getFinalJson(b.client, true)
-		if err != nil {
-			invRec := invalidRecordJson(ev.asJson(), err)
-			b._invalidRecords = append(b._invalidRecords, invRec)
-		} else {
-			b._pendingRecords = append(
-				b._pendingRecords,
-				pendingEventRecord{
-					record:     evJson,
-					recordSize: bodySize,
-				},
-			)
-		}
-	}
+var eventsBulkKind = &bulkRecordKind[Event]{
+	url: func(c *Client) string {
+		return fmt.Sprintf("%sv2/bulk/event/", c.baseUrl)
+	},
+	maxRecords: func(c *Client) int {
+		return c.bulkLimits.MaxEventsInBulk
+	},
+	validate: func(c *Client, ev *Event) (pendingBulkRecord, []string, error) {
+		evJson, bodySize, encoded, err := ev.getFinalJson(c, true)
+		return pendingBulkRecord{record: evJson, recordSize: bodySize, encoded: encoded}, nil, err
+	},
+	asJson: func(ev *Event) map[string]any {
+		return ev.asJson()
+	},
+	attachmentsKey: "properties",
+	dedupKind:      dedupKindEvent,
+	parseResponse:  parseV2BulkEventResponse,
 }
 
-func (b *bulkEvents) _chunkify(startIdx int) {
-	currChunk := newBulkEventsChunk(b.client)
-	b.chunks = append(b.chunks, currChunk)
-	for relIdx, rec := range b._pendingRecords[startIdx:] {
-		isAdded := currChunk.tryToAddIntoChunk(rec.record, rec.recordSize)
-		if !isAdded {
-			// create chunks from remaining records
-			b._chunkify(startIdx + relIdx)
-			// Don't forget to break. As current loop must not continue further
-			break
-		}
-	}
+type bulkEvents struct {
+	engine *bulkEngine[Event]
 }
 
 func (b *bulkEvents) Append(events ...*Event) {
@@ -83,119 +58,27 @@ func (b *bulkEvents) Append(events ...*Event) {
 		if ev == nil {
 			continue
 		}
-		eventCopy := Event{}
-		copier.CopyWithOption(&eventCopy, ev, copier.Option{DeepCopy: true})
-		b._events = append(b._events, eventCopy)
-	}
-}
-
-func (b *bulkEvents) Trigger() (*BulkResponse, error) {
-	b._validateEvents()
-	if len(b._invalidRecords) > 0 {
-		chResponse := invalidRecordsChunkResponse(b._invalidRecords)
-		b.response.mergeChunkResponse(chResponse)
-	}
-	if len(b._pendingRecords) > 0 {
-		b._chunkify(0)
-		for cIdx, ch := range b.chunks {
-			if b.client.debug {
-				log.Printf("DEBUG: triggering api call for chunk: %d", cIdx)
-			}
-			// do api call
-			ch.trigger()
-			// merge response
-			b.response.mergeChunkResponse(ch.response)
-		}
-	} else {
-		if len(b._invalidRecords) == 0 {
-			b.response.mergeChunkResponse(emptyChunkSuccessResponse())
-		}
-	}
-	return b.response, nil
-}
-
-// ==========================================================
-
-type bulkEventsChunk struct {
-	_chunk_apparent_size_in_bytes int
-	_max_records_in_chunk         int
-	//
-	client *Client
-	_url   string
-	//
-	_chunk         []map[string]any
-	_runningSize   int
-	_runningLength int
-	response       *chunkResponse
-}
-
-func newBulkEventsChunk(client *Client) *bulkEventsChunk {
-	bec := &bulkEventsChunk{
-		_chunk_apparent_size_in_bytes: BODY_MAX_APPARENT_SIZE_IN_BYTES,
-		_max_records_in_chunk:         MAX_EVENTS_IN_BULK_API,
-		//
-		client: client,
-		_url:   fmt.Sprintf("%sv2/bulk/event/", client.baseUrl),
-		_chunk: []map[string]any{},
+		evCopy := Event{}
+		copier.CopyWithOption(&evCopy, ev, copier.Option{DeepCopy: true})
+		b.engine.append(evCopy)
 	}
-	return bec
 }
 
-func (b *bulkEventsChunk) _addEventToChunk(event map[string]any, eventSize int) {
-	// First add size, then event to reduce effects of race condition
-	b._runningSize += eventSize
-	b._chunk = append(b._chunk, event)
-	b._runningLength += 1
+func (b *bulkEvents) Trigger(opts ...RequestOption) (*BulkResponse, error) {
+	return b.engine.trigger(opts...)
 }
 
-func (b *bulkEventsChunk) _checkLimitReached() bool {
-	return b._runningLength >= b._max_records_in_chunk || b._runningSize >= b._chunk_apparent_size_in_bytes
+func (b *bulkEvents) RetryFailed(ctx context.Context, opts ...RequestOption) (*BulkResponse, error) {
+	return b.engine.retryFailed(ctx, opts...)
 }
 
-/*
-returns whether passed event was able to get added to this chunk or not,
-if true, event gets added to chunk
-*/
-func (b *bulkEventsChunk) tryToAddIntoChunk(event map[string]any, eventSize int) bool {
-	if event == nil {
-		return true
-	}
-	if b._checkLimitReached() {
-		return false
-	}
-	// if apparent_size of event crosses limit
-	if (b._runningSize + eventSize) > b._chunk_apparent_size_in_bytes {
-		return false
-	}
-	if !ALLOW_ATTACHMENTS_IN_BULK_API {
-		delete(event["properties"].(map[string]any), "$attachments")
-	}
-	// Add Event to chunk
-	b._addEventToChunk(event, eventSize)
-	return true
-}
-
-func (b *bulkEventsChunk) trigger() {
-	// prepare http.Request object
-	request, err := b.client.prepareHttpRequest("POST", b._url, b._chunk)
-	if err != nil {
-		suprResponse := parseV2BulkEventResponse(nil, err, b._chunk)
-		b.response = suprResponse
-	}
-	httpResponse, err := b.client.httpClient.Do(request)
-	if err != nil {
-		suprResponse := parseV2BulkEventResponse(nil, err, b._chunk)
-		b.response = suprResponse
-
-	} else {
-		defer httpResponse.Body.Close()
-		suprResponse := parseV2BulkEventResponse(httpResponse, nil, b._chunk)
-		b.response = suprResponse
-	}
+func (b *bulkEvents) Plan() *BulkPlan {
+	return b.engine.plan()
 }
 
 // Used by bulk apis: /v2/bulk/event/ and /trigger/ endpoints
-func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[string]any) *chunkResponse {
+// _indexes holds the bulk-instance index of each record in _chunk
+func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[string]any, _indexes []int) *chunkResponse {
 	/*
 		"string"
 		OR
@@ -205,12 +88,12 @@ func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[st
 			{"status": "error", "error": {"message": "string", "type": "string"}, "status_code": "string"}
 		]}
 	*/
-	bulkRespFunc := func(statusCode int, errMsg string, respPtr *v2EventBulkResponse) *chunkResponse {
+	bulkRespFunc := func(statusCode int, errMsg string, errType string, respPtr *v2EventBulkResponse) *chunkResponse {
 		failedRecords := []map[string]any{}
-		if statusCode >= 400 {
+		if statusCode >= 400 && (respPtr == nil || len(respPtr.Records) == 0) {
 			// pick error message from response pointer if present
 			if respPtr != nil && respPtr.Error != nil {
-				errMsg = respPtr.Error.Message
+				errMsg, errType = respPtr.Error.Message, respPtr.Error.Type
 			}
 			for _, c := range _chunk {
 				failedRecords = append(failedRecords,
@@ -224,14 +107,35 @@ func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[st
 				status: "fail", statusCode: statusCode,
 				total: len(_chunk), success: 0, failure: len(_chunk),
 				failedRecords: failedRecords,
+				results:       chunkRecordResults(_chunk, _indexes, statusCode, errMsg, errType),
+			}
+		} else if respPtr == nil {
+			// non-json success response, all records are accepted
+			return &chunkResponse{
+				status: "success", statusCode: statusCode,
+				total: len(_chunk), success: len(_chunk), failure: 0,
+				failedRecords: failedRecords,
+				results:       chunkRecordResults(_chunk, _indexes, statusCode, "", ""),
 			}
 		} else {
-			// multi-status 207 response. Filter failed records
+			// multi-status 207 response (or error response with status of each record). Filter failed records
+			results := []BulkRecordResult{}
 			for ri, r := range respPtr.Records {
+				result := BulkRecordResult{
+					Index: -1, Status: r.Status, StatusCode: r.StatusCode, MessageId: r.MessageId,
+				}
+				if ri < len(_chunk) {
+					result.Index, result.Record = _indexes[ri], _chunk[ri]
+				}
 				if r.Status == "error" {
+					errMsg := ""
+					if r.Error != nil {
+						errMsg, result.ErrorType = r.Error.Message, r.Error.Type
+					}
+					result.Error = errMsg
 					failedR := map[string]any{
 						"record": nil,
-						"error":  r.Error.Message,
+						"error":  errMsg,
 						"code":   r.StatusCode,
 					}
 					if ri < len(_chunk) {
@@ -239,6 +143,7 @@ func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[st
 					}
 					failedRecords = append(failedRecords, failedR)
 				}
+				results = append(results, result)
 			}
 			// set derived fields
 			respPtr.setDerivedFields()
@@ -247,17 +152,20 @@ func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[st
 				statusCode: statusCode,
 				total:      respPtr.dTotal, success: respPtr.dSuccess, failure: respPtr.dFailure,
 				failedRecords: failedRecords,
+				results:       results,
+				//
+				hasRecordResults: true,
 			}
 		}
 	}
 	// error during http request
 	if err != nil { //
-		return bulkRespFunc(500, err.Error(), nil)
+		return bulkRespFunc(500, err.Error(), BULK_RECORD_ERROR_TYPE_NETWORK, nil)
 	}
 	// try to parse
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
-		return bulkRespFunc(500, err.Error(), nil)
+		return bulkRespFunc(500, err.Error(), BULK_RECORD_ERROR_TYPE_NETWORK, nil)
 	}
 	// First try to unmarshal to map. If fails, response is likely "string"
 	var tempMap map[string]any
@@ -274,9 +182,9 @@ func parseV2BulkEventResponse(httpRes *http.Response, err error, _chunk []map[st
 		}
 	}
 	if isOldResp {
-		return bulkRespFunc(httpRes.StatusCode, string(respBody), nil)
+		return bulkRespFunc(httpRes.StatusCode, string(respBody), "", nil)
 	} else {
-		res := bulkRespFunc(httpRes.StatusCode, "", respPtr)
+		res := bulkRespFunc(httpRes.StatusCode, "", "", respPtr)
 		res.rawResponse = tempMap
 		return res
 	}
@@ -0,0 +1,108 @@
+package suprsend
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBulkEventsResultsFollowAppendOrder(t *testing.T) {
+	ts := newTestServer(t, perRecordBulkHandler(func(rec map[string]any) int {
+		if rec["distinct_id"] == "u3" {
+			return 400
+		}
+		return 202
+	}))
+	// small chunks, so that results of several chunks get merged
+	c := newTestClient(t, ts.URL, WithBulkLimits(BulkLimits{MaxEventsInBulk: 2}))
+	bulkIns := c.BulkEvents.NewInstance()
+	bulkIns.Append(
+		testEvent("u0", nil),
+		&Event{DistinctId: "u1"}, // invalid: event name missing
+		testEvent("u2", nil),
+		testEvent("u3", nil),
+		testEvent("u4", nil),
+	)
+	resp, err := bulkIns.Trigger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "partial" || resp.Total != 5 || resp.Success != 3 || resp.Failure != 2 {
+		t.Errorf("unexpected response %v", resp)
+	}
+	if len(resp.Results) != 5 {
+		t.Fatalf("results = %d, want 5", len(resp.Results))
+	}
+	tests := []struct {
+		status    string
+		messageId string
+		errorType string
+	}{
+		{"success", "msg-u0", ""},
+		{"error", "", BULK_RECORD_ERROR_TYPE_VALIDATION},
+		{"success", "msg-u2", ""},
+		{"error", "", "test_error"},
+		{"success", "msg-u4", ""},
+	}
+	for i, tt := range tests {
+		r := resp.Results[i]
+		if r.Index != i || r.Status != tt.status || r.MessageId != tt.messageId || r.ErrorType != tt.errorType {
+			t.Errorf("result %d = %+v, want %+v", i, r, tt)
+		}
+		if r.Record == nil {
+			t.Errorf("result %d has no record", i)
+		}
+	}
+	if n := len(ts.requestsTo("/v2/bulk/event/")); n != 2 {
+		t.Errorf("bulk calls = %d, want 2", n)
+	}
+}
+
+func TestBulkEventsRetryFailedResendsRetryableRecordsOnly(t *testing.T) {
+	var flakySeen atomic.Bool
+	ts := newTestServer(t, perRecordBulkHandler(func(rec map[string]any) int {
+		switch rec["distinct_id"] {
+		case "flaky":
+			// fails only the first time
+			if !flakySeen.Swap(true) {
+				return 503
+			}
+		case "bad":
+			return 400
+		}
+		return 202
+	}))
+	c := newTestClient(t, ts.URL)
+	bulkIns := c.BulkEvents.NewInstance()
+	bulkIns.Append(testEvent("ok", nil), testEvent("flaky", nil), testEvent("bad", nil), &Event{DistinctId: "invalid"})
+	resp, _ := bulkIns.Trigger()
+	if resp.Success != 1 || resp.Failure != 3 {
+		t.Fatalf("unexpected first response %v", resp)
+	}
+	if retryable := resp.RetryableRecords(); len(retryable) != 1 || retryable[0].Index != 1 {
+		t.Fatalf("retryable records = %+v, want only index 1", retryable)
+	}
+	resp, err := bulkIns.RetryFailed(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := ts.requestsTo("/v2/bulk/event/")
+	if len(reqs) != 2 {
+		t.Fatalf("bulk calls = %d, want 2", len(reqs))
+	}
+	retriedRecords := reqs[1].jsonBody(t).([]any)
+	if len(retriedRecords) != 1 || retriedRecords[0].(map[string]any)["distinct_id"] != "flaky" {
+		t.Errorf("retry call must resend only flaky record, got %s", reqs[1].Body)
+	}
+	if resp.Total != 4 || resp.Success != 2 || resp.Failure != 2 || len(resp.Results) != 4 {
+		t.Errorf("unexpected response after retry %v", resp)
+	}
+	if r := resp.Results[1]; r.Status != "success" || r.MessageId != "msg-flaky" {
+		t.Errorf("retried record result = %+v", r)
+	}
+	// nothing left to retry
+	resp, _ = bulkIns.RetryFailed(context.Background())
+	if n := len(ts.requestsTo("/v2/bulk/event/")); n != 2 || resp.Total != 4 {
+		t.Errorf("second RetryFailed made %d calls in total, response %v", n, resp)
+	}
+}
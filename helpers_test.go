@@ -0,0 +1,173 @@
+package suprsend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const (
+	testWorkspaceKey    = "test-workspace-key-0123456789"
+	testWorkspaceSecret = "test-workspace-secret"
+)
+
+// request received by testServer. Body is decompressed if request was gzipped
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+func (r recordedRequest) jsonBody(t testing.TB) any {
+	t.Helper()
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(r.Body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("request body of %s is not json: %v: %s", r.Path, err, r.Body)
+	}
+	return v
+}
+
+// stand-in for SuprSend api. Records every request and responds using handler
+type testServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+type testHandler func(w http.ResponseWriter, r *http.Request, body []byte)
+
+func newTestServer(t testing.TB, handler testHandler) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, gerr := gzip.NewReader(bytes.NewReader(body))
+			if gerr != nil {
+				t.Errorf("gzip body: %v", gerr)
+			} else {
+				body, _ = io.ReadAll(gr)
+			}
+		}
+		ts.mu.Lock()
+		ts.requests = append(ts.requests, recordedRequest{
+			Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header.Clone(), Body: body,
+		})
+		ts.mu.Unlock()
+		handler(w, r, body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) Requests() []recordedRequest {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]recordedRequest{}, ts.requests...)
+}
+
+func (ts *testServer) requestsTo(path string) []recordedRequest {
+	matching := []recordedRequest{}
+	for _, r := range ts.Requests() {
+		if r.Path == path {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+func newTestClient(t testing.TB, baseUrl string, opts ...ClientOption) *Client {
+	t.Helper()
+	opts = append([]ClientOption{WithBaseUrl(baseUrl)}, opts...)
+	c, err := NewClient(testWorkspaceKey, testWorkspaceSecret, opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// handler of bulk apis which accepts every record of a chunk
+func acceptAllBulkHandler(w http.ResponseWriter, r *http.Request, body []byte) {
+	var records []any
+	if err := json.Unmarshal(body, &records); err != nil {
+		writeJson(w, 202, map[string]any{"status": "success"})
+		return
+	}
+	writeJson(w, 202, bulkSuccessBody(len(records)))
+}
+
+func bulkSuccessBody(n int) map[string]any {
+	recs := []map[string]any{}
+	for i := 0; i < n; i++ {
+		recs = append(recs, map[string]any{"status": "success", "status_code": 202, "message_id": "msg-" + itoa(i)})
+	}
+	return map[string]any{"status": "success", "records": recs}
+}
+
+func itoa(i int) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func testEvent(distinctId string, props map[string]any) *Event {
+	if props == nil {
+		props = map[string]any{}
+	}
+	return &Event{DistinctId: distinctId, EventName: "test_event", Properties: props}
+}
+
+func testWorkflow(workflow string, distinctId string) *WorkflowTriggerRequest {
+	return &WorkflowTriggerRequest{Body: map[string]any{
+		"workflow":   workflow,
+		"recipients": []any{distinctId},
+		"data":       map[string]any{"k": "v"},
+	}}
+}
+
+/*
+handler of v2 bulk apis which decides status code of each record. Success records get message_id "msg-<distinct_id>".
+Responds 202 if every record succeeds, else 207.
+*/
+func perRecordBulkHandler(statusOf func(rec map[string]any) int) testHandler {
+	return func(w http.ResponseWriter, r *http.Request, body []byte) {
+		var records []map[string]any
+		json.Unmarshal(body, &records)
+		results := []map[string]any{}
+		allOk := true
+		for _, rec := range records {
+			status := statusOf(rec)
+			if status < 400 {
+				results = append(results, map[string]any{"status": "success", "status_code": status,
+					"message_id": "msg-" + fmt.Sprint(rec["distinct_id"])})
+			} else {
+				allOk = false
+				results = append(results, map[string]any{"status": "error", "status_code": status,
+					"error": map[string]any{"message": "rejected", "type": "test_error"}})
+			}
+		}
+		if allOk {
+			writeJson(w, 202, map[string]any{"status": "success", "records": results})
+		} else {
+			writeJson(w, 207, map[string]any{"status": "partial", "records": results})
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package suprsend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// max length of $idempotency_key (see request_json/workflow_trigger.json)
+const IDEMPOTENCY_KEY_MAX_LENGTH = 255
+
+/*
+IdempotencyKeyOptions decides which parts of a request make up its derived idempotency key.
+Workflow slug/event name, recipient distinct_ids and tenant are always part of the key.
+*/
+type IdempotencyKeyOptions struct {
+	// optional, prepended to the hash as "<Prefix>-". Truncated if key would cross 255 chars
+	Prefix string
+	// caller-provided business key (e.g. order id). If set, Fields are ignored
+	BusinessKey string
+	// keys of workflow data / event properties to include. Nested keys can be given as dotted path e.g "order.id".
+	// If neither BusinessKey nor Fields are set, whole data / properties are included
+	Fields []string
+}
+
+func (o *IdempotencyKeyOptions) cleanParams() {
+	o.Prefix = strings.TrimSpace(o.Prefix)
+	o.BusinessKey = strings.TrimSpace(o.BusinessKey)
+}
+
+// GenerateIdempotencyKey returns a stable idempotency key for the request. It doesn't set IdempotencyKey.
+func (w *WorkflowTriggerRequest) GenerateIdempotencyKey(opts *IdempotencyKeyOptions) (string, error) {
+	body := w.Body
+	if body == nil {
+		body = map[string]any{}
+	}
+	tenantId := w.TenantId
+	if tenantId == "" {
+		tenantId, _ = body["tenant_id"].(string)
+	}
+	data, _ := body["data"].(map[string]any)
+	recipients, _ := body["recipients"].([]any)
+	if recipients == nil {
+		// recipients are commonly passed as []string / []map[string]any
+		if rl, err := toAnySlice(body["recipients"]); err == nil {
+			recipients = rl
+		}
+	}
+	workflow, _ := body["workflow"].(string)
+	return deriveIdempotencyKey(opts, map[string]any{
+		"workflow":   workflow,
+		"recipients": recipientIdentifiers(recipients),
+		"tenant_id":  tenantId,
+	}, data)
+}
+
+// GenerateIdempotencyKey returns a stable idempotency key for the event. It doesn't set IdempotencyKey.
+func (e *Event) GenerateIdempotencyKey(opts *IdempotencyKeyOptions) (string, error) {
+	tenantId := e.TenantId
+	if tenantId == "" {
+		tenantId = e.BrandId
+	}
+	identity := map[string]any{
+		"event":       strings.TrimSpace(e.EventName),
+		"distinct_id": strings.TrimSpace(e.DistinctId),
+		"tenant_id":   tenantId,
+	}
+	// same event at different times (e.g while backfilling) is not a duplicate
+	if !e.Timestamp.IsZero() {
+		identity["time"] = e.Timestamp.UnixMilli()
+	}
+	return deriveIdempotencyKey(opts, identity, e.Properties)
+}
+
+// returns key for request if AutoIdempotency is enabled on client. Errors are logged, not returned.
+func (c *Client) autoIdempotencyKey(generate func(*IdempotencyKeyOptions) (string, error)) string {
+	if c.autoIdempotency == nil {
+		return ""
+	}
+	key, err := generate(c.autoIdempotency)
+	if err != nil {
+		log.Printf("WARNING: auto idempotency_key not set: %v", err)
+		return ""
+	}
+	return key
+}
+
+func deriveIdempotencyKey(opts *IdempotencyKeyOptions, identity map[string]any, fields map[string]any) (string, error) {
+	o := IdempotencyKeyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.cleanParams()
+	if o.BusinessKey != "" {
+		identity["business_key"] = o.BusinessKey
+	} else if len(o.Fields) > 0 {
+		selected := map[string]any{}
+		for _, path := range o.Fields {
+			v, found := lookupPath(fields, path)
+			if !found {
+				return "", &Error{Message: fmt.Sprintf("idempotency_key: field '%s' missing", path)}
+			}
+			selected[path] = v
+		}
+		identity["fields"] = selected
+	} else {
+		all := map[string]any{}
+		for k, v := range fields {
+			// skip props added by sdk itself
+			if !strings.HasPrefix(k, "$ss_") {
+				all[k] = v
+			}
+		}
+		identity["fields"] = all
+	}
+	canonical, err := canonicalJson(identity)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	key := hex.EncodeToString(sum[:])
+	if o.Prefix != "" {
+		prefix := o.Prefix
+		if maxPrefixLen := IDEMPOTENCY_KEY_MAX_LENGTH - len(key) - 1; len(prefix) > maxPrefixLen {
+			prefix = prefix[:maxPrefixLen]
+		}
+		key = prefix + "-" + key
+	}
+	return key, nil
+}
+
+// canonicalJson returns json with sorted map keys and numbers in their shortest form,
+// so same value always results in same bytes irrespective of its go types.
+func canonicalJson(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err = dec.Decode(&generic); err != nil {
+		return nil, &Error{Err: err}
+	}
+	// encoding/json writes map keys in sorted order
+	canonical, err := json.Marshal(generic)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return canonical, nil
+}
+
+// sorted distinct_ids (or object_type/id for objects) of recipients
+func recipientIdentifiers(recipients []any) []string {
+	ids := []string{}
+	for _, r := range recipients {
+		switch rv := r.(type) {
+		case string:
+			ids = append(ids, rv)
+		case map[string]any:
+			if distinctId, ok := rv["distinct_id"].(string); ok {
+				ids = append(ids, distinctId)
+			} else {
+				ids = append(ids, fmt.Sprintf("%v/%v", rv["object_type"], rv["id"]))
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func lookupPath(m map[string]any, path string) (any, bool) {
+	var cur any = m
+	for _, part := range strings.Split(path, ".") {
+		cm, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = cm[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func toAnySlice(v any) ([]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
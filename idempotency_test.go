@@ -0,0 +1,153 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func mustWorkflowKey(t *testing.T, body map[string]any, opts *IdempotencyKeyOptions) string {
+	t.Helper()
+	key, err := (&WorkflowTriggerRequest{Body: body}).GenerateIdempotencyKey(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestWorkflowIdempotencyKeyIsStable(t *testing.T) {
+	base := mustWorkflowKey(t, map[string]any{
+		"workflow": "order_placed", "recipients": []any{"u1", "u2"},
+		"data": map[string]any{"amount": 10, "order": map[string]any{"id": "o1"}},
+	}, nil)
+	tests := []struct {
+		name string
+		body map[string]any
+		same bool
+	}{
+		{"recipients in other order, typed slice, float amount", map[string]any{
+			"workflow": "order_placed", "recipients": []string{"u2", "u1"},
+			"data": map[string]any{"order": map[string]any{"id": "o1"}, "amount": 10.0},
+		}, true},
+		{"json.Number amount, recipient as map", map[string]any{
+			"workflow": "order_placed", "recipients": []any{map[string]any{"distinct_id": "u2"}, "u1"},
+			"data": map[string]any{"amount": json.Number("10"), "order": map[string]any{"id": "o1"}},
+		}, true},
+		{"different data", map[string]any{
+			"workflow": "order_placed", "recipients": []any{"u1", "u2"},
+			"data": map[string]any{"amount": 11, "order": map[string]any{"id": "o1"}},
+		}, false},
+		{"different recipients", map[string]any{
+			"workflow": "order_placed", "recipients": []any{"u1"},
+			"data": map[string]any{"amount": 10, "order": map[string]any{"id": "o1"}},
+		}, false},
+		{"different workflow", map[string]any{
+			"workflow": "order_shipped", "recipients": []any{"u1", "u2"},
+			"data": map[string]any{"amount": 10, "order": map[string]any{"id": "o1"}},
+		}, false},
+		{"different tenant", map[string]any{
+			"workflow": "order_placed", "recipients": []any{"u1", "u2"}, "tenant_id": "t1",
+			"data": map[string]any{"amount": 10, "order": map[string]any{"id": "o1"}},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := mustWorkflowKey(t, tt.body, nil); (key == base) != tt.same {
+				t.Errorf("key = %s, base = %s, want same: %v", key, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyOptions(t *testing.T) {
+	body := func(amount int) map[string]any {
+		return map[string]any{
+			"workflow": "wf", "recipients": []any{"u1"},
+			"data": map[string]any{"amount": amount, "order": map[string]any{"id": "o1"}},
+		}
+	}
+	fieldsOpts := &IdempotencyKeyOptions{Fields: []string{"order.id"}}
+	if mustWorkflowKey(t, body(1), fieldsOpts) != mustWorkflowKey(t, body(2), fieldsOpts) {
+		t.Error("fields not selected must not change key")
+	}
+	businessOpts := &IdempotencyKeyOptions{BusinessKey: "order-o1", Fields: []string{"missing"}}
+	if mustWorkflowKey(t, body(1), businessOpts) != mustWorkflowKey(t, body(2), businessOpts) {
+		t.Error("business key must make key independent of data")
+	}
+	if _, err := (&WorkflowTriggerRequest{Body: body(1)}).GenerateIdempotencyKey(
+		&IdempotencyKeyOptions{Fields: []string{"order.missing"}}); err == nil {
+		t.Error("missing field must be an error")
+	}
+	key := mustWorkflowKey(t, body(1), &IdempotencyKeyOptions{Prefix: " orders "})
+	if !strings.HasPrefix(key, "orders-") || len(key) != len("orders-")+64 {
+		t.Errorf("prefixed key = %s", key)
+	}
+	key = mustWorkflowKey(t, body(1), &IdempotencyKeyOptions{Prefix: strings.Repeat("p", 300)})
+	if len(key) != IDEMPOTENCY_KEY_MAX_LENGTH {
+		t.Errorf("key length = %d, want %d", len(key), IDEMPOTENCY_KEY_MAX_LENGTH)
+	}
+}
+
+func TestEventIdempotencyKeySkipsSdkProperties(t *testing.T) {
+	ev := &Event{DistinctId: "u1", EventName: "paid", Properties: map[string]any{"amount": 10}}
+	key, _ := ev.GenerateIdempotencyKey(nil)
+	ev.Properties["$ss_sdk_version"] = "go/1.0"
+	keyWithSdkProps, _ := ev.GenerateIdempotencyKey(nil)
+	if key != keyWithSdkProps {
+		t.Error("$ss_ properties must not change key")
+	}
+	other, _ := (&Event{DistinctId: "u2", EventName: "paid", Properties: map[string]any{"amount": 10}}).
+		GenerateIdempotencyKey(nil)
+	if key == other {
+		t.Error("different distinct_id must change key")
+	}
+}
+
+func TestAutoIdempotencySetsKey(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	c := newTestClient(t, ts.URL, WithAutoIdempotency(nil))
+	c.TrackEvent(testEvent("u1", map[string]any{"k": "v"}))
+	c.TrackEvent(testEvent("u1", map[string]any{"k": "v"}))
+	explicit := testEvent("u1", map[string]any{"k": "v"})
+	explicit.IdempotencyKey = "own-key"
+	c.TrackEvent(explicit)
+	reqs := ts.requestsTo("/v2/event/")
+	if len(reqs) != 3 {
+		t.Fatalf("event calls = %d, want 3", len(reqs))
+	}
+	keys := []any{}
+	for _, r := range reqs {
+		keys = append(keys, r.jsonBody(t).(map[string]any)["$idempotency_key"])
+	}
+	if keys[0] == nil || keys[0] == "" || keys[0] != keys[1] || keys[2] != "own-key" {
+		t.Errorf("idempotency keys = %v", keys)
+	}
+}
+
+func TestAutoIdempotencyKeyFollowsReusedWorkflowRequest(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	c := newTestClient(t, ts.URL, WithAutoIdempotency(nil))
+	wf := testWorkflow("order_placed", "u1")
+	data := wf.Body["data"].(map[string]any)
+	for _, order := range []int{1, 2, 1} {
+		data["order"] = order
+		if _, err := c.Workflows.Trigger(wf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found := wf.Body["$idempotency_key"]; found {
+		t.Errorf("derived key set on caller's request: %v", wf.Body)
+	}
+	keys := []any{}
+	for _, r := range ts.requestsTo("/trigger/") {
+		keys = append(keys, r.jsonBody(t).(map[string]any)["$idempotency_key"])
+	}
+	if len(keys) != 3 || keys[0] == nil || keys[0] == keys[1] || keys[0] != keys[2] {
+		t.Errorf("idempotency keys = %v, want key to change with data", keys)
+	}
+}
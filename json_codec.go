@@ -0,0 +1,58 @@
+package suprsend
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+/*
+JSONCodec encodes request bodies (for sizing, signing and sending, all in one pass) and decodes api responses.
+Set a faster implementation (e.g. one based on goccy/go-json or sonic) with WithJSONCodec.
+Marshal must produce same output as encoding/json for the body to be sized correctly.
+*/
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var _ JSONCodec = stdJSONCodec{}
+
+// JSONCodec based on encoding/json. Default
+type stdJSONCodec struct{}
+
+func (stdJSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// request body which is already json encoded, sent as-is by prepareHttpRequest
+type encodedJson []byte
+
+func (c *Client) marshal(v any) ([]byte, error) {
+	b, err := c.jsonCodec.Marshal(v)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return b, nil
+}
+
+// joins already encoded records into a json array, without decoding/encoding them again
+func joinEncodedRecords(records [][]byte) encodedJson {
+	size := 2 + len(records)
+	for _, r := range records {
+		size += len(r)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	buf.WriteByte('[')
+	for i, r := range records {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(r)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
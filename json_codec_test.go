@@ -0,0 +1,89 @@
+package suprsend
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/suprsend/suprsend-go/signature"
+)
+
+var testSignHeaders = map[string]string{"Content-Type": "application/json; charset=utf-8", "Date": "Mon, 19 Oct 2026 10:00:00 GMT"}
+
+func TestSignatureOverReusedEncodingMatchesMap(t *testing.T) {
+	c := newTestClient(t, "http://localhost/")
+	ev := testEvent("u1", map[string]any{"amount": 10.5, "big": int64(9007199254740993), "html": "<b>&</b>"})
+	evJson, _, encoded, err := ev.getFinalJson(c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded == nil {
+		t.Fatal("event encoding must be reused")
+	}
+	url := "http://localhost/v2/event/"
+	mapBody, mapSig, err := signature.GetRequestSignature(url, "POST", evJson, testSignHeaders, testWorkspaceSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodySig, err := signature.GetRequestSignatureForBody(url, "POST", encoded, testSignHeaders, testWorkspaceSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mapBody, encoded) || mapSig != bodySig {
+		t.Errorf("reused encoding differs from map encoding:\n%s\n%s", encoded, mapBody)
+	}
+	// records joined into a chunk body must be same as encoding the chunk
+	records := []map[string]any{evJson, evJson}
+	chunkJson, _ := json.Marshal(records)
+	if joined := joinEncodedRecords([][]byte{encoded, encoded}); !bytes.Equal(joined, chunkJson) {
+		t.Errorf("joined records = %s, want %s", joined, chunkJson)
+	}
+}
+
+// signature sent with request must verify against body received by server
+func TestRequestSignatureVerifiesOnServer(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, bulkSuccessBody(2))
+	})
+	c := newTestClient(t, ts.URL)
+	c.TrackEvent(testEvent("u1", map[string]any{"k": "v"}))
+	bulkIns := c.BulkEvents.NewInstance()
+	bulkIns.Append(testEvent("u1", nil), testEvent("u2", nil))
+	bulkIns.Trigger()
+	for _, r := range ts.Requests() {
+		headers := map[string]string{"Content-Type": r.Header.Get("Content-Type"), "Date": r.Header.Get("Date")}
+		sig, _ := signature.GetRequestSignatureForBody(ts.URL+r.Path, r.Method, r.Body, headers, testWorkspaceSecret)
+		if want := testWorkspaceKey + ":" + sig; r.Header.Get("Authorization") != want {
+			t.Errorf("%s: Authorization = %s, want %s", r.Path, r.Header.Get("Authorization"), want)
+		}
+	}
+}
+
+func benchmarkEventEncoding(b *testing.B, reuse bool) {
+	c := newTestClient(b, "http://localhost/", WithValidation(ValidationOff))
+	props := map[string]any{"amount": 10, "currency": "INR", "note": strings.Repeat("x", 512),
+		"items": []any{map[string]any{"sku": "s1", "qty": 2}, map[string]any{"sku": "s2", "qty": 1}}}
+	url := "http://localhost/v2/event/"
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evJson, _, encoded, err := testEvent("u1", props).getFinalJson(c, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if reuse {
+			_, err = signature.GetRequestSignatureForBody(url, "POST", encoded, testSignHeaders, testWorkspaceSecret)
+		} else {
+			// body encoded again for signing (and the same bytes sent), as before encoding was reused
+			_, _, err = signature.GetRequestSignature(url, "POST", evJson, testSignHeaders, testWorkspaceSecret)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventEncoding_DoubleMarshal(b *testing.B) { benchmarkEventEncoding(b, false) }
+func BenchmarkEventEncoding_Reuse(b *testing.B)         { benchmarkEventEncoding(b, true) }
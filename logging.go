@@ -2,6 +2,7 @@ package suprsend
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io"
 	"log"
 	"net/http"
@@ -12,21 +13,63 @@ type LoggingRoundTripper struct {
 }
 
 func (l LoggingRoundTripper) RoundTrip(req *http.Request) (res *http.Response, e error) {
-	// read request body for logging
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		log.Printf("DEBUG: error reading request body: %v", err)
+	if err := logRequest(req); err != nil {
 		return nil, err
 	}
+	res, e = l.Proxied.RoundTrip(req)
+	return
+}
+
+// logs request, body is read and set again
+func logRequest(req *http.Request) error {
+	// read request body for logging
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			log.Printf("DEBUG: error reading request body: %v", err)
+			return err
+		}
+	}
 	// prepare request log
 	logString := "DEBUG: HTTP Request ------------------\n" +
 		"METHOD:\t%v\nURL:\t%v\nHEADER\t%v\nBODY:\t%v\n" +
 		"------------------\n"
-	log.Printf(logString, req.Method, req.URL, req.Header, string(body))
+	log.Printf(logString, req.Method, req.URL, req.Header, string(uncompressedBody(req.Header, body)))
 
 	// Set new body
 	req.Body = io.NopCloser(bytes.NewBuffer(body))
-	//
-	res, e = l.Proxied.RoundTrip(req)
-	return
+	return nil
+}
+
+// body of a gzip-encoded request is logged as it was before compression
+func uncompressedBody(header http.Header, body []byte) []byte {
+	if header.Get("Content-Encoding") != "gzip" {
+		return body
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	defer zr.Close()
+	uncompressed, err := io.ReadAll(zr)
+	if err != nil {
+		return body
+	}
+	return uncompressed
+}
+
+// logs response, body is read and set again
+func logResponse(res *http.Response) {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("DEBUG: error reading response body: %v", err)
+	}
+	res.Body.Close()
+	logString := "DEBUG: HTTP Response ------------------\n" +
+		"STATUS:\t%v\nHEADER\t%v\nBODY:\t%v\n" +
+		"------------------\n"
+	log.Printf(logString, res.Status, res.Header, string(body))
+	res.Body = io.NopCloser(bytes.NewReader(body))
 }
@@ -3,6 +3,7 @@ package suprsend
 import (
 	"context"
 	"fmt"
+	"iter"
 	"net/url"
 )
 
@@ -12,24 +13,28 @@ type ObjectIdentifier struct {
 }
 
 type ObjectsService interface {
-	List(context.Context, string, *CursorListApiOptions) (*CursorListApiResponse, error)
-	Get(context.Context, ObjectIdentifier) (map[string]any, error)
-	Upsert(context.Context, ObjectIdentifier, map[string]any) (map[string]any, error)
-	Edit(context.Context, ObjectEditRequest) (map[string]any, error)
-	Delete(context.Context, ObjectIdentifier) error
-	BulkDelete(context.Context, string, ObjectBulkDeletePayload) error
+	List(context.Context, string, *CursorListApiOptions, ...RequestOption) (*CursorListApiResponse, error)
+	Get(context.Context, ObjectIdentifier, ...RequestOption) (map[string]any, error)
+	Upsert(context.Context, ObjectIdentifier, map[string]any, ...RequestOption) (map[string]any, error)
+	Edit(context.Context, ObjectEditRequest, ...RequestOption) (map[string]any, error)
+	Delete(context.Context, ObjectIdentifier, ...RequestOption) error
+	BulkDelete(context.Context, string, ObjectBulkDeletePayload, ...RequestOption) error
 	//
-	GetSubscriptions(context.Context, ObjectIdentifier, *CursorListApiOptions) (*CursorListApiResponse, error)
-	CreateSubscriptions(context.Context, ObjectIdentifier, map[string]any) (map[string]any, error)
-	DeleteSubscriptions(context.Context, ObjectIdentifier, map[string]any) error
+	GetSubscriptions(context.Context, ObjectIdentifier, *CursorListApiOptions, ...RequestOption) (*CursorListApiResponse, error)
+	// iterators over all pages of list apis
+	All(context.Context, string, *CursorListApiOptions, ...RequestOption) iter.Seq2[map[string]any, error]
+	AllSubscriptions(context.Context, ObjectIdentifier, *CursorListApiOptions, ...RequestOption) iter.Seq2[map[string]any, error]
+	AllObjectsSubscribedTo(context.Context, ObjectIdentifier, *CursorListApiOptions, ...RequestOption) iter.Seq2[map[string]any, error]
+	CreateSubscriptions(context.Context, ObjectIdentifier, map[string]any, ...RequestOption) (map[string]any, error)
+	DeleteSubscriptions(context.Context, ObjectIdentifier, map[string]any, ...RequestOption) error
 	GetEditInstance(ObjectIdentifier) ObjectEdit
 	//
-	GetFullPreference(context.Context, ObjectIdentifier, *ObjectFullPreferenceOptions) (*ObjectFullPreferenceResponse, error)
-	GetGlobalChannelsPreference(context.Context, ObjectIdentifier, *ObjectGlobalChannelsPreferenceOptions) (*ObjectGlobalChannelsPreferenceResponse, error)
-	UpdateGlobalChannelsPreference(context.Context, ObjectIdentifier, ObjectGlobalChannelsPreferenceUpdateBody, *ObjectGlobalChannelsPreferenceOptions) (*ObjectGlobalChannelsPreferenceResponse, error)
-	GetAllCategoriesPreference(context.Context, ObjectIdentifier, *ObjectCategoriesPreferenceOptions) (*ObjectCategoriesPreferenceResponse, error)
-	GetCategoryPreference(context.Context, ObjectIdentifier, string, *ObjectCategoryPreferenceOptions) (*ObjectCategoryPreference, error)
-	UpdateCategoryPreference(context.Context, ObjectIdentifier, string, ObjectUpdateCategoryPreferenceBody, *ObjectCategoryPreferenceOptions) (*ObjectCategoryPreference, error)
+	GetFullPreference(context.Context, ObjectIdentifier, *ObjectFullPreferenceOptions, ...RequestOption) (*ObjectFullPreferenceResponse, error)
+	GetGlobalChannelsPreference(context.Context, ObjectIdentifier, *ObjectGlobalChannelsPreferenceOptions, ...RequestOption) (*ObjectGlobalChannelsPreferenceResponse, error)
+	UpdateGlobalChannelsPreference(context.Context, ObjectIdentifier, ObjectGlobalChannelsPreferenceUpdateBody, *ObjectGlobalChannelsPreferenceOptions, ...RequestOption) (*ObjectGlobalChannelsPreferenceResponse, error)
+	GetAllCategoriesPreference(context.Context, ObjectIdentifier, *ObjectCategoriesPreferenceOptions, ...RequestOption) (*ObjectCategoriesPreferenceResponse, error)
+	GetCategoryPreference(context.Context, ObjectIdentifier, string, *ObjectCategoryPreferenceOptions, ...RequestOption) (*ObjectCategoryPreference, error)
+	UpdateCategoryPreference(context.Context, ObjectIdentifier, string, ObjectUpdateCategoryPreferenceBody, *ObjectCategoryPreferenceOptions, ...RequestOption) (*ObjectCategoryPreference, error)
 }
 
 type objectsService struct {
@@ -49,15 +54,15 @@ func newObjectsService(client *Client) *objectsService {
 	return os
 }
 
-func (o *objectsService) List(ctx context.Context, objectType string, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (o *objectsService) List(ctx context.Context, objectType string, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%s%s/", o._url, url.PathEscape(objectType)), opts.BuildQuery())
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -80,15 +85,15 @@ func (o *objectsService) objectDetailAPIUrl(objectType, id string) string {
 	)
 }
 
-func (o *objectsService) Get(ctx context.Context, obj ObjectIdentifier) (map[string]any, error) {
+func (o *objectsService) Get(ctx context.Context, obj ObjectIdentifier, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := o.objectDetailAPIUrl(obj.ObjectType, obj.Id)
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -102,18 +107,18 @@ func (o *objectsService) Get(ctx context.Context, obj ObjectIdentifier) (map[str
 	return resp, nil
 }
 
-func (o *objectsService) Upsert(ctx context.Context, obj ObjectIdentifier, payload map[string]any) (map[string]any, error) {
+func (o *objectsService) Upsert(ctx context.Context, obj ObjectIdentifier, payload map[string]any, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := o.objectDetailAPIUrl(obj.ObjectType, obj.Id)
 	if payload == nil {
 		payload = map[string]any{}
 	}
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := o.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +141,7 @@ type ObjectEditRequest struct {
 	EditInstance ObjectEdit
 }
 
-func (o *objectsService) Edit(ctx context.Context, req ObjectEditRequest) (map[string]any, error) {
+func (o *objectsService) Edit(ctx context.Context, req ObjectEditRequest, reqOpts ...RequestOption) (map[string]any, error) {
 	var urlStr string
 	var payload map[string]any
 	if req.EditInstance != nil {
@@ -152,11 +157,11 @@ func (o *objectsService) Edit(ctx context.Context, req ObjectEditRequest) (map[s
 		urlStr = o.objectDetailAPIUrl(req.Identifier.ObjectType, req.Identifier.Id)
 	}
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("PATCH", urlStr, payload)
+	request, err := o.client.prepareHttpRequest("PATCH", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -170,14 +175,14 @@ func (o *objectsService) Edit(ctx context.Context, req ObjectEditRequest) (map[s
 	return resp, nil
 }
 
-func (o *objectsService) Delete(ctx context.Context, obj ObjectIdentifier) error {
+func (o *objectsService) Delete(ctx context.Context, obj ObjectIdentifier, reqOpts ...RequestOption) error {
 	urlStr := o.objectDetailAPIUrl(obj.ObjectType, obj.Id)
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("DELETE", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("DELETE", urlStr, nil, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -195,14 +200,14 @@ type ObjectBulkDeletePayload struct {
 	ObjectIds []string `json:"object_ids"`
 }
 
-func (o *objectsService) BulkDelete(ctx context.Context, objectType string, payload ObjectBulkDeletePayload) error {
+func (o *objectsService) BulkDelete(ctx context.Context, objectType string, payload ObjectBulkDeletePayload, reqOpts ...RequestOption) error {
 	urlStr := fmt.Sprintf("%s%s/", o._bulkUrl, url.PathEscape(objectType))
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("DELETE", urlStr, payload)
+	request, err := o.client.prepareHttpRequest("DELETE", urlStr, payload, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -215,15 +220,15 @@ func (o *objectsService) BulkDelete(ctx context.Context, objectType string, payl
 	return nil
 }
 
-func (o *objectsService) GetSubscriptions(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (o *objectsService) GetSubscriptions(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%ssubscription/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -244,18 +249,18 @@ func (o *objectsService) GetSubscriptions(ctx context.Context, obj ObjectIdentif
 //		"properties": {"type": "admin"},
 //		"parent_object_properties: {}, // if value non-null, does upsert on parent-object too.
 //	}
-func (o *objectsService) CreateSubscriptions(ctx context.Context, obj ObjectIdentifier, payload map[string]any) (map[string]any, error) {
+func (o *objectsService) CreateSubscriptions(ctx context.Context, obj ObjectIdentifier, payload map[string]any, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := fmt.Sprintf("%ssubscription/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id))
 	if payload == nil {
 		payload = map[string]any{}
 	}
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := o.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -274,17 +279,17 @@ func (o *objectsService) CreateSubscriptions(ctx context.Context, obj ObjectIden
 //	payload: {
 //		"recipients": ["distinct_id1", {"object_type": "type1", "id": "id1"},]
 //	}
-func (o *objectsService) DeleteSubscriptions(ctx context.Context, obj ObjectIdentifier, payload map[string]any) error {
+func (o *objectsService) DeleteSubscriptions(ctx context.Context, obj ObjectIdentifier, payload map[string]any, reqOpts ...RequestOption) error {
 	urlStr := fmt.Sprintf("%ssubscription/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id))
 	if payload == nil {
 		payload = map[string]any{}
 	}
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("DELETE", urlStr, payload)
+	request, err := o.client.prepareHttpRequest("DELETE", urlStr, payload, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -297,15 +302,15 @@ func (o *objectsService) DeleteSubscriptions(ctx context.Context, obj ObjectIden
 	return nil
 }
 
-func (o *objectsService) GetObjectsSubscribedTo(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (o *objectsService) GetObjectsSubscribedTo(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%ssubscribed_to/object/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
 	// prepare http.Request object
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	//
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -323,13 +328,13 @@ func (o *objectsService) GetEditInstance(obj ObjectIdentifier) ObjectEdit {
 	return newObjectEdit(o.client, obj)
 }
 
-func (o *objectsService) GetFullPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectFullPreferenceOptions) (*ObjectFullPreferenceResponse, error) {
+func (o *objectsService) GetFullPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectFullPreferenceOptions, reqOpts ...RequestOption) (*ObjectFullPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -342,13 +347,13 @@ func (o *objectsService) GetFullPreference(ctx context.Context, obj ObjectIdenti
 	return resp, nil
 }
 
-func (o *objectsService) GetGlobalChannelsPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectGlobalChannelsPreferenceOptions) (*ObjectGlobalChannelsPreferenceResponse, error) {
+func (o *objectsService) GetGlobalChannelsPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectGlobalChannelsPreferenceOptions, reqOpts ...RequestOption) (*ObjectGlobalChannelsPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/channel_preference/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -361,13 +366,13 @@ func (o *objectsService) GetGlobalChannelsPreference(ctx context.Context, obj Ob
 	return resp, nil
 }
 
-func (o *objectsService) UpdateGlobalChannelsPreference(ctx context.Context, obj ObjectIdentifier, body ObjectGlobalChannelsPreferenceUpdateBody, opts *ObjectGlobalChannelsPreferenceOptions) (*ObjectGlobalChannelsPreferenceResponse, error) {
+func (o *objectsService) UpdateGlobalChannelsPreference(ctx context.Context, obj ObjectIdentifier, body ObjectGlobalChannelsPreferenceUpdateBody, opts *ObjectGlobalChannelsPreferenceOptions, reqOpts ...RequestOption) (*ObjectGlobalChannelsPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/channel_preference/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := o.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -380,13 +385,13 @@ func (o *objectsService) UpdateGlobalChannelsPreference(ctx context.Context, obj
 	return resp, nil
 }
 
-func (o *objectsService) GetAllCategoriesPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectCategoriesPreferenceOptions) (*ObjectCategoriesPreferenceResponse, error) {
+func (o *objectsService) GetAllCategoriesPreference(ctx context.Context, obj ObjectIdentifier, opts *ObjectCategoriesPreferenceOptions, reqOpts ...RequestOption) (*ObjectCategoriesPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -399,13 +404,13 @@ func (o *objectsService) GetAllCategoriesPreference(ctx context.Context, obj Obj
 	return resp, nil
 }
 
-func (o *objectsService) GetCategoryPreference(ctx context.Context, obj ObjectIdentifier, category string, opts *ObjectCategoryPreferenceOptions) (*ObjectCategoryPreference, error) {
+func (o *objectsService) GetCategoryPreference(ctx context.Context, obj ObjectIdentifier, category string, opts *ObjectCategoryPreferenceOptions, reqOpts ...RequestOption) (*ObjectCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id), url.PathEscape(category)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := o.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -418,13 +423,13 @@ func (o *objectsService) GetCategoryPreference(ctx context.Context, obj ObjectId
 	return resp, nil
 }
 
-func (o *objectsService) UpdateCategoryPreference(ctx context.Context, obj ObjectIdentifier, category string, body ObjectUpdateCategoryPreferenceBody, opts *ObjectCategoryPreferenceOptions) (*ObjectCategoryPreference, error) {
+func (o *objectsService) UpdateCategoryPreference(ctx context.Context, obj ObjectIdentifier, category string, body ObjectUpdateCategoryPreferenceBody, opts *ObjectCategoryPreferenceOptions, reqOpts ...RequestOption) (*ObjectCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", o.objectDetailAPIUrl(obj.ObjectType, obj.Id), url.PathEscape(category)), opts.BuildQuery())
-	request, err := o.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := o.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := o.client.httpClient.Do(request)
+	httpResponse, err := o.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -436,3 +441,30 @@ func (o *objectsService) UpdateCategoryPreference(ctx context.Context, obj Objec
 	}
 	return resp, nil
 }
+
+// All returns iterator over all objects of objectType. Pages are fetched as needed
+func (o *objectsService) All(ctx context.Context, objectType string, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[map[string]any, error] {
+	fetch := cursorPageFetcher(opts, cursorResponsePages(
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiResponse, error) {
+			return o.List(ctx, objectType, pageOpts, reqOpts...)
+		}))
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
+
+// AllSubscriptions returns iterator over all subscriptions of object. Pages are fetched as needed
+func (o *objectsService) AllSubscriptions(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[map[string]any, error] {
+	fetch := cursorPageFetcher(opts, cursorResponsePages(
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiResponse, error) {
+			return o.GetSubscriptions(ctx, obj, pageOpts, reqOpts...)
+		}))
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
+
+// AllObjectsSubscribedTo returns iterator over all objects this object is subscribed to. Pages are fetched as needed
+func (o *objectsService) AllObjectsSubscribedTo(ctx context.Context, obj ObjectIdentifier, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[map[string]any, error] {
+	fetch := cursorPageFetcher(opts, cursorResponsePages(
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiResponse, error) {
+			return o.GetObjectsSubscribedTo(ctx, obj, pageOpts, reqOpts...)
+		}))
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
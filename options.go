@@ -40,6 +40,118 @@ func WithHTTPClient(client *http.Client) ClientOption {
 	}
 }
 
+// WithBulkLimits overrides chunk limits (records/apparent-size per api call) used by bulk apis
+func WithBulkLimits(limits BulkLimits) ClientOption {
+	return func(c *Client) error {
+		c.bulkLimits = limits
+		return nil
+	}
+}
+
+/*
+WithDedupCache enables client-side dedup of workflow triggers and events (single as well as bulk).
+A request whose dedup key (by default its idempotency_key) is found in cache is not sent again,
+a response built from cached entry (status code and message_id) is returned instead. keyFunc is optional.
+*/
+func WithDedupCache(cache DedupCache, keyFunc DedupKeyFunc) ClientOption {
+	return func(c *Client) error {
+		c.dedupCache = cache
+		c.dedupKeyFunc = keyFunc
+		return nil
+	}
+}
+
+/*
+WithAutoIdempotency derives idempotency_key (see GenerateIdempotencyKey) for every workflow trigger
+and event which is sent without one. nil opts derives it from workflow/event, recipients, tenant and whole data/properties.
+*/
+func WithAutoIdempotency(opts *IdempotencyKeyOptions) ClientOption {
+	return func(c *Client) error {
+		if opts == nil {
+			opts = &IdempotencyKeyOptions{}
+		}
+		c.autoIdempotency = opts
+		return nil
+	}
+}
+
+/*
+WithValidation sets how request bodies (workflow triggers, events, broadcasts) are validated before sending.
+ValidationStrict (default) validates against full jsonschema, ValidationFast checks only top-level keys,
+ValidationOff skips schema validation. Schemas registered via RegisterWorkflowSchema are applied in every mode.
+*/
+func WithValidation(mode ValidationMode) ClientOption {
+	return func(c *Client) error {
+		c.validationMode = mode
+		return nil
+	}
+}
+
+// WithJSONCodec replaces encoding/json for encoding request bodies and decoding api responses
+func WithJSONCodec(codec JSONCodec) ClientOption {
+	return func(c *Client) error {
+		c.jsonCodec = codec
+		return nil
+	}
+}
+
+/*
+WithRequestCompression gzip-compresses request bodies larger than thresholdBytes and sends them
+with Content-Encoding: gzip. Request signature is computed on uncompressed body.
+*/
+func WithRequestCompression(thresholdBytes int) ClientOption {
+	return func(c *Client) error {
+		if thresholdBytes < 0 {
+			thresholdBytes = 0
+		}
+		c.compressRequests = true
+		c.compressionThreshold = thresholdBytes
+		return nil
+	}
+}
+
+// WithRetryPolicy sets retry policy of requests made via Client.Do
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) error {
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware to every api request made by client. First added middleware is outermost.
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(c *Client) error {
+		c.middlewares = append(c.middlewares, middlewares...)
+		return nil
+	}
+}
+
+/*
+WithAttachmentUpload enables attachment upload mode. Instead of sending attachment content inline as base64,
+a presigned upload url is requested for each attachment, file is uploaded to it and $attachments carry
+the url of uploaded file. Applies to single as well as bulk requests.
+*/
+func WithAttachmentUpload(opts AttachmentUploadOptions) ClientOption {
+	return func(c *Client) error {
+		if err := opts.cleanParams(); err != nil {
+			return err
+		}
+		c.attachmentUpload = &opts
+		return nil
+	}
+}
+
+/*
+WithAttachmentPolicy restricts size, total size and mime-types of attachments, and sanitizes their filenames.
+It's checked on every request before sending. See AttachmentPolicy
+*/
+func WithAttachmentPolicy(policy AttachmentPolicy) ClientOption {
+	return func(c *Client) error {
+		c.attachmentPolicy = &policy
+		return nil
+	}
+}
+
 func WithTimeout(timeoutInSeconds int) ClientOption {
 	return func(c *Client) error {
 		c.timeout = timeoutInSeconds
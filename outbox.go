@@ -0,0 +1,625 @@
+package suprsend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OUTBOX_WAL_FILE_NAME = "suprsend_outbox.wal"
+	// lock file which makes sure that only one outbox (in any process) uses a dir at a time
+	OUTBOX_LOCK_FILE_NAME = "suprsend_outbox.lock"
+	//
+	OUTBOX_DEFAULT_POLL_INTERVAL     = 1 * time.Second
+	OUTBOX_DEFAULT_MAX_ATTEMPTS      = 10
+	OUTBOX_DEFAULT_COMPACT_THRESHOLD = 1000
+	// max backoff between two attempts of an entry
+	OUTBOX_MAX_RETRY_BACKOFF = 5 * time.Minute
+)
+
+type OutboxOptions struct {
+	/*
+		directory where the write-ahead log file is kept. Mandatory.
+		A dir must be used by only one outbox at a time. On unix, this is enforced with a lock file
+		(NewOutbox returns error if dir is in use), on other platforms caller must make sure of it.
+	*/
+	Dir string
+	// by default, wal file is fsync-ed after every write. Set NoSync to skip fsync (faster, but less durable)
+	NoSync bool
+	// interval at which background sender looks for entries due for (re)delivery. Default: 1s
+	PollInterval time.Duration
+	// max delivery attempts for an entry with retryable error (5xx, 429, network). Default: 10
+	MaxAttempts int
+	// wal file is compacted once these many entries have been marked done. Default: 1000
+	CompactThreshold int
+	// called (from sender goroutine) when an entry is given up
+	OnError func(entryId string, err error)
+}
+
+func (o *OutboxOptions) cleanParams() {
+	if o.PollInterval <= 0 {
+		o.PollInterval = OUTBOX_DEFAULT_POLL_INTERVAL
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = OUTBOX_DEFAULT_MAX_ATTEMPTS
+	}
+	if o.CompactThreshold <= 0 {
+		o.CompactThreshold = OUTBOX_DEFAULT_COMPACT_THRESHOLD
+	}
+}
+
+type OutboxStats struct {
+	// entries written to wal but not yet delivered
+	Pending int
+	// entries delivered/given-up since outbox was opened
+	Delivered int64
+	Failed    int64
+	// current size of wal file
+	FileSizeInBytes int64
+	// age of oldest pending entry
+	Lag time.Duration
+}
+
+/*
+Outbox persists workflow triggers and events to a local write-ahead log before sending them,
+so that they are not lost if the process dies before the api call completes.
+Entries pending in the log are replayed (with their original idempotency keys) when outbox is reopened.
+*/
+type Outbox interface {
+	// writes workflow to wal and returns the outbox entry id
+	EnqueueWorkflow(*WorkflowTriggerRequest) (string, error)
+	// writes event to wal and returns the outbox entry id
+	EnqueueEvent(*Event) (string, error)
+	Stats() OutboxStats
+	// waits till all pending entries are delivered/given-up
+	Drain(context.Context) error
+	// stops background sender and closes wal file. Pending entries are sent when outbox is reopened.
+	Close() error
+}
+
+var _ Outbox = &outbox{}
+
+type outboxWorkflow struct {
+	Body            map[string]any `json:"body"`
+	IdempotencyKey  string         `json:"idempotency_key,omitempty"`
+	TenantId        string         `json:"tenant_id,omitempty"`
+	CancellationKey string         `json:"cancellation_key,omitempty"`
+}
+
+type outboxEvent struct {
+	DistinctId     string         `json:"distinct_id"`
+	EventName      string         `json:"event_name"`
+	Properties     map[string]any `json:"properties,omitempty"`
+	IdempotencyKey string         `json:"idempotency_key,omitempty"`
+	TenantId       string         `json:"tenant_id,omitempty"`
+	BrandId        string         `json:"brand_id,omitempty"`
+	// unix epoch in milliseconds, 0 if not set
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// single line in wal file
+type outboxLogLine struct {
+	// possible op: add/done
+	Op        string          `json:"op"`
+	Id        string          `json:"id"`
+	CreatedAt int64           `json:"created_at,omitempty"`
+	Workflow  *outboxWorkflow `json:"workflow,omitempty"`
+	Event     *outboxEvent    `json:"event,omitempty"`
+	// for op=done. possible status: success/failed
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type outboxEntry struct {
+	line *outboxLogLine
+	//
+	attempts    int
+	nextAttempt time.Time
+}
+
+type outbox struct {
+	client *Client
+	opts   OutboxOptions
+	path   string
+	//
+	mu        sync.Mutex
+	file      *os.File
+	lock      *os.File
+	pending   map[string]*outboxEntry
+	order     []string
+	doneCount int
+	delivered int64
+	failed    int64
+	//
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+func (c *Client) NewOutbox(opts *OutboxOptions) (Outbox, error) {
+	if opts == nil || opts.Dir == "" {
+		return nil, &Error{Message: "outbox: missing dir"}
+	}
+	optsCopy := *opts
+	optsCopy.cleanParams()
+	err := os.MkdirAll(optsCopy.Dir, 0o755)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	lock, err := lockOutboxDir(optsCopy.Dir)
+	if err != nil {
+		return nil, err
+	}
+	o := &outbox{
+		client:  c,
+		opts:    optsCopy,
+		path:    filepath.Join(optsCopy.Dir, OUTBOX_WAL_FILE_NAME),
+		lock:    lock,
+		pending: map[string]*outboxEntry{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	err = o.replay()
+	if err == nil {
+		// rewrite wal with only pending entries, then open it for appending
+		err = o.compact()
+	}
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	go o.run()
+	return o, nil
+}
+
+// reads existing wal file (if any) and collects entries not yet marked done
+func (o *outbox) replay() error {
+	f, err := os.Open(o.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return &Error{Err: err}
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	lineNo := 0
+	for {
+		lineBytes, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(lineBytes)) > 0 {
+			lineNo++
+			var line outboxLogLine
+			if uerr := decodeOutboxLine(lineBytes, &line); uerr != nil {
+				// most likely a partially written line, if process died while appending
+				log.Printf("WARNING: outbox: skipping unreadable wal line %d: %v", lineNo, uerr)
+			} else if line.Op == "add" {
+				o.pending[line.Id] = &outboxEntry{line: &line}
+				o.order = append(o.order, line.Id)
+			} else if line.Op == "done" {
+				delete(o.pending, line.Id)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return &Error{Err: err}
+		}
+	}
+	o.order = o.pendingOrder()
+	if len(o.pending) > 0 {
+		log.Printf("WARNING: outbox: replaying %d pending entries from %s", len(o.pending), o.path)
+	}
+	return nil
+}
+
+func (o *outbox) pendingOrder() []string {
+	order := make([]string, 0, len(o.pending))
+	for _, id := range o.order {
+		if _, found := o.pending[id]; found {
+			order = append(order, id)
+		}
+	}
+	return order
+}
+
+/*
+rewrites wal file with pending entries only. Must be called with lock held (or before sender starts).
+New wal is written to a temp file, which is opened for appending and renamed over the wal, so its handle
+becomes the wal handle. If any step fails, current wal (and its handle) is kept as-is.
+*/
+func (o *outbox) compact() error {
+	tmpPath := o.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return &Error{Err: err}
+	}
+	discardTmp := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return &Error{Err: err}
+	}
+	order := o.pendingOrder()
+	w := bufio.NewWriter(tmp)
+	for _, id := range order {
+		lineBytes, err := json.Marshal(o.pending[id].line)
+		if err != nil {
+			return discardTmp(err)
+		}
+		w.Write(lineBytes)
+		w.WriteByte('\n')
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		return discardTmp(err)
+	}
+	if err = os.Rename(tmpPath, o.path); err != nil {
+		return discardTmp(err)
+	}
+	if !o.opts.NoSync {
+		syncDir(o.opts.Dir)
+	}
+	if o.file != nil {
+		o.file.Close()
+	}
+	o.file = tmp
+	o.order = order
+	o.doneCount = 0
+	return nil
+}
+
+// makes a rename in dir durable. Not supported on all platforms, so errors are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// decodes a wal line. Numbers are kept as json.Number, so that large integers in body/properties
+// don't lose precision (as float64) when entry is sent
+func decodeOutboxLine(lineBytes []byte, line *outboxLogLine) error {
+	decoder := json.NewDecoder(bytes.NewReader(lineBytes))
+	decoder.UseNumber()
+	return decoder.Decode(line)
+}
+
+// appends a line to wal and returns the written bytes. Must be called with lock held
+func (o *outbox) appendLine(line *outboxLogLine) ([]byte, error) {
+	lineBytes, err := json.Marshal(line)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	lineBytes = append(lineBytes, '\n')
+	_, err = o.file.Write(lineBytes)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	if !o.opts.NoSync {
+		err = o.file.Sync()
+		if err != nil {
+			return nil, &Error{Err: err}
+		}
+	}
+	return lineBytes, nil
+}
+
+func (o *outbox) enqueue(line *outboxLogLine) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return "", ErrOutboxClosed
+	}
+	lineBytes, err := o.appendLine(line)
+	if err != nil {
+		return "", err
+	}
+	// keep the decoded copy of what was written, so that a live send is same as a replayed one,
+	// and caller's later changes to request don't affect it.
+	var written outboxLogLine
+	err = decodeOutboxLine(lineBytes, &written)
+	if err != nil {
+		return "", &Error{Err: err}
+	}
+	o.pending[line.Id] = &outboxEntry{line: &written}
+	o.order = append(o.order, line.Id)
+	// wake up sender
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return line.Id, nil
+}
+
+func (o *outbox) EnqueueWorkflow(wf *WorkflowTriggerRequest) (string, error) {
+	if wf == nil {
+		return "", &Error{Message: "missing workflow"}
+	}
+	// validate (on a copy, caller's request is not modified) before writing, so that invalid requests
+	// are reported to caller right away
+	wfCopy := &WorkflowTriggerRequest{Body: wf.asJson()}
+	wfJson, _, _, err := wfCopy.getFinalJson(o.client, false)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	// idempotency_key must remain same across replays. Entry id is used if there's no (derived) key
+	idempotencyKey, _ := wfJson["$idempotency_key"].(string)
+	if idempotencyKey == "" {
+		idempotencyKey = id
+	}
+	line := &outboxLogLine{
+		Op: "add", Id: id, CreatedAt: time.Now().UnixMilli(),
+		Workflow: &outboxWorkflow{
+			Body:            wf.Body,
+			IdempotencyKey:  idempotencyKey,
+			TenantId:        wf.TenantId,
+			CancellationKey: wf.CancellationKey,
+		},
+	}
+	return o.enqueue(line)
+}
+
+func (o *outbox) EnqueueEvent(ev *Event) (string, error) {
+	if ev == nil {
+		return "", &Error{Message: "missing event"}
+	}
+	evCopy := ev.shallowCopy()
+	evJson, _, _, err := evCopy.getFinalJson(o.client, false)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	idempotencyKey, _ := evJson["$idempotency_key"].(string)
+	if idempotencyKey == "" {
+		idempotencyKey = id
+	}
+	line := &outboxLogLine{
+		Op: "add", Id: id, CreatedAt: time.Now().UnixMilli(),
+		Event: &outboxEvent{
+			DistinctId:     ev.DistinctId,
+			EventName:      ev.EventName,
+			Properties:     ev.Properties,
+			IdempotencyKey: idempotencyKey,
+			TenantId:       ev.TenantId,
+			BrandId:        ev.BrandId,
+		},
+	}
+	if !ev.Timestamp.IsZero() {
+		line.Event.Timestamp = ev.Timestamp.UnixMilli()
+	}
+	return o.enqueue(line)
+}
+
+func (o *outbox) Stats() OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stats := OutboxStats{
+		Pending:   len(o.pending),
+		Delivered: o.delivered,
+		Failed:    o.failed,
+	}
+	if o.file != nil {
+		if fi, err := o.file.Stat(); err == nil {
+			stats.FileSizeInBytes = fi.Size()
+		}
+	}
+	for _, id := range o.order {
+		if e, found := o.pending[id]; found {
+			stats.Lag = time.Since(time.UnixMilli(e.line.CreatedAt))
+			break
+		}
+	}
+	return stats
+}
+
+func (o *outbox) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		o.mu.Lock()
+		pendingCount := len(o.pending)
+		o.mu.Unlock()
+		if pendingCount == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+	close(o.stop)
+	<-o.done
+	//
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.file.Close()
+	// dir can be used by another outbox now
+	o.lock.Close()
+	if err != nil {
+		return &Error{Err: err}
+	}
+	return nil
+}
+
+func (o *outbox) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(o.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		o.sendDueEntries()
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *outbox) dueEntries() []*outboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	due := []*outboxEntry{}
+	for _, id := range o.order {
+		if e, found := o.pending[id]; found && !e.nextAttempt.After(now) {
+			due = append(due, e)
+		}
+	}
+	return due
+}
+
+func (o *outbox) sendDueEntries() {
+	for _, e := range o.dueEntries() {
+		select {
+		case <-o.stop:
+			return
+		default:
+		}
+		_, err := o.send(e.line)
+		if err == nil {
+			o.markDone(e, nil)
+			continue
+		}
+		e.attempts++
+		if isRetryableError(err) && e.attempts < o.opts.MaxAttempts {
+			backoff := min(time.Duration(1<<min(e.attempts, 20))*time.Second, OUTBOX_MAX_RETRY_BACKOFF)
+			e.nextAttempt = time.Now().Add(backoff)
+			if o.client.debug {
+				log.Printf("DEBUG: outbox: entry %s failed (attempt %d), retrying in %v: %v", e.line.Id, e.attempts, backoff, err)
+			}
+			continue
+		}
+		log.Printf("ERROR: outbox: giving up entry %s after %d attempts: %v", e.line.Id, e.attempts, err)
+		o.markDone(e, err)
+		if o.opts.OnError != nil {
+			o.opts.OnError(e.line.Id, err)
+		}
+	}
+}
+
+func (o *outbox) send(line *outboxLogLine) (*Response, error) {
+	if line.Workflow != nil {
+		body := line.Workflow.Body
+		if body == nil {
+			body = map[string]any{}
+		}
+		normalizeAttachmentsAfterDecode(body, "data")
+		wf := &WorkflowTriggerRequest{
+			Body:            body,
+			IdempotencyKey:  line.Workflow.IdempotencyKey,
+			TenantId:        line.Workflow.TenantId,
+			CancellationKey: line.Workflow.CancellationKey,
+		}
+		return o.client.Workflows.Trigger(wf)
+	} else if line.Event != nil {
+		props := line.Event.Properties
+		if props == nil {
+			props = map[string]any{}
+		}
+		normalizeAttachmentsAfterDecode(map[string]any{"properties": props}, "properties")
+		ev := &Event{
+			DistinctId:     line.Event.DistinctId,
+			EventName:      line.Event.EventName,
+			Properties:     props,
+			IdempotencyKey: line.Event.IdempotencyKey,
+			TenantId:       line.Event.TenantId,
+			BrandId:        line.Event.BrandId,
+		}
+		if line.Event.Timestamp > 0 {
+			ev.Timestamp = time.UnixMilli(line.Event.Timestamp)
+		}
+		return o.client.TrackEvent(ev)
+	}
+	return nil, &Error{Message: "outbox: invalid entry"}
+}
+
+func (o *outbox) markDone(e *outboxEntry, sendErr error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	line := &outboxLogLine{Op: "done", Id: e.line.Id, Status: "success"}
+	if sendErr != nil {
+		line.Status, line.Error = "failed", sendErr.Error()
+		o.failed++
+	} else {
+		o.delivered++
+	}
+	_, err := o.appendLine(line)
+	if err != nil {
+		// entry would get redelivered on restart, which is fine as idempotency_key is preserved
+		log.Printf("WARNING: outbox: error while marking entry %s done: %v", e.line.Id, err)
+	}
+	delete(o.pending, e.line.Id)
+	o.doneCount++
+	if o.doneCount >= o.opts.CompactThreshold {
+		err = o.compact()
+		if err != nil {
+			log.Printf("WARNING: outbox: error while compacting wal: %v", err)
+		}
+	}
+}
+
+// retryable errors: network errors, 429 and 5xx responses
+func isRetryableError(err error) bool {
+	var serr *Error
+	if errors.As(err, &serr) {
+		if serr.Code != 0 {
+			return serr.Code == 429 || serr.Code >= 500
+		}
+		// code is not set for client-side validation errors, retrying them won't help
+		return serr.Err != nil
+	}
+	return true
+}
+
+/*
+After json round-trip, $attachments is decoded as []any, while rest of the sdk expects []map[string]any.
+Converts container[key]["$attachments"] back to []map[string]any.
+*/
+func normalizeAttachmentsAfterDecode(container map[string]any, key string) {
+	d, ok := container[key].(map[string]any)
+	if !ok {
+		return
+	}
+	attachs, ok := d["$attachments"].([]any)
+	if !ok {
+		return
+	}
+	converted := []map[string]any{}
+	for _, a := range attachs {
+		if am, ok := a.(map[string]any); ok {
+			converted = append(converted, am)
+		}
+	}
+	d["$attachments"] = converted
+}
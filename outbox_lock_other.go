@@ -0,0 +1,17 @@
+//go:build !unix
+
+package suprsend
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// file locking is not supported on this platform, a dir must be used by only one outbox at a time
+func lockOutboxDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, OUTBOX_LOCK_FILE_NAME), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return f, nil
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package suprsend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// takes an exclusive lock on dir, which is held till returned file is closed
+func lockOutboxDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, OUTBOX_LOCK_FILE_NAME), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &Error{Message: fmt.Sprintf("outbox: dir %s is in use by another outbox", dir)}
+		}
+		return nil, &Error{Err: err}
+	}
+	return f, nil
+}
@@ -0,0 +1,226 @@
+package suprsend
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func drainOutbox(t *testing.T, o Outbox) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+}
+
+func TestOutboxReplaysPendingEntriesAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	var attempts atomic.Int32
+	failing := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		attempts.Add(1)
+		writeJson(w, 503, map[string]any{"message": "unavailable"})
+	})
+	c := newTestClient(t, failing.URL)
+	o, err := c.NewOutbox(&OutboxOptions{Dir: dir, NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// above 2^53, would be rounded if decoded as float64
+	ev := testEvent("u1", map[string]any{"big": int64(9007199254740993)})
+	ev.IdempotencyKey = "idem-1"
+	if _, err = o.EnqueueEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for attempts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if attempts.Load() == 0 {
+		t.Fatal("entry was not attempted")
+	}
+	o.Close()
+
+	ok := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"message_id": "m1"})
+	})
+	c = newTestClient(t, ok.URL)
+	o, err = c.NewOutbox(&OutboxOptions{Dir: dir, NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if pending := o.Stats().Pending; pending != 1 {
+		t.Errorf("pending after reopen = %d, want 1", pending)
+	}
+	drainOutbox(t, o)
+	reqs := ok.requestsTo("/v2/event/")
+	if len(reqs) != 1 {
+		t.Fatalf("event calls = %d, want 1", len(reqs))
+	}
+	body := reqs[0].jsonBody(t).(map[string]any)
+	if body["$idempotency_key"] != "idem-1" {
+		t.Errorf("$idempotency_key = %v, want idem-1", body["$idempotency_key"])
+	}
+	if !strings.Contains(string(reqs[0].Body), "9007199254740993") {
+		t.Errorf("large integer lost precision on replay: %s", reqs[0].Body)
+	}
+	if stats := o.Stats(); stats.Delivered != 1 || stats.Pending != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestOutboxSkipsUnreadableWalLines(t *testing.T) {
+	dir := t.TempDir()
+	wal := `{"op":"add","id":"e1","created_at":1,"event":{"distinct_id":"u1","event_name":"test_event","idempotency_key":"k1"}}
+{"op":"add","id":"e2","created_at":2,"event":{"distinct_id":"u2","event_name":"test_event","idempotency_key":"k2"}}
+{"op":"done","id":"e2","status":"success"}
+{"op":"add","id":"e3","crea`
+	if err := os.WriteFile(filepath.Join(dir, OUTBOX_WAL_FILE_NAME), []byte(wal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{})
+	})
+	o, err := newTestClient(t, ts.URL).NewOutbox(&OutboxOptions{Dir: dir, NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	drainOutbox(t, o)
+	reqs := ts.requestsTo("/v2/event/")
+	if len(reqs) != 1 {
+		t.Fatalf("event calls = %d, want 1 (only e1 is pending)", len(reqs))
+	}
+	if key := reqs[0].jsonBody(t).(map[string]any)["$idempotency_key"]; key != "k1" {
+		t.Errorf("$idempotency_key = %v, want k1", key)
+	}
+}
+
+func TestOutboxDirIsLocked(t *testing.T) {
+	dir := t.TempDir()
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{})
+	})
+	c := newTestClient(t, ts.URL)
+	o, err := c.NewOutbox(&OutboxOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.NewOutbox(&OutboxOptions{Dir: dir}); err == nil {
+		t.Fatal("second outbox on same dir must fail while first one is open")
+	}
+	o.Close()
+	o, err = c.NewOutbox(&OutboxOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("dir must be usable after Close: %v", err)
+	}
+	o.Close()
+}
+
+func TestOutboxKeepsWalIfCompactionFails(t *testing.T) {
+	dir := t.TempDir()
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{})
+	})
+	o, err := newTestClient(t, ts.URL).NewOutbox(&OutboxOptions{Dir: dir, NoSync: true, CompactThreshold: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	// a dir in place of temp file makes compaction fail
+	tmpPath := filepath.Join(dir, OUTBOX_WAL_FILE_NAME+".tmp")
+	if err = os.Mkdir(tmpPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"u1", "u2"} {
+		if _, err = o.EnqueueEvent(testEvent(id, nil)); err != nil {
+			t.Fatalf("enqueue after failed compaction: %v", err)
+		}
+		drainOutbox(t, o)
+	}
+	if n := len(ts.requestsTo("/v2/event/")); n != 2 {
+		t.Errorf("event calls = %d, want 2", n)
+	}
+	sizeBefore := o.Stats().FileSizeInBytes
+	if sizeBefore == 0 {
+		t.Fatal("wal must still have entries written before failed compaction")
+	}
+	// once compaction succeeds, wal is rewritten with pending entries only
+	os.Remove(tmpPath)
+	o.EnqueueEvent(testEvent("u3", nil))
+	drainOutbox(t, o)
+	if size := o.Stats().FileSizeInBytes; size >= sizeBefore {
+		t.Errorf("wal size after compaction = %d, want < %d", size, sizeBefore)
+	}
+}
+
+func TestOutboxIdempotencyKey(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m1"})
+	})
+	sentKey := func(path string) any {
+		reqs := ts.requestsTo(path)
+		return reqs[len(reqs)-1].jsonBody(t).(map[string]any)["$idempotency_key"]
+	}
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		ownKey  string
+		derived bool
+	}{
+		{"entry id without any key", nil, "", false},
+		{"own key", []ClientOption{WithAutoIdempotency(nil)}, "own-key", false},
+		{"derived key", []ClientOption{WithAutoIdempotency(nil)}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, ts.URL, tt.opts...)
+			o, err := c.NewOutbox(&OutboxOptions{Dir: t.TempDir(), NoSync: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer o.Close()
+			wf := testWorkflow("wf", "u1")
+			wf.IdempotencyKey = tt.ownKey
+			wfId, err := o.EnqueueWorkflow(wf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ev := testEvent("u1", map[string]any{"k": "v"})
+			ev.IdempotencyKey = tt.ownKey
+			evId, err := o.EnqueueEvent(ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			drainOutbox(t, o)
+			wantWf, wantEv := tt.ownKey, tt.ownKey
+			switch {
+			case tt.derived:
+				wantWf, _ = (&WorkflowTriggerRequest{Body: testWorkflow("wf", "u1").Body}).GenerateIdempotencyKey(nil)
+				wantEv, _ = testEvent("u1", map[string]any{"k": "v"}).GenerateIdempotencyKey(nil)
+			case tt.ownKey == "":
+				wantWf, wantEv = wfId, evId
+			}
+			if got := sentKey("/trigger/"); got != wantWf {
+				t.Errorf("workflow key = %v, want %s", got, wantWf)
+			}
+			if got := sentKey("/v2/event/"); got != wantEv {
+				t.Errorf("event key = %v, want %s", got, wantEv)
+			}
+			// caller's request is not modified
+			if _, found := wf.Body["$idempotency_key"]; found && tt.ownKey == "" {
+				t.Errorf("workflow body modified: %v", wf.Body)
+			}
+			if _, found := ev.Properties["$ss_sdk_version"]; found {
+				t.Errorf("event properties modified: %v", ev.Properties)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package suprsend
+
+import (
+	"context"
+	"iter"
+)
+
+/*
+fetches next page on each call, returns items of page and whether there are more pages after it.
+It's never called concurrently, so it can keep pagination state (cursor/offset) in closure.
+*/
+type pageFetcher[T any] func(ctx context.Context) ([]T, bool, error)
+
+/*
+Returns iterator over items of all pages. Iteration stops at first error (which is yielded with zero item),
+when ctx is done, or when caller breaks out of the loop.
+If prefetch is true, next page is fetched concurrently while caller is going through items of current page.
+*/
+func paginate[T any](ctx context.Context, fetch pageFetcher[T], prefetch bool) iter.Seq2[T, error] {
+	type page struct {
+		items   []T
+		hasNext bool
+		err     error
+	}
+	return func(yield func(T, error) bool) {
+		// cancels in-flight prefetch if caller stops early
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		fetchPage := func() page {
+			if err := ctx.Err(); err != nil {
+				return page{err: &Error{Err: err}}
+			}
+			items, hasNext, err := fetch(ctx)
+			return page{items: items, hasNext: hasNext, err: err}
+		}
+		cur := fetchPage()
+		for {
+			if cur.err != nil {
+				var zero T
+				yield(zero, cur.err)
+				return
+			}
+			var nextCh chan page
+			if prefetch && cur.hasNext {
+				// buffered, so that goroutine doesn't block if caller stops early
+				nextCh = make(chan page, 1)
+				go func() { nextCh <- fetchPage() }()
+			}
+			for _, item := range cur.items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !cur.hasNext {
+				return
+			}
+			if nextCh != nil {
+				cur = <-nextCh
+			} else {
+				cur = fetchPage()
+			}
+		}
+	}
+}
+
+/*
+pageFetcher for cursor paginated apis. Pages are fetched forward from opts.After (or from start),
+caller's opts is not modified.
+*/
+func cursorPageFetcher[T any](opts *CursorListApiOptions,
+	list func(context.Context, *CursorListApiOptions) (*CursorListApiMetaInfo, []T, error),
+) pageFetcher[T] {
+	pageOpts := CursorListApiOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.Before = ""
+	return func(ctx context.Context) ([]T, bool, error) {
+		meta, results, err := list(ctx, &pageOpts)
+		if err != nil {
+			return nil, false, err
+		}
+		if meta == nil || !meta.HasNext || meta.After == "" || meta.After == pageOpts.After {
+			return results, false, nil
+		}
+		pageOpts.After = meta.After
+		return results, true, nil
+	}
+}
+
+/*
+pageFetcher for limit/offset paginated apis. offset is advanced by number of items received,
+until count (total) is reached or an empty page is received.
+*/
+func offsetPageFetcher[T any](limit, offset int,
+	list func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []T, error),
+) pageFetcher[T] {
+	return func(ctx context.Context) ([]T, bool, error) {
+		meta, results, err := list(ctx, limit, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		offset += len(results)
+		hasNext := len(results) > 0 && meta != nil && offset < meta.Count
+		return results, hasNext, nil
+	}
+}
+
+// adapts a list api which returns CursorListApiResponse, for use with cursorPageFetcher
+func cursorResponsePages(list func(context.Context, *CursorListApiOptions) (*CursorListApiResponse, error),
+) func(context.Context, *CursorListApiOptions) (*CursorListApiMetaInfo, []map[string]any, error) {
+	return func(ctx context.Context, opts *CursorListApiOptions) (*CursorListApiMetaInfo, []map[string]any, error) {
+		resp, err := list(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Meta, resp.Results, nil
+	}
+}
+
+func prefetchEnabled(opts *CursorListApiOptions) bool {
+	return opts != nil && opts.Prefetch
+}
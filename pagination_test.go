@@ -0,0 +1,280 @@
+package suprsend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pageFetcher over given pages, counting fetches. Fetch of page errPage (if > 0) fails
+func staticPages(pages [][]int, errPage int, fetches *atomic.Int32) pageFetcher[int] {
+	return func(ctx context.Context) ([]int, bool, error) {
+		n := int(fetches.Add(1))
+		if n == errPage {
+			return nil, false, &Error{Code: 500, Message: "page failed"}
+		}
+		return pages[n-1], n < len(pages), nil
+	}
+}
+
+func collect[T any](seq func(func(T, error) bool)) ([]T, error) {
+	items := []T{}
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func TestPaginate(t *testing.T) {
+	pages := [][]int{{1, 2}, {}, {3}, {4, 5}}
+	tests := []struct {
+		name        string
+		errPage     int
+		prefetch    bool
+		want        []int
+		wantErr     bool
+		wantFetches int32
+	}{
+		{"all pages", 0, false, []int{1, 2, 3, 4, 5}, false, 4},
+		{"all pages with prefetch", 0, true, []int{1, 2, 3, 4, 5}, false, 4},
+		{"error stops iteration", 3, false, []int{1, 2}, true, 3},
+		{"error stops iteration with prefetch", 3, true, []int{1, 2}, true, 3},
+		{"error on first page", 1, false, []int{}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			got, err := collect(paginate(context.Background(), staticPages(pages, tt.errPage, &fetches), tt.prefetch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if n := fetches.Load(); n != tt.wantFetches {
+				t.Errorf("fetches = %d, want %d", n, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestPaginateEarlyBreak(t *testing.T) {
+	pages := [][]int{{1, 2}, {3, 4}, {5}}
+	for _, prefetch := range []bool{false, true} {
+		var fetches atomic.Int32
+		for item := range paginate(context.Background(), staticPages(pages, 0, &fetches), prefetch) {
+			if item == 1 {
+				break
+			}
+		}
+		// with prefetch, page after current one may have been fetched already, but no further
+		maxFetches := int32(1)
+		if prefetch {
+			maxFetches = 2
+		}
+		if n := fetches.Load(); n > maxFetches {
+			t.Errorf("prefetch %v: fetches = %d, want at most %d", prefetch, n, maxFetches)
+		}
+	}
+}
+
+func TestPaginatePrefetchesWhileIterating(t *testing.T) {
+	secondFetched := make(chan struct{})
+	calls := 0
+	fetch := func(ctx context.Context) ([]int, bool, error) {
+		calls++
+		if calls == 2 {
+			close(secondFetched)
+			return []int{3}, false, nil
+		}
+		return []int{1, 2}, true, nil
+	}
+	got := []int{}
+	for item := range paginate(context.Background(), fetch, true) {
+		if item == 1 {
+			// next page is fetched while caller is still on first item of current page
+			select {
+			case <-secondFetched:
+			case <-time.After(2 * time.Second):
+				t.Fatal("next page not prefetched")
+			}
+		}
+		got = append(got, item)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("items = %v", got)
+	}
+}
+
+func TestPaginateContextCancel(t *testing.T) {
+	pages := [][]int{{1, 2}, {3, 4}, {5}}
+	// ctx done before start
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var fetches atomic.Int32
+	_, err := collect(paginate(ctx, staticPages(pages, 0, &fetches), false))
+	if !errors.Is(err, context.Canceled) || fetches.Load() != 0 {
+		t.Errorf("err = %v, fetches = %d", err, fetches.Load())
+	}
+	// ctx done during iteration: current page is finished, next one isn't fetched
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fetches.Store(0)
+	got := []int{}
+	for item, err := range paginate(ctx, staticPages(pages, 0, &fetches), false) {
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v", err)
+			}
+			break
+		}
+		got = append(got, item)
+		cancel()
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) || fetches.Load() != 1 {
+		t.Errorf("items = %v, fetches = %d", got, fetches.Load())
+	}
+}
+
+func TestCursorPageFetcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		meta      *CursorListApiMetaInfo
+		wantNext  bool
+		wantAfter string
+	}{
+		{"has next", &CursorListApiMetaInfo{HasNext: true, After: "c2"}, true, "c2"},
+		{"last page", &CursorListApiMetaInfo{HasNext: false, After: "c2"}, false, "c1"},
+		{"has next without cursor", &CursorListApiMetaInfo{HasNext: true}, false, "c1"},
+		{"same cursor again", &CursorListApiMetaInfo{HasNext: true, After: "c1"}, false, "c1"},
+		{"no meta", nil, false, "c1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &CursorListApiOptions{Limit: 5, Before: "b", After: "c1"}
+			var seen []CursorListApiOptions
+			fetch := cursorPageFetcher(opts, func(ctx context.Context, o *CursorListApiOptions) (*CursorListApiMetaInfo, []int, error) {
+				seen = append(seen, *o)
+				return tt.meta, []int{1}, nil
+			})
+			_, hasNext, _ := fetch(context.Background())
+			if hasNext != tt.wantNext {
+				t.Errorf("hasNext = %v, want %v", hasNext, tt.wantNext)
+			}
+			fetch(context.Background())
+			if seen[0].After != "c1" || seen[0].Before != "" || seen[0].Limit != 5 {
+				t.Errorf("first page options = %+v", seen[0])
+			}
+			if seen[1].After != tt.wantAfter {
+				t.Errorf("next page after = %s, want %s", seen[1].After, tt.wantAfter)
+			}
+			if opts.After != "c1" || opts.Before != "b" {
+				t.Errorf("caller's opts modified: %+v", opts)
+			}
+		})
+	}
+}
+
+func TestOffsetPageFetcher(t *testing.T) {
+	type call struct{ limit, offset int }
+	calls := []call{}
+	results := [][]int{{1, 2}, {3, 4}, {5}}
+	fetch := offsetPageFetcher(2, 10, func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []int, error) {
+		calls = append(calls, call{limit, offset})
+		return &ListApiMetaInfo{Count: 15}, results[len(calls)-1], nil
+	})
+	got, err := collect(paginate(context.Background(), fetch, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("items = %v", got)
+	}
+	if want := []call{{2, 10}, {2, 12}, {2, 14}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	// empty page stops pagination even if count says there are more
+	empty := offsetPageFetcher(2, 0, func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []int, error) {
+		return &ListApiMetaInfo{Count: 100}, nil, nil
+	})
+	if _, hasNext, _ := empty(context.Background()); hasNext {
+		t.Error("empty page must be last page")
+	}
+}
+
+func TestUsersAllFollowsCursor(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("after") {
+		case "":
+			w.Write([]byte(`{"meta": {"has_next": true, "after": "c1"}, "results": [{"distinct_id": "u1"}, {"distinct_id": "u2"}]}`))
+		case "c1":
+			w.Write([]byte(`{"meta": {"has_next": true, "after": "c2"}, "results": [{"distinct_id": "u3"}]}`))
+		default:
+			w.Write([]byte(`{"meta": {"has_next": false}, "results": [{"distinct_id": "u4"}]}`))
+		}
+	})
+	c := newTestClient(t, ts.URL)
+	for _, prefetch := range []bool{false, true} {
+		ids := []string{}
+		for u, err := range c.Users.All(context.Background(), &CursorListApiOptions{Limit: 2, Prefetch: prefetch}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, u.DistinctId)
+		}
+		if !reflect.DeepEqual(ids, []string{"u1", "u2", "u3", "u4"}) {
+			t.Errorf("prefetch %v: users = %v", prefetch, ids)
+		}
+	}
+	if n := len(ts.Requests()); n != 6 {
+		t.Errorf("requests = %d, want 6", n)
+	}
+}
+
+func TestTenantsAllFollowsOffset(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		q, _ := url.ParseQuery(r.URL.RawQuery)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		results := []map[string]any{}
+		for i := offset; i < offset+limit && i < 5; i++ {
+			results = append(results, map[string]any{"tenant_id": "t" + strconv.Itoa(i)})
+		}
+		writeJson(w, 200, map[string]any{
+			"meta":    map[string]any{"count": 5, "limit": limit, "offset": offset},
+			"results": results,
+		})
+	})
+	c := newTestClient(t, ts.URL)
+	ids := []string{}
+	for tenant, err := range c.Tenants.All(context.Background(), &TenantListOptions{Limit: 2, Offset: 1}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, *tenant.TenantId)
+	}
+	if !reflect.DeepEqual(ids, []string{"t1", "t2", "t3", "t4"}) {
+		t.Errorf("tenants = %v", ids)
+	}
+	if n := len(ts.Requests()); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+	// api error is yielded and stops iteration
+	errServer := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 500, map[string]any{"code": 500, "message": "boom"})
+	})
+	c = newTestClient(t, errServer.URL)
+	if _, err := collect(c.Tenants.All(context.Background(), nil)); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,292 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestHandler sends a prepared (signed) request
+type RequestHandler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps sending of every api request e.g. for logging, tracing or metrics.
+// It's called for every attempt, and the request it gets is already signed.
+type Middleware func(next RequestHandler) RequestHandler
+
+// Retry policy of requests made via Client.Do. Requests are retried on network errors, 429 and 5xx responses.
+type RetryPolicy struct {
+	// total attempts, including the first one. default: 1 (no retry)
+	MaxAttempts int
+	// wait before first retry, doubled for every next retry. default: 500ms
+	InitialBackoff time.Duration
+	// max wait between two attempts. default: 10s
+	MaxBackoff time.Duration
+}
+
+func (p *RetryPolicy) cleanParams() {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 1
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 500 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+}
+
+func (p *RetryPolicy) backoff(retryNum int, httpRes *http.Response) time.Duration {
+	// honour Retry-After (in seconds) sent with 429/503
+	if httpRes != nil {
+		if secs, err := strconv.Atoi(httpRes.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, p.MaxBackoff)
+		}
+	}
+	wait := p.InitialBackoff << (retryNum - 1)
+	if wait <= 0 || wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// RequestOption customizes a single api call, without changing the shared Client
+type RequestOption func(o *requestOptions)
+
+type requestOptions struct {
+	headers  map[string]string
+	timeout  time.Duration
+	tenantId string
+	debug    bool
+	ctx      context.Context
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	ro := &requestOptions{headers: map[string]string{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(ro)
+		}
+	}
+	return ro
+}
+
+// WithHeader sets an extra header on the request. It can't override Authorization/Date headers used for signing.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.headers[key] = value
+	}
+}
+
+/*
+WithCallTimeout sets time limit of the call (incl. reading response) in place of client's timeout,
+so it can be shorter or longer than client's timeout.
+*/
+func WithCallTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithTenantId sends tenant_id query param with the call, unless it's already set (e.g. via api options)
+func WithTenantId(tenantId string) RequestOption {
+	return func(o *requestOptions) {
+		o.tenantId = strings.TrimSpace(tenantId)
+	}
+}
+
+// WithDebugTrace logs request and response of this call, irrespective of client's debug setting
+func WithDebugTrace() RequestOption {
+	return func(o *requestOptions) {
+		o.debug = true
+	}
+}
+
+/*
+WithContext sets context of calls which don't take a ctx argument (e.g. TrackEvent, Workflows.Trigger,
+Trigger of bulk instances). Call is cancelled once ctx is done.
+*/
+func WithContext(ctx context.Context) RequestOption {
+	return func(o *requestOptions) {
+		o.ctx = ctx
+	}
+}
+
+// context of call as set by WithContext, Background if not set
+func (ro *requestOptions) callContext() context.Context {
+	if ro.ctx == nil {
+		return context.Background()
+	}
+	return ro.ctx
+}
+
+/*
+Do calls any SuprSend api endpoint, including ones which don't have a dedicated method in sdk yet.
+path is relative to client's base url (e.g "v1/user/"), an absolute url is used as-is.
+body (if not nil) is sent as json, []byte/json.RawMessage are sent as-is. Response json is decoded into out, if not nil.
+Request is signed same as other sdk calls, and goes through client's middleware and retry policy.
+Error responses are returned as *Error.
+*/
+func (c *Client) Do(ctx context.Context, method string, path string, body any, out any, opts ...RequestOption) error {
+	ro := newRequestOptions(opts)
+	method = strings.ToUpper(strings.TrimSpace(method))
+	reqUrl := c.resolveUrl(path)
+	// encode body once, every attempt sends same bytes
+	var reqBody any
+	switch b := body.(type) {
+	case nil:
+	case encodedJson:
+		reqBody = b
+	case json.RawMessage:
+		reqBody = encodedJson(b)
+	case []byte:
+		reqBody = encodedJson(b)
+	default:
+		encoded, err := c.encodeRequestBody(method, body)
+		if err != nil {
+			return err
+		}
+		reqBody = encodedJson(encoded)
+	}
+	policy := c.retryPolicy
+	policy.cleanParams()
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		httpRes, err := c.doOnce(ctx, method, reqUrl, reqBody, ro)
+		if err == nil && !isRetryableStatus(httpRes.StatusCode) {
+			defer httpRes.Body.Close()
+			return c.parseApiResponse(httpRes, out)
+		}
+		if err != nil {
+			// request could not be prepared, or context is done. retrying won't help
+			var serr *Error
+			if errors.As(err, &serr) || ctx.Err() != nil {
+				return err
+			}
+			lastErr = &Error{Err: err}
+		} else {
+			lastErr = c.parseApiResponse(httpRes, nil)
+			httpRes.Body.Close()
+		}
+		if attempt == policy.MaxAttempts {
+			break
+		}
+		wait := policy.backoff(attempt, httpRes)
+		log.Printf("WARNING: %s %s failed (attempt %d/%d), retrying in %v: %v",
+			method, reqUrl, attempt, policy.MaxAttempts, wait, lastErr)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+// prepares, signs and sends request
+func (c *Client) doOnce(ctx context.Context, method, reqUrl string, reqBody any, ro *requestOptions) (*http.Response, error) {
+	request, err := c.prepareHttpRequestWithOptions(method, reqUrl, reqBody, ro)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendWithOptions(ctx, request, ro)
+}
+
+// sends request prepared by prepareHttpRequest, through middleware chain. Every sdk api call goes through it.
+func (c *Client) send(ctx context.Context, request *http.Request, opts ...RequestOption) (*http.Response, error) {
+	return c.sendWithOptions(ctx, request, newRequestOptions(opts))
+}
+
+func (c *Client) sendWithOptions(ctx context.Context, request *http.Request, ro *requestOptions) (*http.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var cancel context.CancelFunc
+	if ro.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ro.timeout)
+	}
+	request = request.WithContext(ctx)
+	httpClient := c.httpClient
+	if ro.timeout > 0 && httpClient.Timeout > 0 {
+		// call's ctx enforces the timeout, client's timeout must not cut a longer one short
+		clientCopy := *httpClient
+		clientCopy.Timeout = 0
+		httpClient = &clientCopy
+	}
+	handler := RequestHandler(httpClient.Do)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	if ro.debug && !c.debug {
+		logRequest(request)
+	}
+	httpRes, err := handler(request)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+	if ro.debug && !c.debug {
+		logResponse(httpRes)
+	}
+	if cancel != nil {
+		// call's timeout covers reading the body as well, so cancel only once body is closed
+		httpRes.Body = &cancelOnClose{ReadCloser: httpRes.Body, cancel: cancel}
+	}
+	return httpRes, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// applies request options to url and headers, before request is signed
+func (ro *requestOptions) apply(httpUrl string, headers map[string]string) string {
+	for k, v := range ro.headers {
+		// keys are canonicalized, so that e.g "content-type" replaces (not duplicates) the header used for signing
+		k = http.CanonicalHeaderKey(k)
+		if k == "Authorization" || k == "Date" {
+			continue
+		}
+		headers[k] = v
+	}
+	if ro.tenantId != "" {
+		parsed, err := url.Parse(httpUrl)
+		if err == nil {
+			q := parsed.Query()
+			if !q.Has("tenant_id") {
+				q.Set("tenant_id", ro.tenantId)
+				parsed.RawQuery = q.Encode()
+				httpUrl = parsed.String()
+			}
+		}
+	}
+	return httpUrl
+}
+
+func (c *Client) resolveUrl(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return fmt.Sprintf("%s%s", c.baseUrl, strings.TrimPrefix(path, "/"))
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == 429 || statusCode >= 500
+}
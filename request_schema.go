@@ -3,6 +3,7 @@ package suprsend
 import (
 	"embed"
 	"fmt"
+	"sync"
 
 	"github.com/xeipuuv/gojsonschema"
 )
@@ -10,13 +11,24 @@ import (
 //go:embed request_json
 var fs embed.FS
 
-var schemaCache = map[string]*gojsonschema.Schema{}
+var (
+	schemaCacheMu sync.RWMutex
+	schemaCache   = map[string]*gojsonschema.Schema{}
+)
 
 /*
 Returns schema from memory cache. If not already in memory, loads it from the file system.
 Returns error if either schema-file is not present or has invalid jsonschema format
 */
 func GetSchema(schemaName string) (*gojsonschema.Schema, error) {
+	schemaCacheMu.RLock()
+	schema, found := schemaCache[schemaName]
+	schemaCacheMu.RUnlock()
+	if found {
+		return schema, nil
+	}
+	schemaCacheMu.Lock()
+	defer schemaCacheMu.Unlock()
 	if _, found := schemaCache[schemaName]; !found {
 		schema, err := loadJsonSchema(schemaName)
 		if err != nil {
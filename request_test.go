@@ -0,0 +1,200 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/suprsend/suprsend-go/signature"
+)
+
+func TestClientDo(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch r.URL.Path {
+		case "/v1/things/":
+			writeJson(w, 200, map[string]any{"method": r.Method, "query": r.URL.RawQuery, "body": string(body)})
+		default:
+			writeJson(w, 404, map[string]any{"code": 404, "message": "not found"})
+		}
+	})
+	c := newTestClient(t, ts.URL)
+	type echo struct {
+		Method string `json:"method"`
+		Query  string `json:"query"`
+		Body   string `json:"body"`
+	}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   echo
+	}{
+		{"get with query", "get", "/v1/things/?limit=2", nil, echo{"GET", "limit=2", ""}},
+		{"post map", "POST", "v1/things/", map[string]any{"a": 1}, echo{"POST", "", `{"a":1}`}},
+		{"post raw bytes", "POST", "v1/things/", []byte(`{"raw": true}`), echo{"POST", "", `{"raw": true}`}},
+		{"patch raw message", "PATCH", "v1/things/", json.RawMessage(`[1,2]`), echo{"PATCH", "", `[1,2]`}},
+		{"absolute url", "DELETE", ts.URL + "/v1/things/", nil, echo{"DELETE", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got echo
+			if err := c.Do(context.Background(), tt.method, tt.path, tt.body, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	err := c.Do(context.Background(), "GET", "v1/unknown/", nil, nil)
+	var serr *Error
+	if !errors.As(err, &serr) || serr.Code != 404 {
+		t.Errorf("error = %v, want *Error with code 404", err)
+	}
+}
+
+func TestClientDoRetries(t *testing.T) {
+	var calls atomic.Int32
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		n := calls.Add(1)
+		switch {
+		case r.URL.Path == "/bad/":
+			writeJson(w, 400, map[string]any{"message": "bad"})
+		case n < 3:
+			w.Header().Set("Retry-After", "0")
+			writeJson(w, 503, map[string]any{"message": "unavailable"})
+		default:
+			writeJson(w, 200, map[string]any{"ok": true})
+		}
+	})
+	var attempts atomic.Int32
+	countAttempts := func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			attempts.Add(1)
+			return next(req)
+		}
+	}
+	c := newTestClient(t, ts.URL, WithMiddleware(countAttempts),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	var out map[string]any
+	if err := c.Do(context.Background(), "POST", "ok/", map[string]any{"k": "v"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["ok"] != true || attempts.Load() != 3 {
+		t.Errorf("out = %v after %d attempts, want success after 3", out, attempts.Load())
+	}
+	// every attempt sends same body, signed again
+	reqs := ts.requestsTo("/ok/")
+	if string(reqs[0].Body) != string(reqs[2].Body) || reqs[0].Header.Get("Authorization") == "" {
+		t.Errorf("retried request differs: %s vs %s", reqs[0].Body, reqs[2].Body)
+	}
+	// 4xx is not retried
+	attempts.Store(0)
+	if err := c.Do(context.Background(), "POST", "bad/", nil, nil); err == nil || attempts.Load() != 1 {
+		t.Errorf("400: err = %v, attempts = %d", err, attempts.Load())
+	}
+}
+
+func TestCallTimeoutReplacesClientTimeout(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		time.Sleep(200 * time.Millisecond)
+		writeJson(w, 200, map[string]any{})
+	})
+	c := newTestClient(t, ts.URL)
+	c.httpClient.Timeout = 50 * time.Millisecond
+	tests := []struct {
+		name    string
+		opts    []RequestOption
+		wantErr bool
+	}{
+		{"client timeout", nil, true},
+		{"longer call timeout", []RequestOption{WithCallTimeout(2 * time.Second)}, false},
+		{"shorter call timeout", []RequestOption{WithCallTimeout(20 * time.Millisecond)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Do(context.Background(), "GET", "slow/", nil, nil, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if c.httpClient.Timeout != 50*time.Millisecond {
+		t.Error("call timeout must not change client's timeout")
+	}
+}
+
+func TestRequestHeaderOptions(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 200, map[string]any{})
+	})
+	c := newTestClient(t, ts.URL)
+	err := c.Do(context.Background(), "POST", "v1/things/", map[string]any{"a": 1}, nil,
+		WithHeader("authorization", "forged"), WithHeader("DATE", "forged"),
+		WithHeader("x-request-id", "r1"), WithHeader("content-type", "application/json"),
+		WithTenantId(" t1 "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := ts.Requests()[0]
+	if got := r.Header.Values("Authorization"); len(got) != 1 || got[0] == "forged" {
+		t.Errorf("Authorization = %v", got)
+	}
+	if got := r.Header.Values("Date"); len(got) != 1 || got[0] == "forged" {
+		t.Errorf("Date = %v", got)
+	}
+	if got := r.Header.Values("Content-Type"); len(got) != 1 || got[0] != "application/json" {
+		t.Errorf("Content-Type = %v", got)
+	}
+	if r.Header.Get("X-Request-Id") != "r1" || r.Query != "tenant_id=t1" {
+		t.Errorf("X-Request-Id = %s, query = %s", r.Header.Get("X-Request-Id"), r.Query)
+	}
+	// signature covers overridden content-type
+	headers := map[string]string{"Content-Type": r.Header.Get("Content-Type"), "Date": r.Header.Get("Date")}
+	sig, _ := signature.GetRequestSignatureForBody(ts.URL+r.Path+"?"+r.Query, r.Method, r.Body, headers, testWorkspaceSecret)
+	if r.Header.Get("Authorization") != testWorkspaceKey+":"+sig {
+		t.Error("request signature doesn't verify")
+	}
+	// tenant_id already present in url is kept
+	c.Do(context.Background(), "GET", "v1/things/?tenant_id=t0", nil, nil, WithTenantId("t1"))
+	if q := ts.Requests()[1].Query; q != "tenant_id=t0" {
+		t.Errorf("query = %s, want tenant_id=t0", q)
+	}
+}
+
+func TestWithContextCancelsCalls(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.TrackEvent(testEvent("u1", nil), WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("event err = %v, want context.Canceled", err)
+	}
+	if _, err := c.Workflows.Trigger(testWorkflow("wf", "u1"), WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("workflow err = %v, want context.Canceled", err)
+	}
+	for name, bulkIns := range map[string]interface {
+		Trigger(...RequestOption) (*BulkResponse, error)
+	}{
+		"events":    appendTo(c.BulkEvents.NewInstance(), testEvent("u1", nil), testEvent("u2", nil)),
+		"workflows": appendTo(c.Workflows.BulkTriggerInstance(), testWorkflow("wf", "u1"), testWorkflow("wf", "u2")),
+	} {
+		resp, err := bulkIns.Trigger(WithContext(ctx))
+		if err != nil || resp.Total != 2 || resp.Failure != 2 {
+			t.Errorf("bulk %s: response = %v, err = %v, want all records failed", name, resp, err)
+		}
+	}
+	if n := len(ts.Requests()); n != 0 {
+		t.Errorf("requests = %d, want none once ctx is done", n)
+	}
+}
+
+func appendTo[T any, B interface{ Append(...T) }](b B, records ...T) B {
+	b.Append(records...)
+	return b
+}
@@ -2,6 +2,7 @@ package suprsend
 
 import (
 	"fmt"
+	"slices"
 )
 
 type Response struct {
@@ -23,6 +24,32 @@ type BulkResponse struct {
 	Success       int
 	Failure       int
 	Warnings      []string
+	// result of every record, ordered by the index at which it was appended to bulk instance
+	Results []BulkRecordResult
+}
+
+const (
+	// ErrorType of a record which got rejected by sdk (before making api call)
+	BULK_RECORD_ERROR_TYPE_VALIDATION = "validation_error"
+	// ErrorType of a record whose api call could not be completed
+	BULK_RECORD_ERROR_TYPE_NETWORK = "network_error"
+)
+
+type BulkRecordResult struct {
+	// position of the record in the order it was appended to bulk instance (starting at 0)
+	Index int
+	// possible status: success/error
+	Status     string
+	StatusCode int
+	MessageId  string
+	Error      string
+	// error type returned by SuprSend, or one of BULK_RECORD_ERROR_TYPE_VALIDATION/BULK_RECORD_ERROR_TYPE_NETWORK
+	ErrorType string
+	// final json of the record
+	Record map[string]any
+	// true if record was not sent as it's a duplicate (as per client's DedupCache) of an already sent record.
+	// Result of the original record is reported against it.
+	Duplicate bool
 }
 
 func (b *BulkResponse) String() string {
@@ -54,6 +81,14 @@ func (b *BulkResponse) mergeChunkResponse(chResponse *chunkResponse) {
 	b.Success += chResponse.success
 	b.Failure += chResponse.failure
 	b.FailedRecords = append(b.FailedRecords, chResponse.failedRecords...)
+	b.Results = append(b.Results, chResponse.results...)
+}
+
+// orders Results by record index. Called once all chunk responses have been merged
+func (b *BulkResponse) sortResults() {
+	slices.SortStableFunc(b.Results, func(x, y BulkRecordResult) int {
+		return x.Index - y.Index
+	})
 }
 
 type chunkResponse struct {
@@ -64,6 +99,9 @@ type chunkResponse struct {
 	failure       int
 	failedRecords []map[string]any
 	rawResponse   map[string]any
+	results       []BulkRecordResult
+	// true if api responded with status of each record (instead of one error for whole chunk)
+	hasRecordResults bool
 }
 
 func emptyChunkSuccessResponse() *chunkResponse {
@@ -78,7 +116,16 @@ func emptyChunkSuccessResponse() *chunkResponse {
 	}
 }
 
-func invalidRecordsChunkResponse(invalidRecords []map[string]any) *chunkResponse {
+func invalidRecordsChunkResponse(invalidRecords []map[string]any, indexes []int) *chunkResponse {
+	results := []BulkRecordResult{}
+	for i, invRec := range invalidRecords {
+		record, _ := invRec["record"].(map[string]any)
+		errMsg, _ := invRec["error"].(string)
+		results = append(results, BulkRecordResult{
+			Index: indexes[i], Status: "error", StatusCode: 500,
+			Error: errMsg, ErrorType: BULK_RECORD_ERROR_TYPE_VALIDATION, Record: record,
+		})
+	}
 	return &chunkResponse{
 		status:        "fail",
 		statusCode:    500,
@@ -87,9 +134,24 @@ func invalidRecordsChunkResponse(invalidRecords []map[string]any) *chunkResponse
 		failure:       len(invalidRecords),
 		failedRecords: invalidRecords,
 		rawResponse:   nil,
+		results:       results,
 	}
 }
 
+// same result for every record of the chunk. Used when whole chunk succeeded/failed
+func chunkRecordResults(_chunk []map[string]any, indexes []int, statusCode int, errMsg string, errType string,
+) []BulkRecordResult {
+	results := []BulkRecordResult{}
+	for i, c := range _chunk {
+		r := BulkRecordResult{Index: indexes[i], Status: "success", StatusCode: statusCode, Record: c}
+		if statusCode >= 400 {
+			r.Status, r.Error, r.ErrorType = "error", errMsg, errType
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
 type v2EventSingleResponse struct {
 	Status    string `json:"status" mapstructure:"status"`
 	MessageId string `json:"message_id" mapstructure:"message_id"`
@@ -133,3 +195,91 @@ func (b *v2EventBulkResponse) setDerivedFields() {
 		b.dStatus = "success"
 	}
 }
+
+/*
+Returns results of records which failed with a retryable error (5xx, 429 or network error).
+Records rejected by sdk validation are not retryable.
+*/
+func (b *BulkResponse) RetryableRecords() []BulkRecordResult {
+	retryable := []BulkRecordResult{}
+	for _, r := range b.Results {
+		if r.isRetryable() {
+			retryable = append(retryable, r)
+		}
+	}
+	return retryable
+}
+
+func (r *BulkRecordResult) isRetryable() bool {
+	if r.Status == "success" || r.ErrorType == BULK_RECORD_ERROR_TYPE_VALIDATION {
+		return false
+	}
+	return r.ErrorType == BULK_RECORD_ERROR_TYPE_NETWORK || r.StatusCode == 429 || r.StatusCode >= 500
+}
+
+// indexes of retryable records of a response
+func retryableIndexes(b *BulkResponse) map[int]bool {
+	indexes := map[int]bool{}
+	if b == nil {
+		return indexes
+	}
+	for _, r := range b.RetryableRecords() {
+		indexes[r.Index] = true
+	}
+	return indexes
+}
+
+// chunk response built from already known results of records
+func chunkResponseFromResults(results []BulkRecordResult) *chunkResponse {
+	chResponse := &chunkResponse{
+		statusCode:    200,
+		failedRecords: []map[string]any{},
+		results:       results,
+	}
+	for _, r := range results {
+		chResponse.total++
+		if r.Status == "success" {
+			chResponse.success++
+		} else {
+			chResponse.failure++
+			chResponse.failedRecords = append(chResponse.failedRecords, map[string]any{
+				"record": r.Record,
+				"error":  r.Error,
+				"code":   r.StatusCode,
+			})
+		}
+	}
+	if chResponse.failure == 0 {
+		chResponse.status = "success"
+	} else if chResponse.success == 0 {
+		chResponse.status = "fail"
+	} else {
+		chResponse.status = "partial"
+	}
+	return chResponse
+}
+
+/*
+Builds a fresh response after retry: results of records which were not retried are carried over
+from previous response, rest are replaced by results of retry.
+*/
+func mergeRetryResponse(prev *BulkResponse, retried []BulkRecordResult) *BulkResponse {
+	retriedIdx := map[int]bool{}
+	for _, r := range retried {
+		retriedIdx[r.Index] = true
+	}
+	results := []BulkRecordResult{}
+	for _, r := range prev.Results {
+		if !retriedIdx[r.Index] {
+			results = append(results, r)
+		}
+	}
+	results = append(results, retried...)
+	//
+	slices.SortStableFunc(results, func(x, y BulkRecordResult) int {
+		return x.Index - y.Index
+	})
+	fresh := &BulkResponse{Warnings: prev.Warnings}
+	fresh.mergeChunkResponse(chunkResponseFromResults(results))
+	return fresh
+}
@@ -17,24 +17,37 @@ func GetRequestSignature(urlStr string, httpVerb string, content any,
 ) ([]byte, string, error) {
 	//
 	var contentBody []byte
-	var contentMd5 string
 	// possible methods: POST/GET/PUT
 	if httpVerb == "GET" || SafeCheckNil(content) {
-		contentBody, contentMd5 = []byte(""), ""
+		contentBody = []byte("")
 	} else {
 		cBytes, err := json.Marshal(content)
 		if err != nil {
-			return contentBody, contentMd5, fmt.Errorf("failed to marshal content: %w", err)
+			return contentBody, "", fmt.Errorf("failed to marshal content: %w", err)
 		}
 		contentBody = cBytes
+	}
+	sig, err := GetRequestSignatureForBody(urlStr, httpVerb, contentBody, headers, secret)
+	return contentBody, sig, err
+}
+
+/*
+Same as GetRequestSignature, but for an already encoded body, which is sent as-is.
+Use it to avoid encoding the body again for signing.
+*/
+func GetRequestSignatureForBody(urlStr string, httpVerb string, contentBody []byte,
+	headers map[string]string, secret string,
+) (string, error) {
+	var contentMd5 string
+	if httpVerb != "GET" && len(contentBody) > 0 {
 		// MD5 of the content
-		md5Hash := md5.Sum(cBytes)
+		md5Hash := md5.Sum(contentBody)
 		contentMd5 = hex.EncodeToString(md5Hash[:])
 	}
 	// Proper Url encoding
 	requestUrlPath, err := getUrlPath(urlStr)
 	if err != nil {
-		return contentBody, contentMd5, err
+		return "", err
 	}
 	// Create string to sign
 	stringToSign := fmt.Sprintf(
@@ -52,7 +65,7 @@ func GetRequestSignature(urlStr string, httpVerb string, content any,
 	// signature
 	sig := base64.StdEncoding.EncodeToString(hash.Sum(nil))
 	//
-	return contentBody, sig, nil
+	return sig, nil
 }
 
 func getUrlPath(urlStr string) (string, error) {
@@ -1,8 +1,8 @@
 package suprsend
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
@@ -157,7 +157,7 @@ func (s *subscriber) Save() (*Response, error) {
 		return nil, err
 	}
 	//
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +170,7 @@ func (s *subscriber) Save() (*Response, error) {
 }
 
 func (s *subscriber) formatAPIResponse(httpRes *http.Response) (*Response, error) {
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
 		return nil, &Error{Err: err}
 	}
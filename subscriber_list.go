@@ -2,6 +2,7 @@ package suprsend
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -46,6 +47,8 @@ type SubscriberListAll struct {
 type SubscriberListAllOptions struct {
 	Limit  int
 	Offset int
+	// used only by All iterator: fetch next page concurrently while current page is being iterated
+	Prefetch bool
 }
 
 func (b *SubscriberListAllOptions) cleanParams() {
@@ -83,23 +86,43 @@ type SubscriberListBroadcast struct {
 }
 
 func (s *SubscriberListBroadcast) AddAttachment(filePath string, ao *AttachmentOption) error {
-	if d, found := s.Body["data"]; !found || d == nil {
-		s.Body["data"] = map[string]any{}
-	}
 	attachment, err := GetAttachmentJson(filePath, ao)
 	if err != nil {
 		return err
 	}
-	if attachment == nil {
-		return nil
+	return s.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentReader adds attachment with content read from r. contentType is detected if empty.
+func (s *SubscriberListBroadcast) AddAttachmentReader(fileName string, contentType string, r io.Reader, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromReader(fileName, contentType, r, ao)
+	if err != nil {
+		return err
+	}
+	return s.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentBytes adds attachment with in-memory content. contentType is detected if empty.
+func (s *SubscriberListBroadcast) AddAttachmentBytes(fileName string, contentType string, content []byte, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromBytes(fileName, contentType, content, ao)
+	if err != nil {
+		return err
+	}
+	return s.addAttachmentJson(attachment, ao)
+}
+
+// adds attachment to data->$attachments. nil attachment (ignored due to error) is skipped
+func (s *SubscriberListBroadcast) addAttachmentJson(attachment map[string]any, ao *AttachmentOption) error {
+	if d, found := s.Body["data"]; !found || d == nil {
+		s.Body["data"] = map[string]any{}
 	}
 	data := s.Body["data"].(map[string]any)
-	if a, found := data["$attachments"]; !found || a == nil {
-		data["$attachments"] = []map[string]any{}
+	allAttachments, _ := data["$attachments"].([]map[string]any)
+	attachment, err := applyAttachmentOptionPolicy(attachment, allAttachments, ao)
+	if err != nil || attachment == nil {
+		return err
 	}
-	allAttachments := data["$attachments"].([]map[string]any)
-	allAttachments = append(allAttachments, attachment)
-	data["$attachments"] = allAttachments
+	data["$attachments"] = append(allAttachments, attachment)
 	return nil
 }
 
@@ -115,7 +138,12 @@ func (s *SubscriberListBroadcast) getFinalJson(client *Client) (map[string]any,
 	if s.BrandId != "" {
 		s.Body["brand_id"] = s.BrandId
 	}
-	body, err := validateListBroadcastBodySchema(s.Body)
+	body, err := validateListBroadcastBodySchema(s.Body, client.validationMode)
+	if err != nil {
+		return nil, 0, err
+	}
+	// check attachments against client's attachment policy
+	body, err = client.applyAttachmentPolicy(body, "data")
 	if err != nil {
 		return nil, 0, err
 	}
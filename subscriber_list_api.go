@@ -3,7 +3,7 @@ package suprsend
 import (
 	"context"
 	"fmt"
-	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -11,19 +11,20 @@ import (
 )
 
 type SubscriberListsService interface {
-	GetAll(context.Context, *SubscriberListAllOptions) (*SubscriberListAll, error)
-	Create(context.Context, *SubscriberListCreateInput) (*SubscriberList, error)
-	Get(context.Context, string) (*SubscriberList, error)
-	Add(context.Context, string, []string) (map[string]any, error)
-	Remove(context.Context, string, []string) (map[string]any, error)
-	Delete(context.Context, string) error
-	Broadcast(context.Context, *SubscriberListBroadcast) (*Response, error)
-	StartSync(context.Context, string) (*SubscriberList, error)
-	GetVersion(context.Context, string, string) (*SubscriberList, error)
-	AddToVersion(context.Context, string, string, []string) (map[string]any, error)
-	RemoveFromVersion(context.Context, string, string, []string) (map[string]any, error)
-	FinishSync(context.Context, string, string) (*SubscriberList, error)
-	DeleteVersion(context.Context, string, string) error
+	GetAll(context.Context, *SubscriberListAllOptions, ...RequestOption) (*SubscriberListAll, error)
+	All(context.Context, *SubscriberListAllOptions, ...RequestOption) iter.Seq2[*SubscriberList, error]
+	Create(context.Context, *SubscriberListCreateInput, ...RequestOption) (*SubscriberList, error)
+	Get(context.Context, string, ...RequestOption) (*SubscriberList, error)
+	Add(context.Context, string, []string, ...RequestOption) (map[string]any, error)
+	Remove(context.Context, string, []string, ...RequestOption) (map[string]any, error)
+	Delete(context.Context, string, ...RequestOption) error
+	Broadcast(context.Context, *SubscriberListBroadcast, ...RequestOption) (*Response, error)
+	StartSync(context.Context, string, ...RequestOption) (*SubscriberList, error)
+	GetVersion(context.Context, string, string, ...RequestOption) (*SubscriberList, error)
+	AddToVersion(context.Context, string, string, []string, ...RequestOption) (map[string]any, error)
+	RemoveFromVersion(context.Context, string, string, []string, ...RequestOption) (map[string]any, error)
+	FinishSync(context.Context, string, string, ...RequestOption) (*SubscriberList, error)
+	DeleteVersion(context.Context, string, string, ...RequestOption) error
 }
 
 type subscriberListsService struct {
@@ -58,14 +59,14 @@ func (s *subscriberListsService) prepareQueryParams(opt *SubscriberListAllOption
 	return params.Encode()
 }
 
-func (s *subscriberListsService) GetAll(ctx context.Context, opts *SubscriberListAllOptions) (*SubscriberListAll, error) {
+func (s *subscriberListsService) GetAll(ctx context.Context, opts *SubscriberListAllOptions, reqOpts ...RequestOption) (*SubscriberListAll, error) {
 	urlStr := fmt.Sprintf("%s?%s", s._subscriberListUrl, s.prepareQueryParams(opts))
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := s.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +80,24 @@ func (s *subscriberListsService) GetAll(ctx context.Context, opts *SubscriberLis
 	return resp, nil
 }
 
+// All returns iterator over all lists, starting at opts.Offset. Pages of opts.Limit lists are fetched as needed
+func (s *subscriberListsService) All(ctx context.Context, opts *SubscriberListAllOptions, reqOpts ...RequestOption) iter.Seq2[*SubscriberList, error] {
+	pageOpts := SubscriberListAllOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.cleanParams()
+	fetch := offsetPageFetcher(pageOpts.Limit, pageOpts.Offset,
+		func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []*SubscriberList, error) {
+			resp, err := s.GetAll(ctx, &SubscriberListAllOptions{Limit: limit, Offset: offset}, reqOpts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp.Meta, resp.Results, nil
+		})
+	return paginate(ctx, fetch, pageOpts.Prefetch)
+}
+
 func (s *subscriberListsService) validateListId(listId string) (string, error) {
 	listId = strings.TrimSpace(listId)
 	if listId == "" {
@@ -87,7 +106,7 @@ func (s *subscriberListsService) validateListId(listId string) (string, error) {
 	return listId, nil
 }
 
-func (s *subscriberListsService) Create(ctx context.Context, createParams *SubscriberListCreateInput) (*SubscriberList, error) {
+func (s *subscriberListsService) Create(ctx context.Context, createParams *SubscriberListCreateInput, reqOpts ...RequestOption) (*SubscriberList, error) {
 	var err error
 	if createParams == nil {
 		return nil, &Error{Message: "missing payload"}
@@ -98,11 +117,11 @@ func (s *subscriberListsService) Create(ctx context.Context, createParams *Subsc
 	}
 	urlStr := s._subscriberListUrl
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, createParams)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, createParams, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -121,18 +140,18 @@ func (b *subscriberListsService) listDetailAPIUrl(listId string) string {
 	return fmt.Sprintf("%s%s/", b._subscriberListUrl, listId)
 }
 
-func (s *subscriberListsService) Get(ctx context.Context, listId string) (*SubscriberList, error) {
+func (s *subscriberListsService) Get(ctx context.Context, listId string, reqOpts ...RequestOption) (*SubscriberList, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
 	}
 	urlStr := s.listDetailAPIUrl(listId)
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := s.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +165,7 @@ func (s *subscriberListsService) Get(ctx context.Context, listId string) (*Subsc
 	return resp, nil
 }
 
-func (s *subscriberListsService) Add(ctx context.Context, listId string, distinctIds []string) (map[string]any, error) {
+func (s *subscriberListsService) Add(ctx context.Context, listId string, distinctIds []string, reqOpts ...RequestOption) (map[string]any, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -154,11 +173,11 @@ func (s *subscriberListsService) Add(ctx context.Context, listId string, distinc
 	urlStr := fmt.Sprintf("%ssubscriber/add/", s.listDetailAPIUrl(listId))
 	payload := map[string]any{"distinct_ids": distinctIds}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +191,7 @@ func (s *subscriberListsService) Add(ctx context.Context, listId string, distinc
 	return resp, nil
 }
 
-func (s *subscriberListsService) Remove(ctx context.Context, listId string, distinctIds []string) (map[string]any, error) {
+func (s *subscriberListsService) Remove(ctx context.Context, listId string, distinctIds []string, reqOpts ...RequestOption) (map[string]any, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -180,11 +199,11 @@ func (s *subscriberListsService) Remove(ctx context.Context, listId string, dist
 	urlStr := fmt.Sprintf("%ssubscriber/remove/", s.listDetailAPIUrl(listId))
 	payload := map[string]any{"distinct_ids": distinctIds}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +217,7 @@ func (s *subscriberListsService) Remove(ctx context.Context, listId string, dist
 	return resp, nil
 }
 
-func (s *subscriberListsService) Delete(ctx context.Context, listId string) error {
+func (s *subscriberListsService) Delete(ctx context.Context, listId string, reqOpts ...RequestOption) error {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return err
@@ -206,11 +225,11 @@ func (s *subscriberListsService) Delete(ctx context.Context, listId string) erro
 	urlStr := fmt.Sprintf("%sdelete/", s.listDetailAPIUrl(listId))
 	payload := map[string]any{}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -223,7 +242,7 @@ func (s *subscriberListsService) Delete(ctx context.Context, listId string) erro
 	return nil
 }
 
-func (s *subscriberListsService) Broadcast(ctx context.Context, broadcastIns *SubscriberListBroadcast) (*Response, error) {
+func (s *subscriberListsService) Broadcast(ctx context.Context, broadcastIns *SubscriberListBroadcast, reqOpts ...RequestOption) (*Response, error) {
 	if broadcastIns == nil {
 		return nil, &Error{Message: "missing payload"}
 	}
@@ -231,12 +250,16 @@ func (s *subscriberListsService) Broadcast(ctx context.Context, broadcastIns *Su
 	if err != nil {
 		return nil, err
 	}
+	broadcastBody, _, err = s.client.uploadAttachmentsInBody(ctx, broadcastBody, "data", nil)
+	if err != nil {
+		return nil, err
+	}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", s._broadcastUrl, broadcastBody)
+	request, err := s.client.prepareHttpRequest("POST", s._broadcastUrl, broadcastBody, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +271,7 @@ func (s *subscriberListsService) Broadcast(ctx context.Context, broadcastIns *Su
 	return suprResponse, nil
 }
 
-func (s *subscriberListsService) StartSync(ctx context.Context, listId string) (*SubscriberList, error) {
+func (s *subscriberListsService) StartSync(ctx context.Context, listId string, reqOpts ...RequestOption) (*SubscriberList, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -256,11 +279,11 @@ func (s *subscriberListsService) StartSync(ctx context.Context, listId string) (
 	urlStr := fmt.Sprintf("%sstart_sync/", s.listDetailAPIUrl(listId))
 	payload := map[string]any{}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -288,7 +311,7 @@ func (b *subscriberListsService) listAPIUrlWithVersion(listId, versionId string)
 	return fmt.Sprintf("%s%s/version/%s/", b._subscriberListUrl, listId, versionId)
 }
 
-func (s *subscriberListsService) GetVersion(ctx context.Context, listId, versionId string) (*SubscriberList, error) {
+func (s *subscriberListsService) GetVersion(ctx context.Context, listId, versionId string, reqOpts ...RequestOption) (*SubscriberList, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -299,11 +322,11 @@ func (s *subscriberListsService) GetVersion(ctx context.Context, listId, version
 	}
 	urlStr := s.listAPIUrlWithVersion(listId, versionId)
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := s.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -317,7 +340,7 @@ func (s *subscriberListsService) GetVersion(ctx context.Context, listId, version
 	return resp, nil
 }
 
-func (s *subscriberListsService) AddToVersion(ctx context.Context, listId string, versionId string, distinctIds []string) (map[string]any, error) {
+func (s *subscriberListsService) AddToVersion(ctx context.Context, listId string, versionId string, distinctIds []string, reqOpts ...RequestOption) (map[string]any, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -329,11 +352,11 @@ func (s *subscriberListsService) AddToVersion(ctx context.Context, listId string
 	urlStr := fmt.Sprintf("%ssubscriber/add/", s.listAPIUrlWithVersion(listId, versionId))
 	payload := map[string]any{"distinct_ids": distinctIds}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -347,7 +370,7 @@ func (s *subscriberListsService) AddToVersion(ctx context.Context, listId string
 	return resp, nil
 }
 
-func (s *subscriberListsService) RemoveFromVersion(ctx context.Context, listId string, versionId string, distinctIds []string) (map[string]any, error) {
+func (s *subscriberListsService) RemoveFromVersion(ctx context.Context, listId string, versionId string, distinctIds []string, reqOpts ...RequestOption) (map[string]any, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -359,11 +382,11 @@ func (s *subscriberListsService) RemoveFromVersion(ctx context.Context, listId s
 	urlStr := fmt.Sprintf("%ssubscriber/remove/", s.listAPIUrlWithVersion(listId, versionId))
 	payload := map[string]any{"distinct_ids": distinctIds}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -377,7 +400,7 @@ func (s *subscriberListsService) RemoveFromVersion(ctx context.Context, listId s
 	return resp, nil
 }
 
-func (s *subscriberListsService) FinishSync(ctx context.Context, listId string, versionId string) (*SubscriberList, error) {
+func (s *subscriberListsService) FinishSync(ctx context.Context, listId string, versionId string, reqOpts ...RequestOption) (*SubscriberList, error) {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return nil, err
@@ -389,11 +412,11 @@ func (s *subscriberListsService) FinishSync(ctx context.Context, listId string,
 	urlStr := fmt.Sprintf("%sfinish_sync/", s.listAPIUrlWithVersion(listId, versionId))
 	payload := map[string]any{}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -407,7 +430,7 @@ func (s *subscriberListsService) FinishSync(ctx context.Context, listId string,
 	return resp, nil
 }
 
-func (s *subscriberListsService) DeleteVersion(ctx context.Context, listId string, versionId string) error {
+func (s *subscriberListsService) DeleteVersion(ctx context.Context, listId string, versionId string, reqOpts ...RequestOption) error {
 	listId, err := s.validateListId(listId)
 	if err != nil {
 		return err
@@ -419,11 +442,11 @@ func (s *subscriberListsService) DeleteVersion(ctx context.Context, listId strin
 	urlStr := fmt.Sprintf("%sdelete/", s.listAPIUrlWithVersion(listId, versionId))
 	payload := map[string]any{}
 	// prepare http.Request object
-	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload)
+	request, err := s.client.prepareHttpRequest("PATCH", urlStr, payload, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := s.client.httpClient.Do(request)
+	httpResponse, err := s.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -437,7 +460,7 @@ func (s *subscriberListsService) DeleteVersion(ctx context.Context, listId strin
 }
 
 func (s *subscriberListsService) formatAPIResponse(httpRes *http.Response) (*Response, error) {
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
 		return nil, &Error{Err: err}
 	}
@@ -2,9 +2,6 @@ package suprsend
 
 import (
 	"fmt"
-	"io"
-	"log"
-	"net/http"
 
 	"github.com/jinzhu/copier"
 )
@@ -15,77 +12,44 @@ type bulkSubscribersService struct {
 
 func (b *bulkSubscribersService) NewInstance() BulkSubscribers {
 	return &bulkSubscribers{
-		client:   b.client,
-		response: &BulkResponse{},
+		engine: newBulkEngine(b.client, subscribersBulkKind),
 	}
 }
 
 type BulkSubscribers interface {
 	Append(subscribers ...Subscriber)
-	Save() (*BulkResponse, error)
+	Save(...RequestOption) (*BulkResponse, error)
+	// Plan returns chunks in which appended records would be sent, without sending them
+	Plan() *BulkPlan
 }
 
 var _ BulkSubscribers = &bulkSubscribers{}
 
-type bulkSubscribers struct {
-	client *Client
-	//
-	_subscribers    []subscriber
-	_pendingRecords []pendingIdentityEventRecord
-	chunks          []*bulkSubscribersChunk
-	//
-	response *BulkResponse
-	// invalid_record json: {"record": event-json, "error": error_str, "code": 500}
-	_invalidRecords []map[string]any
-}
-
-type pendingIdentityEventRecord struct {
-	record     map[string]any
-	recordSize int
-}
-
-func (b *bulkSubscribers) _validateSubscriberEvents() {
-	for _, sub := range b._subscribers {
+var subscribersBulkKind = &bulkRecordKind[subscriber]{
+	url: func(c *Client) string {
+		return fmt.Sprintf("%sevent/", c.baseUrl)
+	},
+	maxRecords: func(c *Client) int {
+		return c.bulkLimits.MaxIdentityEventsInBulk
+	},
+	validate: func(c *Client, sub *subscriber) (pendingBulkRecord, []string, error) {
 		// -- check if there is any error/warning, if so add it to warnings list of BulkResponse
 		warningsList, err := sub.validateBody(true)
 		if err != nil {
-			invRec := invalidRecordJson(sub.asJson(), err)
-			b._invalidRecords = append(b._invalidRecords, invRec)
-		} else {
-			if len(warningsList) > 0 {
-				b.response.Warnings = append(b.response.Warnings, warningsList...)
-			}
-			//
-			ev := sub.getEvent()
-			evJson, bodySize, err := sub.validateEventSize(ev)
-			if err != nil {
-				invRec := invalidRecordJson(sub.asJson(), err)
-				b._invalidRecords = append(b._invalidRecords, invRec)
-			} else {
-				b._pendingRecords = append(
-					b._pendingRecords,
-					pendingIdentityEventRecord{
-						record:     evJson,
-						recordSize: bodySize,
-					},
-				)
-			}
+			return pendingBulkRecord{}, nil, err
 		}
-	}
+		ev := sub.getEvent()
+		evJson, bodySize, err := sub.validateEventSize(ev)
+		return pendingBulkRecord{record: evJson, recordSize: bodySize}, warningsList, err
+	},
+	asJson: func(sub *subscriber) map[string]any {
+		return sub.asJson()
+	},
+	parseResponse: parseBulkChunkResponse,
 }
 
-func (b *bulkSubscribers) _chunkify(startIdx int) {
-	currChunk := newBulkSubscribersChunk(b.client)
-	b.chunks = append(b.chunks, currChunk)
-	for relIdx, rec := range b._pendingRecords[startIdx:] {
-		isAdded := currChunk.tryToAddIntoChunk(rec.record, rec.recordSize)
-		if !isAdded {
-			// create chunks from remaining records
-			b._chunkify(startIdx + relIdx)
-			// Don't forget to break. As current loop must not continue further
-			break
-		}
-	}
+type bulkSubscribers struct {
+	engine *bulkEngine[subscriber]
 }
 
 func (b *bulkSubscribers) Append(subscribers ...Subscriber) {
@@ -96,154 +60,19 @@ func (b *bulkSubscribers) Append(subscribers ...Subscriber) {
 		if subStruct, ok := sub.(*subscriber); ok {
 			subCopy := subscriber{}
 			copier.CopyWithOption(&subCopy, subStruct, copier.Option{DeepCopy: true})
-			b._subscribers = append(b._subscribers, subCopy)
+			b.engine.append(subCopy)
 		}
 	}
 }
 
-func (b *bulkSubscribers) Trigger() (*BulkResponse, error) {
-	return b.Save()
+func (b *bulkSubscribers) Trigger(opts ...RequestOption) (*BulkResponse, error) {
+	return b.Save(opts...)
 }
 
-func (b *bulkSubscribers) Save() (*BulkResponse, error) {
-	b._validateSubscriberEvents()
-	if len(b._invalidRecords) > 0 {
-		chResponse := invalidRecordsChunkResponse(b._invalidRecords)
-		b.response.mergeChunkResponse(chResponse)
-	}
-	if len(b._pendingRecords) > 0 {
-		b._chunkify(0)
-		for cIdx, ch := range b.chunks {
-			if b.client.debug {
-				log.Printf("DEBUG: triggering api call for chunk: %d", cIdx)
-			}
-			// do api call
-			ch.trigger()
-			// merge response
-			b.response.mergeChunkResponse(ch.response)
-		}
-	} else {
-		if len(b._invalidRecords) == 0 {
-			b.response.mergeChunkResponse(emptyChunkSuccessResponse())
-		}
-	}
-	return b.response, nil
-}
-
-// ==========================================================
-
-type bulkSubscribersChunk struct {
-	_chunk_apparent_size_in_bytes int
-	_max_records_in_chunk         int
-	//
-	client *Client
-	_url   string
-	//
-	_chunk         []map[string]any
-	_runningSize   int
-	_runningLength int
-	response       *chunkResponse
-}
-
-func newBulkSubscribersChunk(client *Client) *bulkSubscribersChunk {
-	bsc := &bulkSubscribersChunk{
-		_chunk_apparent_size_in_bytes: BODY_MAX_APPARENT_SIZE_IN_BYTES,
-		_max_records_in_chunk:         MAX_IDENTITY_EVENTS_IN_BULK_API,
-		//
-		client: client,
-		_url:   fmt.Sprintf("%sevent/", client.baseUrl),
-		_chunk: []map[string]any{},
-	}
-	return bsc
+func (b *bulkSubscribers) Save(opts ...RequestOption) (*BulkResponse, error) {
+	return b.engine.trigger(opts...)
 }
 
-func (b *bulkSubscribersChunk) _addEventToChunk(event map[string]any, eventSize int) {
-	// First add size, then event to reduce effects of race condition
-	b._runningSize += eventSize
-	b._chunk = append(b._chunk, event)
-	b._runningLength += 1
-}
-
-func (b *bulkSubscribersChunk) _checkLimitReached() bool {
-	return b._runningLength >= b._max_records_in_chunk || b._runningSize >= b._chunk_apparent_size_in_bytes
-}
-
-/*
-returns whether passed event was able to get added to this chunk or not,
-if true, event gets added to chunk
-*/
-func (b *bulkSubscribersChunk) tryToAddIntoChunk(event map[string]any, eventSize int) bool {
-	if event == nil {
-		return true
-	}
-	if b._checkLimitReached() {
-		return false
-	}
-	// if apparent_size of event crosses limit
-	if (b._runningSize + eventSize) > b._chunk_apparent_size_in_bytes {
-		return false
-	}
-	// Add Event to chunk
-	b._addEventToChunk(event, eventSize)
-	return true
-}
-
-func (b *bulkSubscribersChunk) trigger() {
-	// prepare http.Request object
-	request, err := b.client.prepareHttpRequest("POST", b._url, b._chunk)
-	if err != nil {
-		suprResponse := b.formatAPIResponse(nil, err)
-		b.response = suprResponse
-	}
-	//
-	httpResponse, err := b.client.httpClient.Do(request)
-	if err != nil {
-		suprResponse := b.formatAPIResponse(nil, err)
-		b.response = suprResponse
-
-	} else {
-		defer httpResponse.Body.Close()
-		suprResponse := b.formatAPIResponse(httpResponse, nil)
-		b.response = suprResponse
-	}
-}
-
-func (b *bulkSubscribersChunk) formatAPIResponse(httpRes *http.Response, err error) *chunkResponse {
-	//
-	bulkRespFunc := func(statusCode int, errMsg string) *chunkResponse {
-		failedRecords := []map[string]any{}
-		if statusCode >= 400 {
-			for _, c := range b._chunk {
-				failedRecords = append(failedRecords,
-					map[string]any{
-						"record": c,
-						"error":  errMsg,
-						"code":   statusCode,
-					})
-			}
-			return &chunkResponse{
-				status: "fail", statusCode: statusCode,
-				total: len(b._chunk), success: 0, failure: len(b._chunk),
-				failedRecords: failedRecords,
-			}
-		} else {
-			return &chunkResponse{
-				status: "success", statusCode: statusCode,
-				total: len(b._chunk), success: len(b._chunk), failure: 0,
-				failedRecords: failedRecords,
-			}
-		}
-	}
-	if err != nil {
-		return bulkRespFunc(500, err.Error())
-
-	} else if httpRes != nil {
-		respBody, err := io.ReadAll(httpRes.Body)
-		if err != nil {
-			return bulkRespFunc(500, err.Error())
-		}
-		//
-		return bulkRespFunc(httpRes.StatusCode, string(respBody))
-	}
-	return nil
+func (b *bulkSubscribers) Plan() *BulkPlan {
+	return b.engine.plan()
 }
@@ -39,6 +39,8 @@ type TenantList struct {
 type TenantListOptions struct {
 	Limit  int
 	Offset int
+	// used only by All iterator: fetch next page concurrently while current page is being iterated
+	Prefetch bool
 }
 
 func (t *TenantListOptions) cleanParams() {
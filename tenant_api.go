@@ -3,22 +3,24 @@ package suprsend
 import (
 	"context"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 )
 
 type TenantsService interface {
-	Get(context.Context, string) (*Tenant, error)
-	Upsert(context.Context, string, *Tenant) (*Tenant, error)
-	List(context.Context, *TenantListOptions) (*TenantList, error)
-	Delete(context.Context, string) error
-	ListPreferenceCategories(context.Context, string, *TenantCategoriesPreferenceOptions) (*TenantCategoriesPreferenceResponse, error)
-	GetPreferenceCategory(context.Context, string, string, *TenantPreferenceCategoryOptions) (*TenantCategoryPreference, error)
-	UpdatePreferenceCategory(context.Context, string, string, TenantPreferenceCategoryUpdateBody, *TenantPreferenceCategoryOptions) (*TenantCategoryPreference, error)
+	Get(context.Context, string, ...RequestOption) (*Tenant, error)
+	Upsert(context.Context, string, *Tenant, ...RequestOption) (*Tenant, error)
+	List(context.Context, *TenantListOptions, ...RequestOption) (*TenantList, error)
+	All(context.Context, *TenantListOptions, ...RequestOption) iter.Seq2[*Tenant, error]
+	Delete(context.Context, string, ...RequestOption) error
+	ListPreferenceCategories(context.Context, string, *TenantCategoriesPreferenceOptions, ...RequestOption) (*TenantCategoriesPreferenceResponse, error)
+	GetPreferenceCategory(context.Context, string, string, *TenantPreferenceCategoryOptions, ...RequestOption) (*TenantCategoryPreference, error)
+	UpdatePreferenceCategory(context.Context, string, string, TenantPreferenceCategoryUpdateBody, *TenantPreferenceCategoryOptions, ...RequestOption) (*TenantCategoryPreference, error)
 	// Deprecated: Use ListPreferenceCategories instead.
-	GetAllCategoriesPreference(context.Context, string, *TenantCategoriesPreferenceOptions) (*TenantCategoriesPreferenceResponse, error)
+	GetAllCategoriesPreference(context.Context, string, *TenantCategoriesPreferenceOptions, ...RequestOption) (*TenantCategoriesPreferenceResponse, error)
 	// Deprecated: Use UpdatePreferenceCategory instead.
-	UpdateCategoryPreference(context.Context, string, string, TenantCategoryPreferenceUpdateBody) (*TenantCategoryPreference, error)
+	UpdateCategoryPreference(context.Context, string, string, TenantCategoryPreferenceUpdateBody, ...RequestOption) (*TenantCategoryPreference, error)
 }
 
 type tenantsService struct {
@@ -47,14 +49,14 @@ func (t *tenantsService) prepareQueryParams(opt *TenantListOptions) string {
 	return params.Encode()
 }
 
-func (t *tenantsService) List(ctx context.Context, opts *TenantListOptions) (*TenantList, error) {
+func (t *tenantsService) List(ctx context.Context, opts *TenantListOptions, reqOpts ...RequestOption) (*TenantList, error) {
 	urlStr := fmt.Sprintf("%s?%s", t._url, t.prepareQueryParams(opts))
 	// prepare http.Request object
-	request, err := t.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,19 +70,37 @@ func (t *tenantsService) List(ctx context.Context, opts *TenantListOptions) (*Te
 	return resp, nil
 }
 
+// All returns iterator over all tenants, starting at opts.Offset. Pages of opts.Limit tenants are fetched as needed
+func (t *tenantsService) All(ctx context.Context, opts *TenantListOptions, reqOpts ...RequestOption) iter.Seq2[*Tenant, error] {
+	pageOpts := TenantListOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.cleanParams()
+	fetch := offsetPageFetcher(pageOpts.Limit, pageOpts.Offset,
+		func(ctx context.Context, limit, offset int) (*ListApiMetaInfo, []*Tenant, error) {
+			resp, err := t.List(ctx, &TenantListOptions{Limit: limit, Offset: offset}, reqOpts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp.Meta, resp.Results, nil
+		})
+	return paginate(ctx, fetch, pageOpts.Prefetch)
+}
+
 func (t *tenantsService) tenantAPIUrl(tenantId string) string {
 	tenantId = url.PathEscape(tenantId)
 	return fmt.Sprintf("%s%s/", t._url, tenantId)
 }
 
-func (t *tenantsService) Get(ctx context.Context, tenantId string) (*Tenant, error) {
+func (t *tenantsService) Get(ctx context.Context, tenantId string, reqOpts ...RequestOption) (*Tenant, error) {
 	urlStr := t.tenantAPIUrl(tenantId)
 	// prepare http.Request object
-	request, err := t.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -94,14 +114,14 @@ func (t *tenantsService) Get(ctx context.Context, tenantId string) (*Tenant, err
 	return resp, nil
 }
 
-func (t *tenantsService) Upsert(ctx context.Context, tenantId string, payload *Tenant) (*Tenant, error) {
+func (t *tenantsService) Upsert(ctx context.Context, tenantId string, payload *Tenant, reqOpts ...RequestOption) (*Tenant, error) {
 	urlStr := t.tenantAPIUrl(tenantId)
 	// prepare http.Request object
-	request, err := t.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := t.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -115,14 +135,14 @@ func (t *tenantsService) Upsert(ctx context.Context, tenantId string, payload *T
 	return resp, nil
 }
 
-func (t *tenantsService) Delete(ctx context.Context, tenantId string) error {
+func (t *tenantsService) Delete(ctx context.Context, tenantId string, reqOpts ...RequestOption) error {
 	urlStr := t.tenantAPIUrl(tenantId)
 	// prepare http.Request object
-	request, err := t.client.prepareHttpRequest("DELETE", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("DELETE", urlStr, nil, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -189,13 +209,13 @@ func (opts *TenantCategoriesPreferenceOptions) BuildQuery() string {
 	return query.Encode()
 }
 
-func (t *tenantsService) ListPreferenceCategories(ctx context.Context, tenantId string, opts *TenantCategoriesPreferenceOptions) (*TenantCategoriesPreferenceResponse, error) {
+func (t *tenantsService) ListPreferenceCategories(ctx context.Context, tenantId string, opts *TenantCategoriesPreferenceOptions, reqOpts ...RequestOption) (*TenantCategoriesPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/", t.tenantAPIUrl(tenantId)), opts.BuildQuery())
-	request, err := t.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -222,13 +242,13 @@ func (opts *TenantPreferenceCategoryOptions) BuildQuery() string {
 	return query.Encode()
 }
 
-func (t *tenantsService) GetPreferenceCategory(ctx context.Context, tenantId, category string, opts *TenantPreferenceCategoryOptions) (*TenantCategoryPreference, error) {
+func (t *tenantsService) GetPreferenceCategory(ctx context.Context, tenantId, category string, opts *TenantPreferenceCategoryOptions, reqOpts ...RequestOption) (*TenantCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", t.tenantAPIUrl(tenantId), url.PathEscape(category)), opts.BuildQuery())
-	request, err := t.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -251,13 +271,13 @@ type TenantPreferenceCategoryUpdateBody struct {
 	OptInChannels       []string `json:"opt_in_channels"`
 }
 
-func (t *tenantsService) UpdatePreferenceCategory(ctx context.Context, tenantId, category string, body TenantPreferenceCategoryUpdateBody, opts *TenantPreferenceCategoryOptions) (*TenantCategoryPreference, error) {
+func (t *tenantsService) UpdatePreferenceCategory(ctx context.Context, tenantId, category string, body TenantPreferenceCategoryUpdateBody, opts *TenantPreferenceCategoryOptions, reqOpts ...RequestOption) (*TenantCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", t.tenantAPIUrl(tenantId), url.PathEscape(category)), opts.BuildQuery())
-	request, err := t.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := t.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -271,13 +291,13 @@ func (t *tenantsService) UpdatePreferenceCategory(ctx context.Context, tenantId,
 }
 
 // Deprecated: Use ListPreferenceCategories instead.
-func (t *tenantsService) GetAllCategoriesPreference(ctx context.Context, tenantId string, opts *TenantCategoriesPreferenceOptions) (*TenantCategoriesPreferenceResponse, error) {
+func (t *tenantsService) GetAllCategoriesPreference(ctx context.Context, tenantId string, opts *TenantCategoriesPreferenceOptions, reqOpts ...RequestOption) (*TenantCategoriesPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%scategory/", t.tenantAPIUrl(tenantId)), opts.BuildQuery())
-	request, err := t.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := t.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -298,13 +318,13 @@ type TenantCategoryPreferenceUpdateBody struct {
 }
 
 // Deprecated: Use UpdatePreferenceCategory instead.
-func (t *tenantsService) UpdateCategoryPreference(ctx context.Context, tenantId, category string, body TenantCategoryPreferenceUpdateBody) (*TenantCategoryPreference, error) {
+func (t *tenantsService) UpdateCategoryPreference(ctx context.Context, tenantId, category string, body TenantCategoryPreferenceUpdateBody, reqOpts ...RequestOption) (*TenantCategoryPreference, error) {
 	urlStr := fmt.Sprintf("%scategory/%s/", t.tenantAPIUrl(tenantId), url.PathEscape(category))
-	request, err := t.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := t.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := t.client.httpClient.Do(request)
+	httpResponse, err := t.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,174 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// User profile, as returned by Users.GetUser/ListUsers
+type User struct {
+	DistinctId string `json:"distinct_id"`
+	//
+	Email       []UserChannel       `json:"$email,omitempty"`
+	Sms         []UserChannel       `json:"$sms,omitempty"`
+	Whatsapp    []UserChannel       `json:"$whatsapp,omitempty"`
+	AndroidPush []UserPushToken     `json:"$androidpush,omitempty"`
+	IosPush     []UserPushToken     `json:"$iospush,omitempty"`
+	WebPush     []UserObjectChannel `json:"$webpush,omitempty"`
+	Slack       []UserObjectChannel `json:"$slack,omitempty"`
+	MSTeams     []UserObjectChannel `json:"$ms_teams,omitempty"`
+	//
+	PreferredLanguage string `json:"$preferred_language,omitempty"`
+	Timezone          string `json:"$timezone,omitempty"`
+	Locale            string `json:"$locale,omitempty"`
+	// custom properties of user (set via UserEdit.Set etc.)
+	Properties map[string]any `json:"-"`
+	// zero if not present in response
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	// user json as returned by api, incl. keys which don't have a field in User yet
+	Raw map[string]any `json:"-"`
+}
+
+// value of an email/sms/whatsapp channel
+type UserChannel struct {
+	Value       string `json:"value"`
+	Status      string `json:"status,omitempty"`
+	PermaStatus string `json:"perma_status,omitempty"`
+}
+
+// android/ios push token
+type UserPushToken struct {
+	Value       string `json:"value"`
+	Provider    string `json:"provider,omitempty"`
+	Status      string `json:"status,omitempty"`
+	PermaStatus string `json:"perma_status,omitempty"`
+}
+
+// value of a channel which is a json object e.g webpush subscription, slack/ms_teams config
+type UserObjectChannel struct {
+	Value       map[string]any `json:"value"`
+	Provider    string         `json:"provider,omitempty"`
+	Status      string         `json:"status,omitempty"`
+	PermaStatus string         `json:"perma_status,omitempty"`
+}
+
+type UserList struct {
+	Meta    *CursorListApiMetaInfo `json:"meta"`
+	Results []*User                `json:"results"`
+}
+
+// keys of user json which are not custom properties
+var userNonPropertyKeys = []string{"distinct_id", "properties", "created_at", "updated_at"}
+
+func (u *User) UnmarshalJSON(data []byte) error {
+	// alias doesn't have UnmarshalJSON, so typed fields get decoded by default rules
+	type userAlias User
+	if err := json.Unmarshal(data, (*userAlias)(u)); err != nil {
+		return err
+	}
+	u.Raw = map[string]any{}
+	if err := json.Unmarshal(data, &u.Raw); err != nil {
+		return err
+	}
+	u.CreatedAt = parseUserTime(u.Raw["created_at"])
+	u.UpdatedAt = parseUserTime(u.Raw["updated_at"])
+	u.Properties = userProperties(u.Raw)
+	return nil
+}
+
+/*
+MarshalJSON is symmetric with UnmarshalJSON: Raw is written with typed fields, CreatedAt/UpdatedAt and
+Properties (nested in "properties") replacing values they were decoded from. So a user which is decoded,
+modified and encoded again carries the modifications, as well as keys which don't have a field in User.
+*/
+func (u User) MarshalJSON() ([]byte, error) {
+	out := maps.Clone(u.Raw)
+	if out == nil {
+		out = map[string]any{}
+	}
+	// typed fields and custom properties are written from fields only (an emptied field must not come back from Raw)
+	for k := range out {
+		if userFieldKeys[k] || !isReservedUserKey(k) {
+			delete(out, k)
+		}
+	}
+	type userAlias User
+	content, err := json.Marshal(userAlias(u))
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if err = json.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	maps.Copy(out, fields)
+	delete(out, "created_at")
+	delete(out, "updated_at")
+	if !u.CreatedAt.IsZero() {
+		out["created_at"] = u.CreatedAt.Format(time.RFC3339Nano)
+	}
+	if !u.UpdatedAt.IsZero() {
+		out["updated_at"] = u.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	delete(out, "properties")
+	if len(u.Properties) > 0 {
+		out["properties"] = u.Properties
+	}
+	return json.Marshal(out)
+}
+
+// json keys of typed fields of User
+var userFieldKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	for _, f := range reflect.VisibleFields(reflect.TypeOf(User{})) {
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+/*
+custom properties are returned either nested in "properties", or as top-level keys alongside
+distinct_id and channels. Keys starting with $ or ss_ are reserved by SuprSend and aren't custom properties.
+*/
+func userProperties(raw map[string]any) map[string]any {
+	props := map[string]any{}
+	if nested, ok := raw["properties"].(map[string]any); ok {
+		for k, v := range nested {
+			props[k] = v
+		}
+	}
+	for k, v := range raw {
+		if isReservedUserKey(k) {
+			continue
+		}
+		props[k] = v
+	}
+	return props
+}
+
+func isReservedUserKey(k string) bool {
+	for _, nk := range userNonPropertyKeys {
+		if k == nk {
+			return true
+		}
+	}
+	return strings.HasPrefix(k, "$") || strings.HasPrefix(strings.ToLower(k), "ss_")
+}
+
+func parseUserTime(v any) time.Time {
+	s, _ := v.(string)
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -49,6 +49,9 @@ type UserEdit interface {
 	//
 	AddMSTeams(value map[string]any)
 	RemoveMSTeams(value map[string]any)
+	//
+	// apparent size (in bytes) of user-edit payload, must not cross IDENTITY_SINGLE_EVENT_MAX_APPARENT_SIZE_IN_BYTES
+	EstimateSize() (int, error)
 }
 
 var _ UserEdit = &userEdit{}
@@ -113,6 +116,10 @@ func (u *userEdit) validatePayloadSize(payload map[string]any) (map[string]any,
 	return payload, apparentSize, nil
 }
 
+func (u *userEdit) EstimateSize() (int, error) {
+	return getApparentIdentityEventSize(u.GetAsyncPayload())
+}
+
 func (u *userEdit) validateBody() []string {
 	u._warningsList = []string{}
 	if len(u._infos) > 0 {
@@ -0,0 +1,182 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testUserJson = `{
+	"distinct_id": "u1",
+	"$email": [{"value": "u1@example.com", "status": "active", "perma_status": "active"}],
+	"$sms": [{"value": "+911234567890"}],
+	"$androidpush": [{"value": "token-1", "provider": "fcm"}],
+	"$slack": [{"value": {"email": "u1@example.com", "access_token": "xoxb"}}],
+	"$preferred_language": "en",
+	"$timezone": "Asia/Kolkata",
+	"created_at": "2024-05-01T10:30:00.123Z",
+	"updated_at": "not a time",
+	"properties": {"plan": "pro"},
+	"name": "User One",
+	"ss_internal": 1,
+	"$unknown_channel": ["x"]
+}`
+
+func TestUserUnmarshal(t *testing.T) {
+	var u User
+	if err := json.Unmarshal([]byte(testUserJson), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.DistinctId != "u1" || u.PreferredLanguage != "en" || u.Timezone != "Asia/Kolkata" {
+		t.Errorf("user = %+v", u)
+	}
+	if want := []UserChannel{{Value: "u1@example.com", Status: "active", PermaStatus: "active"}}; !reflect.DeepEqual(u.Email, want) {
+		t.Errorf("email = %+v", u.Email)
+	}
+	if len(u.Sms) != 1 || u.Sms[0].Value != "+911234567890" {
+		t.Errorf("sms = %+v", u.Sms)
+	}
+	if want := []UserPushToken{{Value: "token-1", Provider: "fcm"}}; !reflect.DeepEqual(u.AndroidPush, want) {
+		t.Errorf("androidpush = %+v", u.AndroidPush)
+	}
+	if len(u.Slack) != 1 || u.Slack[0].Value["access_token"] != "xoxb" {
+		t.Errorf("slack = %+v", u.Slack)
+	}
+	if want := time.Date(2024, 5, 1, 10, 30, 0, 123e6, time.UTC); !u.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", u.CreatedAt, want)
+	}
+	if !u.UpdatedAt.IsZero() {
+		t.Errorf("unparseable updated_at = %v, want zero", u.UpdatedAt)
+	}
+	// nested and top-level custom properties are merged, reserved keys are left out
+	if want := map[string]any{"plan": "pro", "name": "User One"}; !reflect.DeepEqual(u.Properties, want) {
+		t.Errorf("properties = %v, want %v", u.Properties, want)
+	}
+	if _, found := u.Raw["$unknown_channel"]; !found {
+		t.Errorf("raw = %v, must have keys without a field", u.Raw)
+	}
+}
+
+func TestUserUnmarshalProperties(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want map[string]any
+	}{
+		{"no properties", `{"distinct_id": "u1"}`, map[string]any{}},
+		{"only nested", `{"distinct_id": "u1", "properties": {"a": 1}}`, map[string]any{"a": 1.0}},
+		{"only top-level", `{"distinct_id": "u1", "a": "x", "$email": []}`, map[string]any{"a": "x"}},
+		{"top-level wins over nested", `{"properties": {"a": 1}, "a": 2}`, map[string]any{"a": 2.0}},
+		{"SS_ prefix reserved in any case", `{"SS_flag": true, "Ss_x": 1, "b": null}`, map[string]any{"b": nil}},
+		{"properties not an object", `{"properties": "x"}`, map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u User
+			if err := json.Unmarshal([]byte(tt.json), &u); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(u.Properties, tt.want) {
+				t.Errorf("properties = %#v, want %#v", u.Properties, tt.want)
+			}
+		})
+	}
+	var u User
+	if err := json.Unmarshal([]byte(`{"$email": "not a list"}`), &u); err == nil {
+		t.Error("channel of wrong type must give error")
+	}
+}
+
+func TestUserMarshalRoundTrip(t *testing.T) {
+	var u User
+	if err := json.Unmarshal([]byte(testUserJson), &u); err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got User
+	if err = json.Unmarshal(content, &got); err != nil {
+		t.Fatal(err)
+	}
+	got.Raw, u.Raw = nil, nil
+	if !reflect.DeepEqual(got, u) {
+		t.Errorf("round trip = %+v, want %+v", got, u)
+	}
+
+	// modifications of fields are written, keys without a field are kept
+	if err = json.Unmarshal([]byte(testUserJson), &u); err != nil {
+		t.Fatal(err)
+	}
+	u.Email = nil
+	u.Timezone = "UTC"
+	u.Properties = map[string]any{"plan": "free"}
+	u.UpdatedAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	content, err = json.Marshal(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]any{}
+	json.Unmarshal(content, &out)
+	want := map[string]any{
+		"$timezone": "UTC", "properties": map[string]any{"plan": "free"}, "updated_at": "2024-06-01T00:00:00Z",
+		"created_at": "2024-05-01T10:30:00.123Z", "ss_internal": 1.0, "$unknown_channel": []any{"x"},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(out[k], v) {
+			t.Errorf("%s = %v, want %v", k, out[k], v)
+		}
+	}
+	for _, k := range []string{"$email", "name"} {
+		if _, found := out[k]; found {
+			t.Errorf("%s = %v, must not be written", k, out[k])
+		}
+	}
+}
+
+func TestGetUserAndListUsers(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch r.URL.Path {
+		case "/v1/user/":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"meta": {"limit": 2, "count": 2, "after": "c2", "has_next": true},
+				"results": [` + testUserJson + `, {"distinct_id": "u2", "city": "Pune"}]}`))
+		case "/v1/user/a b/":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testUserJson))
+		default:
+			writeJson(w, 404, map[string]any{"code": 404, "message": "user not found"})
+		}
+	})
+	c := newTestClient(t, ts.URL)
+	ctx := context.Background()
+
+	u, err := c.Users.GetUser(ctx, " a b ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.DistinctId != "u1" || u.Properties["plan"] != "pro" || len(u.Email) != 1 {
+		t.Errorf("user = %+v", u)
+	}
+	if _, err = c.Users.GetUser(ctx, "missing"); err == nil {
+		t.Error("missing user must give error")
+	}
+
+	list, err := c.Users.ListUsers(ctx, &CursorListApiOptions{Limit: 2, After: "c0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := ts.requestsTo("/v1/user/")[0].Query; q != "after=c0&limit=2" {
+		t.Errorf("query = %s", q)
+	}
+	if !list.Meta.HasNext || list.Meta.After != "c2" || len(list.Results) != 2 {
+		t.Fatalf("list = %+v", list)
+	}
+	if second := list.Results[1]; second.DistinctId != "u2" || second.Properties["city"] != "Pune" {
+		t.Errorf("second user = %+v", second)
+	}
+}
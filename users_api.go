@@ -4,36 +4,47 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
 type UsersService interface {
-	List(context.Context, *CursorListApiOptions) (*CursorListApiResponse, error)
-	Get(context.Context, string) (map[string]any, error)
-	Upsert(context.Context, string, map[string]any) (map[string]any, error)
-	AsyncEdit(context.Context, UserEdit) (*Response, error)
-	Edit(context.Context, UserEditRequest) (map[string]any, error)
-	Merge(context.Context, string, UserMergeRequest) (map[string]any, error)
-	Delete(context.Context, string) error
-	BulkDelete(context.Context, UserBulkDeletePayload) error
-	GetObjectsSubscribedTo(context.Context, string, *CursorListApiOptions) (*CursorListApiResponse, error)
-	GetListsSubscribedTo(context.Context, string, *CursorListApiOptions) (*CursorListApiResponse, error)
+	List(context.Context, *CursorListApiOptions, ...RequestOption) (*CursorListApiResponse, error)
+	Get(context.Context, string, ...RequestOption) (map[string]any, error)
+	// typed variants of List/Get
+	ListUsers(context.Context, *CursorListApiOptions, ...RequestOption) (*UserList, error)
+	GetUser(context.Context, string, ...RequestOption) (*User, error)
+	// iterators over all pages of list apis
+	All(context.Context, *CursorListApiOptions, ...RequestOption) iter.Seq2[*User, error]
+	AllObjectsSubscribedTo(context.Context, string, *CursorListApiOptions, ...RequestOption) iter.Seq2[map[string]any, error]
+	AllListsSubscribedTo(context.Context, string, *CursorListApiOptions, ...RequestOption) iter.Seq2[map[string]any, error]
+	Export(context.Context, io.Writer, UserExportFormat, *UserExportOptions, ...RequestOption) (*UserExportReport, error)
+	Import(context.Context, io.Reader, *UserImportOptions) (*UserImportReport, error)
+	ImportFile(context.Context, string, *UserImportOptions) (*UserImportReport, error)
+	Upsert(context.Context, string, map[string]any, ...RequestOption) (map[string]any, error)
+	AsyncEdit(context.Context, UserEdit, ...RequestOption) (*Response, error)
+	Edit(context.Context, UserEditRequest, ...RequestOption) (map[string]any, error)
+	Merge(context.Context, string, UserMergeRequest, ...RequestOption) (map[string]any, error)
+	Delete(context.Context, string, ...RequestOption) error
+	BulkDelete(context.Context, UserBulkDeletePayload, ...RequestOption) error
+	GetObjectsSubscribedTo(context.Context, string, *CursorListApiOptions, ...RequestOption) (*CursorListApiResponse, error)
+	GetListsSubscribedTo(context.Context, string, *CursorListApiOptions, ...RequestOption) (*CursorListApiResponse, error)
 	//
 	GetEditInstance(string) UserEdit
 	GetBulkEditInstance() BulkUsersEdit
 	// Old accessor method (to be deprecated)
 	GetInstance(string) Subscriber
 	//
-	GetFullPreference(context.Context, string, *UserFullPreferencesOptions) (*UserFullPreferenceResponse, error)
-	GetGlobalChannelsPreference(context.Context, string, *UserGlobalChannelsPreferenceOptions) (*UserGlobalChannelsPreferenceResponse, error)
-	UpdateGlobalChannelsPreference(context.Context, string, UserGlobalChannelsPreferenceUpdateBody, *UserGlobalChannelsPreferenceOptions) (*UserGlobalChannelsPreferenceResponse, error)
-	GetAllCategoriesPreference(context.Context, string, *UserCategoriesPreferenceOptions) (*UserCategoriesPreferenceResponse, error)
-	GetCategoryPreference(context.Context, string, string, *UserCategoryPreferenceOptions) (*UserCategoryPreference, error)
-	UpdateCategoryPreference(context.Context, string, string, UserUpdateCategoryPreferenceBody, *UserCategoryPreferenceOptions) (*UserCategoryPreference, error)
-	BulkUpdatePreferences(context.Context, UserBulkPreferenceUpdateBody, *UserBulkPreferenceUpdateOptions) (*UserBulkPreferenceUpdateResponse, error)
-	ResetPreferences(context.Context, UserBulkPreferenceResetBody, *UserBulkPreferenceUpdateOptions) (*UserBulkPreferenceUpdateResponse, error)
+	GetFullPreference(context.Context, string, *UserFullPreferencesOptions, ...RequestOption) (*UserFullPreferenceResponse, error)
+	GetGlobalChannelsPreference(context.Context, string, *UserGlobalChannelsPreferenceOptions, ...RequestOption) (*UserGlobalChannelsPreferenceResponse, error)
+	UpdateGlobalChannelsPreference(context.Context, string, UserGlobalChannelsPreferenceUpdateBody, *UserGlobalChannelsPreferenceOptions, ...RequestOption) (*UserGlobalChannelsPreferenceResponse, error)
+	GetAllCategoriesPreference(context.Context, string, *UserCategoriesPreferenceOptions, ...RequestOption) (*UserCategoriesPreferenceResponse, error)
+	GetCategoryPreference(context.Context, string, string, *UserCategoryPreferenceOptions, ...RequestOption) (*UserCategoryPreference, error)
+	UpdateCategoryPreference(context.Context, string, string, UserUpdateCategoryPreferenceBody, *UserCategoryPreferenceOptions, ...RequestOption) (*UserCategoryPreference, error)
+	BulkUpdatePreferences(context.Context, UserBulkPreferenceUpdateBody, *UserBulkPreferenceUpdateOptions, ...RequestOption) (*UserBulkPreferenceUpdateResponse, error)
+	ResetPreferences(context.Context, UserBulkPreferenceResetBody, *UserBulkPreferenceUpdateOptions, ...RequestOption) (*UserBulkPreferenceUpdateResponse, error)
 }
 
 type usersService struct {
@@ -53,14 +64,14 @@ func newUsersService(client *Client) *usersService {
 	return us
 }
 
-func (u *usersService) List(ctx context.Context, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (u *usersService) List(ctx context.Context, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(u._url, opts.BuildQuery())
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -82,14 +93,14 @@ func (u *usersService) userDetailAPIUrl(distinctId string) string {
 	)
 }
 
-func (u *usersService) Get(ctx context.Context, distinctId string) (map[string]any, error) {
+func (u *usersService) Get(ctx context.Context, distinctId string, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := u.userDetailAPIUrl(distinctId)
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,17 +114,72 @@ func (u *usersService) Get(ctx context.Context, distinctId string) (map[string]a
 	return resp, nil
 }
 
-func (u *usersService) Upsert(ctx context.Context, distinctId string, payload map[string]any) (map[string]any, error) {
+func (u *usersService) ListUsers(ctx context.Context, opts *CursorListApiOptions, reqOpts ...RequestOption) (*UserList, error) {
+	urlStr := appendQueryParamPart(u._url, opts.BuildQuery())
+	// prepare http.Request object
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	//
+	resp := &UserList{}
+	err = u.client.parseApiResponse(httpResponse, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// All returns iterator over all users (see ListUsers), starting after opts.After. Pages are fetched as needed
+func (u *usersService) All(ctx context.Context, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[*User, error] {
+	fetch := cursorPageFetcher(opts,
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiMetaInfo, []*User, error) {
+			resp, err := u.ListUsers(ctx, pageOpts, reqOpts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp.Meta, resp.Results, nil
+		})
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
+
+func (u *usersService) GetUser(ctx context.Context, distinctId string, reqOpts ...RequestOption) (*User, error) {
+	urlStr := u.userDetailAPIUrl(distinctId)
+	// prepare http.Request object
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	//
+	resp := &User{}
+	err = u.client.parseApiResponse(httpResponse, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (u *usersService) Upsert(ctx context.Context, distinctId string, payload map[string]any, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := u.userDetailAPIUrl(distinctId)
 	if payload == nil {
 		payload = map[string]any{}
 	}
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := u.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +193,7 @@ func (u *usersService) Upsert(ctx context.Context, distinctId string, payload ma
 	return resp, nil
 }
 
-func (u *usersService) AsyncEdit(ctx context.Context, editInstance UserEdit) (*Response, error) {
+func (u *usersService) AsyncEdit(ctx context.Context, editInstance UserEdit, reqOpts ...RequestOption) (*Response, error) {
 	ue := editInstance.(*userEdit)
 	ue.validateBody()
 	payload := ue.GetAsyncPayload()
@@ -137,11 +203,11 @@ func (u *usersService) AsyncEdit(ctx context.Context, editInstance UserEdit) (*R
 	}
 	urlStr := fmt.Sprintf("%sevent/", u.client.baseUrl)
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := u.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +221,7 @@ func (u *usersService) AsyncEdit(ctx context.Context, editInstance UserEdit) (*R
 }
 
 func (u *usersService) asyncAPIResponse(httpRes *http.Response) (*Response, error) {
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
 		return nil, &Error{Err: err}
 	}
@@ -174,7 +240,7 @@ type UserEditRequest struct {
 	EditInstance UserEdit
 }
 
-func (u *usersService) Edit(ctx context.Context, req UserEditRequest) (map[string]any, error) {
+func (u *usersService) Edit(ctx context.Context, req UserEditRequest, reqOpts ...RequestOption) (map[string]any, error) {
 	var urlStr string
 	var payload map[string]any
 	if req.EditInstance != nil {
@@ -190,11 +256,11 @@ func (u *usersService) Edit(ctx context.Context, req UserEditRequest) (map[strin
 		urlStr = u.userDetailAPIUrl(req.DistinctId)
 	}
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("PATCH", urlStr, payload)
+	request, err := u.client.prepareHttpRequest("PATCH", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -212,14 +278,14 @@ type UserMergeRequest struct {
 	FromUserId string `json:"from_user_id"`
 }
 
-func (u *usersService) Merge(ctx context.Context, distinctId string, payload UserMergeRequest) (map[string]any, error) {
+func (u *usersService) Merge(ctx context.Context, distinctId string, payload UserMergeRequest, reqOpts ...RequestOption) (map[string]any, error) {
 	urlStr := fmt.Sprintf("%smerge/", u.userDetailAPIUrl(distinctId))
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("POST", urlStr, payload)
+	request, err := u.client.prepareHttpRequest("POST", urlStr, payload, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -233,14 +299,14 @@ func (u *usersService) Merge(ctx context.Context, distinctId string, payload Use
 	return resp, nil
 }
 
-func (u *usersService) Delete(ctx context.Context, distinctId string) error {
+func (u *usersService) Delete(ctx context.Context, distinctId string, reqOpts ...RequestOption) error {
 	urlStr := u.userDetailAPIUrl(distinctId)
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("DELETE", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("DELETE", urlStr, nil, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -258,13 +324,13 @@ type UserBulkDeletePayload struct {
 }
 
 // payload: {"distinct_ids": ["id1", "id2"]}
-func (u *usersService) BulkDelete(ctx context.Context, payload UserBulkDeletePayload) error {
+func (u *usersService) BulkDelete(ctx context.Context, payload UserBulkDeletePayload, reqOpts ...RequestOption) error {
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("DELETE", u._bulkUrl, payload)
+	request, err := u.client.prepareHttpRequest("DELETE", u._bulkUrl, payload, reqOpts...)
 	if err != nil {
 		return err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return err
 	}
@@ -277,14 +343,14 @@ func (u *usersService) BulkDelete(ctx context.Context, payload UserBulkDeletePay
 	return nil
 }
 
-func (u *usersService) GetObjectsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (u *usersService) GetObjectsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%ssubscribed_to/object/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -298,14 +364,14 @@ func (u *usersService) GetObjectsSubscribedTo(ctx context.Context, distinctId st
 	return resp, nil
 }
 
-func (u *usersService) GetListsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions) (*CursorListApiResponse, error) {
+func (u *usersService) GetListsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions, reqOpts ...RequestOption) (*CursorListApiResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%ssubscribed_to/list/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
 	// prepare http.Request object
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -333,13 +399,13 @@ func (u *usersService) GetInstance(distinctId string) Subscriber {
 }
 
 // GetFullPreference fetches the current notification preferences for the user across all categories and channels.
-func (u *usersService) GetFullPreference(ctx context.Context, distinctId string, opts *UserFullPreferencesOptions) (*UserFullPreferenceResponse, error) {
+func (u *usersService) GetFullPreference(ctx context.Context, distinctId string, opts *UserFullPreferencesOptions, reqOpts ...RequestOption) (*UserFullPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -352,13 +418,13 @@ func (u *usersService) GetFullPreference(ctx context.Context, distinctId string,
 	return resp, nil
 }
 
-func (u *usersService) GetGlobalChannelsPreference(ctx context.Context, distinctId string, opts *UserGlobalChannelsPreferenceOptions) (*UserGlobalChannelsPreferenceResponse, error) {
+func (u *usersService) GetGlobalChannelsPreference(ctx context.Context, distinctId string, opts *UserGlobalChannelsPreferenceOptions, reqOpts ...RequestOption) (*UserGlobalChannelsPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/channel_preference/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -371,13 +437,13 @@ func (u *usersService) GetGlobalChannelsPreference(ctx context.Context, distinct
 	return resp, nil
 }
 
-func (u *usersService) UpdateGlobalChannelsPreference(ctx context.Context, distinctId string, body UserGlobalChannelsPreferenceUpdateBody, opts *UserGlobalChannelsPreferenceOptions) (*UserGlobalChannelsPreferenceResponse, error) {
+func (u *usersService) UpdateGlobalChannelsPreference(ctx context.Context, distinctId string, body UserGlobalChannelsPreferenceUpdateBody, opts *UserGlobalChannelsPreferenceOptions, reqOpts ...RequestOption) (*UserGlobalChannelsPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/channel_preference/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -390,13 +456,13 @@ func (u *usersService) UpdateGlobalChannelsPreference(ctx context.Context, disti
 	return resp, nil
 }
 
-func (u *usersService) GetAllCategoriesPreference(ctx context.Context, distinctId string, opts *UserCategoriesPreferenceOptions) (*UserCategoriesPreferenceResponse, error) {
+func (u *usersService) GetAllCategoriesPreference(ctx context.Context, distinctId string, opts *UserCategoriesPreferenceOptions, reqOpts ...RequestOption) (*UserCategoriesPreferenceResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/", u.userDetailAPIUrl(distinctId)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -409,13 +475,13 @@ func (u *usersService) GetAllCategoriesPreference(ctx context.Context, distinctI
 	return resp, nil
 }
 
-func (u *usersService) GetCategoryPreference(ctx context.Context, distinctId string, category string, opts *UserCategoryPreferenceOptions) (*UserCategoryPreference, error) {
+func (u *usersService) GetCategoryPreference(ctx context.Context, distinctId string, category string, opts *UserCategoryPreferenceOptions, reqOpts ...RequestOption) (*UserCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", u.userDetailAPIUrl(distinctId), url.PathEscape(category)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("GET", urlStr, nil)
+	request, err := u.client.prepareHttpRequest("GET", urlStr, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -428,13 +494,13 @@ func (u *usersService) GetCategoryPreference(ctx context.Context, distinctId str
 	return resp, nil
 }
 
-func (u *usersService) UpdateCategoryPreference(ctx context.Context, distinctId string, category string, body UserUpdateCategoryPreferenceBody, opts *UserCategoryPreferenceOptions) (*UserCategoryPreference, error) {
+func (u *usersService) UpdateCategoryPreference(ctx context.Context, distinctId string, category string, body UserUpdateCategoryPreferenceBody, opts *UserCategoryPreferenceOptions, reqOpts ...RequestOption) (*UserCategoryPreference, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/category/%s/", u.userDetailAPIUrl(distinctId), url.PathEscape(category)), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -447,13 +513,13 @@ func (u *usersService) UpdateCategoryPreference(ctx context.Context, distinctId
 	return resp, nil
 }
 
-func (u *usersService) BulkUpdatePreferences(ctx context.Context, body UserBulkPreferenceUpdateBody, opts *UserBulkPreferenceUpdateOptions) (*UserBulkPreferenceUpdateResponse, error) {
+func (u *usersService) BulkUpdatePreferences(ctx context.Context, body UserBulkPreferenceUpdateBody, opts *UserBulkPreferenceUpdateOptions, reqOpts ...RequestOption) (*UserBulkPreferenceUpdateResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/", u._bulkUrl), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -466,13 +532,13 @@ func (u *usersService) BulkUpdatePreferences(ctx context.Context, body UserBulkP
 	return resp, nil
 }
 
-func (u *usersService) ResetPreferences(ctx context.Context, body UserBulkPreferenceResetBody, opts *UserBulkPreferenceUpdateOptions) (*UserBulkPreferenceUpdateResponse, error) {
+func (u *usersService) ResetPreferences(ctx context.Context, body UserBulkPreferenceResetBody, opts *UserBulkPreferenceUpdateOptions, reqOpts ...RequestOption) (*UserBulkPreferenceUpdateResponse, error) {
 	urlStr := appendQueryParamPart(fmt.Sprintf("%spreference/reset/", u._bulkUrl), opts.BuildQuery())
-	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body)
+	request, err := u.client.prepareHttpRequest("PATCH", urlStr, body, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := u.client.httpClient.Do(request)
+	httpResponse, err := u.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -484,3 +550,21 @@ func (u *usersService) ResetPreferences(ctx context.Context, body UserBulkPrefer
 	}
 	return resp, nil
 }
+
+// AllObjectsSubscribedTo returns iterator over all objects user is subscribed to. Pages are fetched as needed
+func (u *usersService) AllObjectsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[map[string]any, error] {
+	fetch := cursorPageFetcher(opts, cursorResponsePages(
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiResponse, error) {
+			return u.GetObjectsSubscribedTo(ctx, distinctId, pageOpts, reqOpts...)
+		}))
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
+
+// AllListsSubscribedTo returns iterator over all lists user is subscribed to. Pages are fetched as needed
+func (u *usersService) AllListsSubscribedTo(ctx context.Context, distinctId string, opts *CursorListApiOptions, reqOpts ...RequestOption) iter.Seq2[map[string]any, error] {
+	fetch := cursorPageFetcher(opts, cursorResponsePages(
+		func(ctx context.Context, pageOpts *CursorListApiOptions) (*CursorListApiResponse, error) {
+			return u.GetListsSubscribedTo(ctx, distinctId, pageOpts, reqOpts...)
+		}))
+	return paginate(ctx, fetch, prefetchEnabled(opts))
+}
@@ -0,0 +1,431 @@
+package suprsend
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type UserExportFormat string
+
+const (
+	UserExportFormatJSONL UserExportFormat = "jsonl"
+	UserExportFormatCSV   UserExportFormat = "csv"
+)
+
+/*
+Columns written in csv export. Channels with string values (email, sms, whatsapp, androidpush, iospush)
+are joined with ";", other channels (webpush, slack, ms_teams), properties and preferences are written as json.
+"properties.<key>" writes a single custom property, "lists" writes ids of lists user is subscribed to (joined with ";").
+Any other column is looked up in user json as returned by api.
+*/
+var defaultUserExportColumns = []string{
+	"distinct_id", "email", "sms", "whatsapp", "androidpush", "iospush", "webpush", "slack", "ms_teams",
+	"preferred_language", "timezone", "locale", "properties", "created_at", "updated_at",
+}
+
+type UserExportOptions struct {
+	// users fetched per Users.List call. default: 100
+	PageSize int
+	// export starts after this cursor (CursorListApiMetaInfo.After). Ignored if checkpoint file exists
+	After string
+	// csv columns, see defaultUserExportColumns. default: all of them (+ lists/preferences if included)
+	Columns []string
+	// add full preference (GetFullPreference) of each user, under "preferences" key/column
+	IncludePreferences bool
+	// add lists each user is subscribed to (GetListsSubscribedTo), under "lists" key/column
+	IncludeLists bool
+	// max concurrent api calls while adding preferences/lists of users of a page. default: 5
+	Concurrency int
+	/*
+		cursor of next page is saved to this file after every page is written. If file exists, export
+		resumes from its cursor, so an interrupted export should be resumed with w appending to same output.
+		Each page is written to w with a single Write call. csv header is written unless export is resumed from this file.
+		If checkpoint file is of a finished export, Export returns ErrUserExportDone without writing anything.
+	*/
+	CheckpointFile string
+	// called after every page
+	OnProgress func(UserExportReport)
+}
+
+func (o *UserExportOptions) cleanParams() {
+	if o.PageSize <= 0 || o.PageSize > 1000 {
+		o.PageSize = 100
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 5
+	}
+	if len(o.Columns) == 0 {
+		o.Columns = append([]string{}, defaultUserExportColumns...)
+		if o.IncludeLists {
+			o.Columns = append(o.Columns, "lists")
+		}
+		if o.IncludePreferences {
+			o.Columns = append(o.Columns, "preferences")
+		}
+	}
+}
+
+type UserExportReport struct {
+	// users written, including ones written before resuming
+	Users int
+	Pages int
+	// cursor to resume from. empty once all users are written
+	After string
+	Done  bool
+}
+
+func (r UserExportReport) String() string {
+	return fmt.Sprintf("UserExportReport{Users: %v, Pages: %v, After: %v, Done: %v}", r.Users, r.Pages, r.After, r.Done)
+}
+
+// content of checkpoint file
+type userExportCheckpoint struct {
+	After     string `json:"after"`
+	Users     int    `json:"users"`
+	Pages     int    `json:"pages"`
+	Done      bool   `json:"done"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// user with data added by export
+type exportedUser struct {
+	user        *User
+	preferences *UserFullPreferenceResponse
+	lists       []map[string]any
+}
+
+/*
+Export writes all users to w, as jsonl (user json as returned by api, one per line) or csv (see UserExportOptions.Columns).
+Users are fetched, written and forgotten one page at a time, so memory use doesn't grow with number of users.
+Returns error if a page or preferences/lists of a user can't be fetched, or w can't be written to, along with
+report till then. Export can be resumed from report.After (or checkpoint file).
+*/
+func (u *usersService) Export(ctx context.Context, w io.Writer, format UserExportFormat, opts *UserExportOptions, reqOpts ...RequestOption,
+) (*UserExportReport, error) {
+	var o UserExportOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.cleanParams()
+	ex := &userExport{service: u, opts: &o, reqOpts: reqOpts, report: &UserExportReport{After: o.After}}
+	err := ex.run(ctx, w, UserExportFormat(strings.ToLower(strings.TrimSpace(string(format)))))
+	return ex.report, err
+}
+
+type userExport struct {
+	service *usersService
+	opts    *UserExportOptions
+	reqOpts []RequestOption
+	report  *UserExportReport
+}
+
+func (ex *userExport) run(ctx context.Context, w io.Writer, format UserExportFormat) error {
+	resumed, err := ex.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	if ex.report.Done {
+		return ErrUserExportDone
+	}
+	// each page is encoded here first and written to w in one go, so that w never has part of a page
+	var buf bytes.Buffer
+	var writeUser func(*exportedUser) error
+	var endPage func() error
+	switch format {
+	case "", UserExportFormatJSONL:
+		writeUser = func(eu *exportedUser) error { return ex.writeJsonl(&buf, eu) }
+		endPage = func() error { return nil }
+	case UserExportFormatCSV:
+		cw := csv.NewWriter(&buf)
+		// resumed export appends to output which already has header
+		if !resumed {
+			if err = cw.Write(ex.opts.Columns); err != nil {
+				return &Error{Err: err}
+			}
+		}
+		writeUser = func(eu *exportedUser) error { return cw.Write(ex.csvRow(eu)) }
+		endPage = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return &Error{Message: fmt.Sprintf("export: unsupported format %s", format)}
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		page, err := ex.service.ListUsers(ctx, &CursorListApiOptions{Limit: ex.opts.PageSize, After: ex.report.After}, ex.reqOpts...)
+		if err != nil {
+			return err
+		}
+		users, err := ex.enrich(ctx, page.Results)
+		if err != nil {
+			return err
+		}
+		for _, eu := range users {
+			if err = writeUser(eu); err != nil {
+				return &Error{Err: err}
+			}
+		}
+		if err = endPage(); err != nil {
+			return &Error{Err: err}
+		}
+		// page must be in output before checkpoint moves past it
+		if _, err = w.Write(buf.Bytes()); err != nil {
+			return &Error{Err: err}
+		}
+		buf.Reset()
+		ex.report.Users += len(users)
+		ex.report.Pages++
+		meta := page.Meta
+		if meta == nil || !meta.HasNext || meta.After == "" || meta.After == ex.report.After {
+			ex.report.After, ex.report.Done = "", true
+		} else {
+			ex.report.After = meta.After
+		}
+		if err = ex.saveCheckpoint(); err != nil {
+			return err
+		}
+		if ex.report.Done {
+			return nil
+		}
+	}
+}
+
+// fetches preferences/lists of users (if included), with at most Concurrency api calls at a time
+func (ex *userExport) enrich(ctx context.Context, users []*User) ([]*exportedUser, error) {
+	out := make([]*exportedUser, len(users))
+	for i, user := range users {
+		out[i] = &exportedUser{user: user}
+	}
+	if !ex.opts.IncludePreferences && !ex.opts.IncludeLists {
+		return out, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, ex.opts.Concurrency)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for _, eu := range out {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			distinctId := eu.user.DistinctId
+			if ex.opts.IncludePreferences {
+				prefs, err := ex.service.GetFullPreference(ctx, distinctId, nil, ex.reqOpts...)
+				if err != nil {
+					fail(fmt.Errorf("export: preferences of user %s: %w", distinctId, err))
+					return
+				}
+				eu.preferences = prefs
+			}
+			if ex.opts.IncludeLists {
+				eu.lists = []map[string]any{}
+				for list, err := range ex.service.AllListsSubscribedTo(ctx, distinctId, &CursorListApiOptions{Limit: 100}, ex.reqOpts...) {
+					if err != nil {
+						fail(fmt.Errorf("export: lists of user %s: %w", distinctId, err))
+						return
+					}
+					eu.lists = append(eu.lists, list)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+func (ex *userExport) writeJsonl(w io.Writer, eu *exportedUser) error {
+	line := eu.user.Raw
+	if eu.preferences != nil || eu.lists != nil {
+		line = make(map[string]any, len(eu.user.Raw)+2)
+		for k, v := range eu.user.Raw {
+			line[k] = v
+		}
+		if eu.preferences != nil {
+			line["preferences"] = eu.preferences
+		}
+		if eu.lists != nil {
+			line["lists"] = eu.lists
+		}
+	}
+	content, err := ex.service.client.marshal(line)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+func (ex *userExport) csvRow(eu *exportedUser) []string {
+	row := make([]string, len(ex.opts.Columns))
+	for i, col := range ex.opts.Columns {
+		row[i] = userExportColumnValue(eu, col)
+	}
+	return row
+}
+
+func userExportColumnValue(eu *exportedUser, col string) string {
+	user := eu.user
+	switch col {
+	case "distinct_id":
+		return user.DistinctId
+	case "email":
+		return joinUserChannels(user.Email)
+	case "sms":
+		return joinUserChannels(user.Sms)
+	case "whatsapp":
+		return joinUserChannels(user.Whatsapp)
+	case "androidpush":
+		return joinPushTokens(user.AndroidPush)
+	case "iospush":
+		return joinPushTokens(user.IosPush)
+	case "webpush":
+		return exportJsonValue(user.WebPush)
+	case "slack":
+		return exportJsonValue(user.Slack)
+	case "ms_teams":
+		return exportJsonValue(user.MSTeams)
+	case "preferred_language":
+		return user.PreferredLanguage
+	case "timezone":
+		return user.Timezone
+	case "locale":
+		return user.Locale
+	case "properties":
+		return exportJsonValue(user.Properties)
+	case "created_at":
+		return exportTimeValue(user.CreatedAt)
+	case "updated_at":
+		return exportTimeValue(user.UpdatedAt)
+	case "preferences":
+		return exportJsonValue(eu.preferences)
+	case "lists":
+		ids := []string{}
+		for _, l := range eu.lists {
+			if id := subscribedListId(l); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return strings.Join(ids, ";")
+	}
+	if key, isProp := strings.CutPrefix(col, "properties."); isProp {
+		return exportJsonValue(user.Properties[key])
+	}
+	return exportJsonValue(user.Raw[col])
+}
+
+func joinUserChannels(channels []UserChannel) string {
+	values := make([]string, len(channels))
+	for i, ch := range channels {
+		values[i] = ch.Value
+	}
+	return strings.Join(values, ";")
+}
+
+func joinPushTokens(tokens []UserPushToken) string {
+	values := make([]string, len(tokens))
+	for i, t := range tokens {
+		values[i] = t.Value
+	}
+	return strings.Join(values, ";")
+}
+
+// strings are written as-is, everything else as json. nil/empty values give empty cell
+func exportJsonValue(v any) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	}
+	content, err := json.Marshal(v)
+	if err != nil || string(content) == "null" {
+		return ""
+	}
+	return string(content)
+}
+
+func exportTimeValue(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// list id of an item of GetListsSubscribedTo, which has it either at top level or under "list"
+func subscribedListId(item map[string]any) string {
+	if id, ok := item["list_id"].(string); ok {
+		return id
+	}
+	if l, ok := item["list"].(map[string]any); ok {
+		id, _ := l["list_id"].(string)
+		return id
+	}
+	return ""
+}
+
+// returns true if export is resumed from checkpoint file
+func (ex *userExport) loadCheckpoint() (bool, error) {
+	if ex.opts.CheckpointFile == "" {
+		return false, nil
+	}
+	content, err := os.ReadFile(ex.opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, &Error{Err: err}
+	}
+	cp := userExportCheckpoint{}
+	if err = json.Unmarshal(content, &cp); err != nil {
+		return false, &Error{Message: fmt.Sprintf("export: invalid checkpoint file %s: %v", ex.opts.CheckpointFile, err)}
+	}
+	*ex.report = UserExportReport{Users: cp.Users, Pages: cp.Pages, After: cp.After, Done: cp.Done}
+	return true, nil
+}
+
+func (ex *userExport) saveCheckpoint() error {
+	if ex.opts.OnProgress != nil {
+		ex.opts.OnProgress(*ex.report)
+	}
+	if ex.opts.CheckpointFile == "" {
+		return nil
+	}
+	content, err := json.Marshal(userExportCheckpoint{
+		After:     ex.report.After,
+		Users:     ex.report.Users,
+		Pages:     ex.report.Pages,
+		Done:      ex.report.Done,
+		UpdatedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return &Error{Err: err}
+	}
+	return writeFileAtomic(ex.opts.CheckpointFile, content)
+}
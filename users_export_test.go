@@ -0,0 +1,252 @@
+package suprsend
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+stand-in for users apis used by export: 5 users over 3 pages (cursors c1, c2), lists and preferences of each user.
+List call for page after failAfter (if set) fails.
+*/
+func exportServer(t *testing.T, failAfter *atomic.Value) *testServer {
+	pages := map[string]string{
+		"":   `{"meta": {"has_next": true, "after": "c1"}, "results": [{"distinct_id": "u1", "$email": [{"value": "u1@example.com"}, {"value": "u1@work.com"}], "properties": {"plan": "pro"}}, {"distinct_id": "u2", "city": "Pune"}]}`,
+		"c1": `{"meta": {"has_next": true, "after": "c2"}, "results": [{"distinct_id": "u3"}, {"distinct_id": "u4", "$timezone": "UTC"}]}`,
+		"c2": `{"meta": {"has_next": false}, "results": [{"distinct_id": "u5", "created_at": "2024-05-01T10:30:00Z"}]}`,
+	}
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/user/":
+			after := r.URL.Query().Get("after")
+			if failAfter != nil && failAfter.Load() == after {
+				writeJson(w, 500, map[string]any{"code": 500, "message": "boom"})
+				return
+			}
+			w.Write([]byte(pages[after]))
+		case strings.HasSuffix(r.URL.Path, "/subscribed_to/list/"):
+			id := strings.Split(r.URL.Path, "/")[3]
+			w.Write([]byte(`{"meta": {"has_next": false}, "results": [{"list": {"list_id": "news-` + id + `"}}, {"list_id": "all"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/preference/"):
+			w.Write([]byte(`{"sections": [], "channel_preferences": [{"channel": "email", "is_restricted": false}]}`))
+		default:
+			writeJson(w, 404, map[string]any{"code": 404, "message": "not found"})
+		}
+	})
+}
+
+// records each Write call separately
+type writeRecorder struct {
+	writes []string
+}
+
+func (wr *writeRecorder) Write(p []byte) (int, error) {
+	wr.writes = append(wr.writes, string(p))
+	return len(p), nil
+}
+
+func (wr *writeRecorder) String() string {
+	return strings.Join(wr.writes, "")
+}
+
+func readCsv(t *testing.T, content string) [][]string {
+	t.Helper()
+	rows, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestUserExportJSONL(t *testing.T) {
+	ts := exportServer(t, nil)
+	c := newTestClient(t, ts.URL)
+	var out writeRecorder
+	progress := []UserExportReport{}
+	report, err := c.Users.Export(context.Background(), &out, UserExportFormatJSONL, &UserExportOptions{
+		PageSize: 2, IncludeLists: true, IncludePreferences: true,
+		OnProgress: func(r UserExportReport) { progress = append(progress, r) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserExportReport{Users: 5, Pages: 3, Done: true}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	if len(progress) != 3 || progress[0].After != "c1" || progress[0].Users != 2 {
+		t.Errorf("progress = %v", progress)
+	}
+	// each page is written with a single call, ending at a line boundary
+	if len(out.writes) != 3 {
+		t.Fatalf("writes = %d, want 3", len(out.writes))
+	}
+	for i, w := range out.writes {
+		if !strings.HasSuffix(w, "\n") {
+			t.Errorf("write %d doesn't end with a full line: %q", i, w)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("lines = %v", lines)
+	}
+	first := map[string]any{}
+	if err = json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["distinct_id"] != "u1" || first["properties"].(map[string]any)["plan"] != "pro" {
+		t.Errorf("user json = %v", first)
+	}
+	if lists := first["lists"].([]any); len(lists) != 2 {
+		t.Errorf("lists = %v", lists)
+	}
+	if prefs := first["preferences"].(map[string]any); len(prefs["channel_preferences"].([]any)) != 1 {
+		t.Errorf("preferences = %v", prefs)
+	}
+	if n := len(ts.requestsTo("/v1/user/u3/preference/")); n != 1 {
+		t.Errorf("preference calls for u3 = %d, want 1", n)
+	}
+}
+
+func TestUserExportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		opts UserExportOptions
+		want [][]string
+	}{
+		{"custom columns", UserExportOptions{PageSize: 2, Columns: []string{"distinct_id", "email", "properties.plan", "city", "timezone", "created_at"}},
+			[][]string{
+				{"distinct_id", "email", "properties.plan", "city", "timezone", "created_at"},
+				{"u1", "u1@example.com;u1@work.com", "pro", "", "", ""},
+				{"u2", "", "", "Pune", "", ""},
+				{"u3", "", "", "", "", ""},
+				{"u4", "", "", "", "UTC", ""},
+				{"u5", "", "", "", "", "2024-05-01T10:30:00Z"},
+			}},
+		{"lists column", UserExportOptions{PageSize: 2, Columns: []string{"distinct_id", "lists"}, IncludeLists: true},
+			[][]string{
+				{"distinct_id", "lists"},
+				{"u1", "news-u1;all"}, {"u2", "news-u2;all"}, {"u3", "news-u3;all"}, {"u4", "news-u4;all"}, {"u5", "news-u5;all"},
+			}},
+		{"starting after cursor without checkpoint still writes header", UserExportOptions{PageSize: 2, After: "c2", Columns: []string{"distinct_id"}},
+			[][]string{{"distinct_id"}, {"u5"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := exportServer(t, nil)
+			c := newTestClient(t, ts.URL)
+			var out bytes.Buffer
+			_, err := c.Users.Export(context.Background(), &out, UserExportFormatCSV, &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readCsv(t, out.String()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("csv = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// default columns
+	ts := exportServer(t, nil)
+	c := newTestClient(t, ts.URL)
+	var out bytes.Buffer
+	if _, err := c.Users.Export(context.Background(), &out, "CSV", nil); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readCsv(t, out.String()); !reflect.DeepEqual(rows[0], defaultUserExportColumns) || len(rows) != 6 {
+		t.Errorf("csv = %v", rows)
+	}
+	if _, err := c.Users.Export(context.Background(), &out, "xml", nil); err == nil {
+		t.Error("unsupported format must give error")
+	}
+}
+
+func TestUserExportResumesFromCheckpoint(t *testing.T) {
+	for _, format := range []UserExportFormat{UserExportFormatCSV, UserExportFormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var failAfter atomic.Value
+			failAfter.Store("c1")
+			ts := exportServer(t, &failAfter)
+			c := newTestClient(t, ts.URL)
+			opts := &UserExportOptions{PageSize: 2, Columns: []string{"distinct_id"},
+				CheckpointFile: filepath.Join(t.TempDir(), "export.json")}
+			var out writeRecorder
+
+			// first run fails on second page, after first page is written and checkpointed
+			report, err := c.Users.Export(context.Background(), &out, format, opts)
+			if err == nil {
+				t.Fatal("want error of second page")
+			}
+			if report.Users != 2 || report.After != "c1" || report.Done {
+				t.Errorf("report = %v", report)
+			}
+
+			// second run appends rest of users to same output, without another csv header
+			failAfter.Store("none")
+			report, err = c.Users.Export(context.Background(), &out, format, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (UserExportReport{Users: 5, Pages: 3, Done: true}); *report != want {
+				t.Errorf("report = %v, want %v", report, want)
+			}
+			ids := []string{}
+			if format == UserExportFormatCSV {
+				for _, row := range readCsv(t, out.String()) {
+					ids = append(ids, row[0])
+				}
+			} else {
+				for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+					u := map[string]any{}
+					json.Unmarshal([]byte(line), &u)
+					ids = append(ids, u["distinct_id"].(string))
+				}
+			}
+			want := []string{"u1", "u2", "u3", "u4", "u5"}
+			if format == UserExportFormatCSV {
+				want = append([]string{"distinct_id"}, want...)
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("output ids = %v, want %v", ids, want)
+			}
+
+			// finished export isn't repeated, nor silently reported as an export of 0 users
+			calls, writes := len(ts.requestsTo("/v1/user/")), len(out.writes)
+			report, err = c.Users.Export(context.Background(), &out, format, opts)
+			if !errors.Is(err, ErrUserExportDone) || !report.Done || report.Users != 5 {
+				t.Errorf("report = %v, err = %v, want ErrUserExportDone", report, err)
+			}
+			if len(ts.requestsTo("/v1/user/")) != calls || len(out.writes) != writes {
+				t.Error("finished export must not make calls or write output")
+			}
+		})
+	}
+}
+
+func TestUserExportEnrichError(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if r.URL.Path == "/v1/user/" {
+			writeJson(w, 200, map[string]any{"meta": map[string]any{"has_next": false},
+				"results": []any{map[string]any{"distinct_id": "u1"}}})
+			return
+		}
+		writeJson(w, 400, map[string]any{"code": 400, "message": "bad"})
+	})
+	c := newTestClient(t, ts.URL)
+	var out bytes.Buffer
+	report, err := c.Users.Export(context.Background(), &out, UserExportFormatJSONL, &UserExportOptions{IncludePreferences: true})
+	if err == nil || !strings.Contains(err.Error(), "preferences of user u1") {
+		t.Fatalf("err = %v", err)
+	}
+	if out.Len() != 0 || report.Users != 0 {
+		t.Errorf("output = %q, report = %v, want nothing written", out.String(), report)
+	}
+}
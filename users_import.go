@@ -0,0 +1,651 @@
+package suprsend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+type UserImportFormat string
+
+const (
+	UserImportFormatJSONL UserImportFormat = "jsonl"
+	UserImportFormatCSV   UserImportFormat = "csv"
+)
+
+// UserEdit operation to which a column is mapped
+type UserImportOp string
+
+const (
+	// property named as column (without "properties." prefix) is set, see UserEdit.Set
+	UserImportOpSet     UserImportOp = "set"
+	UserImportOpSetOnce UserImportOp = "set_once"
+	UserImportOpAppend  UserImportOp = "append"
+	// column has a json object, each of its keys is set as property
+	UserImportOpSetProperties UserImportOp = "set_properties"
+	/*
+		channels: value is added (AddEmail etc.). In csv, multiple values are separated by ";".
+		In jsonl, value can also be a list of strings, or of objects with "value" (as in user json)
+	*/
+	UserImportOpEmail    UserImportOp = "email"
+	UserImportOpSms      UserImportOp = "sms"
+	UserImportOpWhatsapp UserImportOp = "whatsapp"
+	/*
+		push tokens: same as above, objects can also have "provider". default provider: fcm (androidpush),
+		apns (iospush)
+	*/
+	UserImportOpAndroidpush UserImportOp = "androidpush"
+	UserImportOpIospush     UserImportOp = "iospush"
+	/*
+		channels whose value is a json object: a json list of objects with "value" (as in user json, and as
+		written by Users.Export), or a single json object which is the value itself
+	*/
+	UserImportOpWebpush UserImportOp = "webpush"
+	UserImportOpSlack   UserImportOp = "slack"
+	UserImportOpMSTeams UserImportOp = "ms_teams"
+	//
+	UserImportOpTimezone          UserImportOp = "timezone"
+	UserImportOpPreferredLanguage UserImportOp = "preferred_language"
+	UserImportOpLocale            UserImportOp = "locale"
+	// column is not imported
+	UserImportOpIgnore UserImportOp = "ignore"
+)
+
+/*
+Maps columns (csv) or keys (jsonl) to UserEdit operations. Without explicit mapping, channel columns (email, sms,
+whatsapp, androidpush, iospush, webpush, slack, ms_teams) and timezone/preferred_language/locale columns (with or
+without "$" prefix) are mapped to their channel/setter, "properties" to set_properties, and other columns to Unmapped.
+Reserved keys ($..., ss_..., created_at, updated_at) and lists/preferences added by Users.Export are ignored,
+so that jsonl/csv written by Users.Export can be imported as-is.
+*/
+type UserImportMapping struct {
+	// default: distinct_id
+	DistinctId string
+	// column -> operation. Takes precedence over default mapping
+	Columns map[string]UserImportOp
+	// operation of columns which aren't mapped. default: set
+	Unmapped UserImportOp
+}
+
+var defaultUserImportColumns = map[string]UserImportOp{
+	"email":               UserImportOpEmail,
+	"$email":              UserImportOpEmail,
+	"sms":                 UserImportOpSms,
+	"$sms":                UserImportOpSms,
+	"whatsapp":            UserImportOpWhatsapp,
+	"$whatsapp":           UserImportOpWhatsapp,
+	"timezone":            UserImportOpTimezone,
+	"$timezone":           UserImportOpTimezone,
+	"androidpush":         UserImportOpAndroidpush,
+	"$androidpush":        UserImportOpAndroidpush,
+	"iospush":             UserImportOpIospush,
+	"$iospush":            UserImportOpIospush,
+	"webpush":             UserImportOpWebpush,
+	"$webpush":            UserImportOpWebpush,
+	"slack":               UserImportOpSlack,
+	"$slack":              UserImportOpSlack,
+	"ms_teams":            UserImportOpMSTeams,
+	"$ms_teams":           UserImportOpMSTeams,
+	"preferred_language":  UserImportOpPreferredLanguage,
+	"$preferred_language": UserImportOpPreferredLanguage,
+	"locale":              UserImportOpLocale,
+	"$locale":             UserImportOpLocale,
+	"properties":          UserImportOpSetProperties,
+	// added by Users.Export, these can't be imported via UserEdit
+	"lists":       UserImportOpIgnore,
+	"preferences": UserImportOpIgnore,
+}
+
+func (m *UserImportMapping) cleanParams() {
+	m.DistinctId = strings.TrimSpace(m.DistinctId)
+	if m.DistinctId == "" {
+		m.DistinctId = "distinct_id"
+	}
+	if m.Unmapped == "" {
+		m.Unmapped = UserImportOpSet
+	}
+}
+
+func (m *UserImportMapping) op(column string) UserImportOp {
+	if op, found := m.Columns[column]; found {
+		return op
+	}
+	if op, found := defaultUserImportColumns[column]; found {
+		return op
+	}
+	if column == m.DistinctId || isReservedUserKey(column) {
+		return UserImportOpIgnore
+	}
+	return m.Unmapped
+}
+
+type UserImportOptions struct {
+	// default: jsonl. ImportFile picks it from file extension (.csv/.jsonl/.ndjson) if not set
+	Format  UserImportFormat
+	Mapping UserImportMapping
+	// users sent per bulk instance. default: client's max identity events in bulk
+	BatchSize int
+	// rows are read, validated and planned (see BulkUsersEdit.Plan), but nothing is sent and no checkpoint is saved
+	DryRun bool
+	/*
+		progress is saved to this file after every batch. If file exists, rows already done
+		as per it are skipped, so that an interrupted import resumes from where it stopped.
+	*/
+	CheckpointFile string
+	// rows which got validation warnings or could not be imported are appended to this file (jsonl), with their row number
+	ReportFile string
+	// called after every batch
+	OnProgress func(UserImportReport)
+}
+
+func (o *UserImportOptions) cleanParams(c *Client) {
+	o.Format = UserImportFormat(strings.ToLower(strings.TrimSpace(string(o.Format))))
+	if o.Format == "" {
+		o.Format = UserImportFormatJSONL
+	}
+	o.Mapping.cleanParams()
+	if o.BatchSize <= 0 || o.BatchSize > c.bulkLimits.MaxIdentityEventsInBulk {
+		o.BatchSize = c.bulkLimits.MaxIdentityEventsInBulk
+	}
+}
+
+type UserImportReport struct {
+	// rows read from source, including skipped ones
+	Total int
+	// rows skipped as they were done before resuming (as per checkpoint file)
+	Skipped int
+	// rows imported (in dry run: rows which would be sent)
+	Success int
+	// rows which could not be parsed, validated or imported
+	Failure int
+	// rows imported with validation warnings (e.g invalid email, which is dropped from user edit)
+	Warnings int
+}
+
+func (r UserImportReport) String() string {
+	return fmt.Sprintf("UserImportReport{Total: %v, Skipped: %v, Success: %v, Failure: %v, Warnings: %v}",
+		r.Total, r.Skipped, r.Success, r.Failure, r.Warnings)
+}
+
+// single line in report file
+type userImportReportLine struct {
+	// line of row in source
+	Row        int      `json:"row"`
+	DistinctId string   `json:"distinct_id,omitempty"`
+	Status     string   `json:"status"`
+	Record     any      `json:"record,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	ErrorType  string   `json:"error_type,omitempty"`
+	StatusCode int      `json:"status_code,omitempty"`
+}
+
+// content of checkpoint file
+type userImportCheckpoint struct {
+	RowsDone  int   `json:"rows_done"`
+	Success   int   `json:"success"`
+	Failure   int   `json:"failure"`
+	Warnings  int   `json:"warnings"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+/*
+ImportFile imports users from a jsonl/csv file, in batches through BulkUsersEdit.
+See Import for details.
+*/
+func (u *usersService) ImportFile(ctx context.Context, path string, opts *UserImportOptions) (*UserImportReport, error) {
+	var o UserImportOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			o.Format = UserImportFormatCSV
+		default:
+			o.Format = UserImportFormatJSONL
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	defer f.Close()
+	return u.Import(ctx, f, &o)
+}
+
+/*
+Import reads users from r and imports them through BulkUsersEdit, one batch at a time, so memory use
+doesn't grow with size of source. Each row becomes a UserEdit as per UserImportOptions.Mapping.
+Rows with validation warnings, and rows which can't be parsed or imported, are written to report file.
+Progress is checkpointed after each batch (see UserImportOptions).
+Returns error only if import can't continue (e.g source/checkpoint file can't be read, ctx is done),
+along with the report till then.
+*/
+func (u *usersService) Import(ctx context.Context, r io.Reader, opts *UserImportOptions) (*UserImportReport, error) {
+	var o UserImportOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.cleanParams(u.client)
+	im := &userImport{service: u, opts: &o, report: &UserImportReport{}}
+	err := im.run(ctx, r)
+	return im.report, err
+}
+
+type userImport struct {
+	service    *usersService
+	opts       *UserImportOptions
+	report     *UserImportReport
+	checkpoint userImportCheckpoint
+	reportFile *os.File
+}
+
+func (im *userImport) run(ctx context.Context, r io.Reader) error {
+	if err := im.loadCheckpoint(); err != nil {
+		return err
+	}
+	if im.opts.ReportFile != "" {
+		f, err := os.OpenFile(im.opts.ReportFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return &Error{Err: err}
+		}
+		defer f.Close()
+		im.reportFile = f
+	}
+	var next func() (*sourceRecord, error)
+	switch im.opts.Format {
+	case UserImportFormatJSONL:
+		next = jsonlRecordReader(r)
+	case UserImportFormatCSV:
+		nextRow, err := csvRowReader(r)
+		if err != nil {
+			return err
+		}
+		next = csvUserRecordReader(nextRow)
+	default:
+		return &Error{Message: fmt.Sprintf("import: unsupported format %s", im.opts.Format)}
+	}
+	batch := make([]*sourceRecord, 0, im.opts.BatchSize)
+	for {
+		// checked for every row, as skipping rows done before resuming doesn't make any api call
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rec, err := next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &Error{Err: err}
+		}
+		if rec != nil {
+			im.report.Total++
+			if im.report.Total <= im.checkpoint.RowsDone {
+				im.report.Skipped++
+				continue
+			}
+			batch = append(batch, rec)
+		}
+		if len(batch) == im.opts.BatchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			if ierr := im.importBatch(ctx, batch); ierr != nil {
+				return ierr
+			}
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// csv rows as records, with column values as strings. columns are ordered as in header
+func csvUserRecordReader(nextRow func() (*csvRow, error)) func() (*sourceRecord, error) {
+	return func() (*sourceRecord, error) {
+		row, err := nextRow()
+		if err != nil {
+			return nil, err
+		}
+		rec := &sourceRecord{line: row.line, err: row.err}
+		if row.values == nil {
+			rec.raw = row.raw
+			return rec, nil
+		}
+		rec.raw = row.values
+		rec.fields = make(map[string]any, len(row.values))
+		for col, value := range row.values {
+			rec.fields[col] = value
+		}
+		return rec, nil
+	}
+}
+
+func (im *userImport) importBatch(ctx context.Context, batch []*sourceRecord) error {
+	bulkIns := im.service.GetBulkEditInstance()
+	// index in bulk instance -> record
+	sent := []*sourceRecord{}
+	for _, rec := range batch {
+		distinctId := stringField(rec.fields[im.opts.Mapping.DistinctId])
+		ue, err := im.userEdit(rec)
+		if err == nil {
+			err = rec.err
+		}
+		if err != nil {
+			im.fail(userImportReportLine{Row: rec.line, DistinctId: distinctId, Record: rec.raw, Error: err.Error(),
+				ErrorType: BULK_RECORD_ERROR_TYPE_VALIDATION})
+			continue
+		}
+		if warnings := userEditWarnings(ue); len(warnings) > 0 {
+			im.report.Warnings++
+			im.writeReportLine(userImportReportLine{Row: rec.line, DistinctId: distinctId, Status: "warning",
+				Warnings: warnings})
+		}
+		bulkIns.Append(ue)
+		sent = append(sent, rec)
+	}
+	if len(sent) > 0 {
+		var results []BulkRecordResult
+		if im.opts.DryRun {
+			plan := bulkIns.Plan()
+			results = plan.InvalidRecords
+			im.report.Success += len(sent) - len(results)
+		} else {
+			// ctx may be done while batch was being read/validated; batch isn't sent then, so it's resent on resume
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			bulkResponse, err := bulkIns.Save()
+			if err != nil {
+				return err
+			}
+			results = bulkResponse.Results
+		}
+		for _, res := range results {
+			if res.Status == "success" {
+				im.report.Success++
+				continue
+			}
+			rec := sent[res.Index]
+			im.fail(userImportReportLine{Row: rec.line, DistinctId: stringField(rec.fields[im.opts.Mapping.DistinctId]),
+				Record: rec.raw, Error: res.Error, ErrorType: res.ErrorType, StatusCode: res.StatusCode})
+		}
+	}
+	return im.saveCheckpoint()
+}
+
+// converts record to user edit as per column mapping
+func (im *userImport) userEdit(rec *sourceRecord) (*userEdit, error) {
+	if rec.fields == nil {
+		return nil, rec.err
+	}
+	mapping := &im.opts.Mapping
+	distinctId := strings.TrimSpace(stringField(rec.fields[mapping.DistinctId]))
+	if distinctId == "" {
+		return nil, fmt.Errorf("%s is missing", mapping.DistinctId)
+	}
+	ue := im.service.GetEditInstance(distinctId).(*userEdit)
+	// sorted, so that operations are in same order on every run
+	for _, col := range slices.Sorted(maps.Keys(rec.fields)) {
+		value := rec.fields[col]
+		if isEmptyImportValue(value) {
+			continue
+		}
+		op := mapping.op(col)
+		propKey := strings.TrimPrefix(col, "properties.")
+		switch op {
+		case UserImportOpIgnore:
+		case UserImportOpSet:
+			ue.SetKV(propKey, importPropertyValue(value))
+		case UserImportOpSetOnce:
+			ue.SetOnceKV(propKey, importPropertyValue(value))
+		case UserImportOpAppend:
+			ue.AppendKV(propKey, importPropertyValue(value))
+		case UserImportOpSetProperties:
+			props, err := importPropertiesValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", col, err)
+			}
+			if len(props) > 0 {
+				ue.Set(props)
+			}
+		case UserImportOpEmail:
+			for _, v := range importChannelValues(value) {
+				ue.AddEmail(v)
+			}
+		case UserImportOpSms:
+			for _, v := range importChannelValues(value) {
+				ue.AddSms(v)
+			}
+		case UserImportOpWhatsapp:
+			for _, v := range importChannelValues(value) {
+				ue.AddWhatsapp(v)
+			}
+		case UserImportOpAndroidpush:
+			for _, t := range importPushTokens(value, "fcm") {
+				ue.AddAndroidpush(t.Value, t.Provider)
+			}
+		case UserImportOpIospush:
+			for _, t := range importPushTokens(value, "apns") {
+				ue.AddIospush(t.Value, t.Provider)
+			}
+		case UserImportOpWebpush, UserImportOpSlack, UserImportOpMSTeams:
+			channels, err := importObjectChannels(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", col, err)
+			}
+			for _, ch := range channels {
+				switch op {
+				case UserImportOpWebpush:
+					provider := ch.Provider
+					if provider == "" {
+						provider = "vapid"
+					}
+					ue.AddWebpush(ch.Value, provider)
+				case UserImportOpSlack:
+					ue.AddSlack(ch.Value)
+				default:
+					ue.AddMSTeams(ch.Value)
+				}
+			}
+		case UserImportOpTimezone:
+			ue.SetTimezone(stringField(value))
+		case UserImportOpPreferredLanguage:
+			ue.SetPreferredLanguage(stringField(value))
+		case UserImportOpLocale:
+			ue.SetLocale(stringField(value))
+		default:
+			return nil, fmt.Errorf("%s: unknown import operation %s", col, op)
+		}
+	}
+	return ue, nil
+}
+
+// same messages as validateBody, without logging them
+func userEditWarnings(ue *userEdit) []string {
+	warnings := append([]string{}, ue._infos...)
+	return append(warnings, ue._errors...)
+}
+
+func isEmptyImportValue(v any) bool {
+	switch tv := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(tv) == ""
+	}
+	return false
+}
+
+func importPropertyValue(v any) any {
+	nv, _ := normalizePropertyValue(v)
+	return nv
+}
+
+// json object, either decoded (jsonl) or as string (csv)
+func importPropertiesValue(v any) (map[string]any, error) {
+	switch tv := v.(type) {
+	case map[string]any:
+		return normalizeEventProperties(tv), nil
+	case string:
+		decoder := json.NewDecoder(strings.NewReader(tv))
+		decoder.UseNumber()
+		props := map[string]any{}
+		if err := decoder.Decode(&props); err != nil {
+			return nil, fmt.Errorf("invalid json object: %v", err)
+		}
+		return normalizeEventProperties(props), nil
+	}
+	return nil, errors.New("must be a json object")
+}
+
+// values of a channel column: ";" separated string, or list of strings/objects with "value"
+func importChannelValues(v any) []string {
+	values := []string{}
+	switch tv := v.(type) {
+	case string:
+		for _, s := range strings.Split(tv, ";") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	case []any:
+		for _, item := range tv {
+			if m, ok := item.(map[string]any); ok {
+				item = m["value"]
+			}
+			if s := strings.TrimSpace(stringField(item)); s != "" {
+				values = append(values, s)
+			}
+		}
+	default:
+		values = append(values, stringField(v))
+	}
+	return values
+}
+
+// push tokens of a channel column: as importChannelValues, with provider of objects (if any)
+func importPushTokens(v any, defaultProvider string) []UserPushToken {
+	tokens := []UserPushToken{}
+	if items, ok := v.([]any); ok {
+		for _, item := range items {
+			provider := ""
+			if m, ok := item.(map[string]any); ok {
+				item, provider = m["value"], strings.TrimSpace(stringField(m["provider"]))
+			}
+			if s := strings.TrimSpace(stringField(item)); s != "" {
+				tokens = append(tokens, UserPushToken{Value: s, Provider: provider})
+			}
+		}
+	} else {
+		for _, s := range importChannelValues(v) {
+			tokens = append(tokens, UserPushToken{Value: s})
+		}
+	}
+	for i := range tokens {
+		if tokens[i].Provider == "" {
+			tokens[i].Provider = defaultProvider
+		}
+	}
+	return tokens
+}
+
+// values of a json object channel column, either decoded (jsonl) or as json string (csv)
+func importObjectChannels(v any) ([]UserObjectChannel, error) {
+	if s, ok := v.(string); ok {
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid json: %v", err)
+		}
+	}
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+	channels := []UserObjectChannel{}
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, errors.New("must be a json object or a list of json objects")
+		}
+		if value, ok := m["value"].(map[string]any); ok {
+			channels = append(channels, UserObjectChannel{Value: value, Provider: strings.TrimSpace(stringField(m["provider"]))})
+			continue
+		}
+		channels = append(channels, UserObjectChannel{Value: m})
+	}
+	return channels, nil
+}
+
+func (im *userImport) fail(line userImportReportLine) {
+	im.report.Failure++
+	line.Status = "error"
+	im.writeReportLine(line)
+}
+
+func (im *userImport) writeReportLine(line userImportReportLine) {
+	if im.reportFile == nil {
+		return
+	}
+	content, err := json.Marshal(line)
+	if err != nil {
+		log.Printf("WARNING: import: error while encoding report of row %d: %v", line.Row, err)
+		return
+	}
+	if _, err = im.reportFile.Write(append(content, '\n')); err != nil {
+		log.Printf("WARNING: import: error while writing report of row %d: %v", line.Row, err)
+	}
+}
+
+func (im *userImport) loadCheckpoint() error {
+	if im.opts.CheckpointFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(im.opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return &Error{Err: err}
+	}
+	if err = json.Unmarshal(content, &im.checkpoint); err != nil {
+		return &Error{Message: fmt.Sprintf("import: invalid checkpoint file %s: %v", im.opts.CheckpointFile, err)}
+	}
+	// counts of earlier run(s) are carried over, so that report covers whole import
+	im.report.Success, im.report.Failure, im.report.Warnings = im.checkpoint.Success, im.checkpoint.Failure, im.checkpoint.Warnings
+	return nil
+}
+
+func (im *userImport) saveCheckpoint() error {
+	im.checkpoint = userImportCheckpoint{
+		RowsDone:  im.report.Total,
+		Success:   im.report.Success,
+		Failure:   im.report.Failure,
+		Warnings:  im.report.Warnings,
+		UpdatedAt: time.Now().UnixMilli(),
+	}
+	if im.opts.OnProgress != nil {
+		im.opts.OnProgress(*im.report)
+	}
+	// nothing is imported in dry run, so there's nothing to resume from
+	if im.opts.CheckpointFile == "" || im.opts.DryRun {
+		return nil
+	}
+	if im.reportFile != nil {
+		// report of a batch must be durable before batch is marked done
+		if err := im.reportFile.Sync(); err != nil {
+			return &Error{Err: err}
+		}
+	}
+	content, err := json.Marshal(im.checkpoint)
+	if err != nil {
+		return &Error{Err: err}
+	}
+	return writeFileAtomic(im.opts.CheckpointFile, content)
+}
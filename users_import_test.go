@@ -0,0 +1,290 @@
+package suprsend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// user edits sent to bulk user api across all requests, in order, as distinct_id -> $user_operations json
+func sentUserEdits(t *testing.T, ts *testServer) ([]string, map[string]string) {
+	t.Helper()
+	ids := []string{}
+	ops := map[string]string{}
+	for _, req := range ts.requestsTo("/event/") {
+		for _, rec := range req.jsonBody(t).([]any) {
+			m := rec.(map[string]any)
+			id := m["distinct_id"].(string)
+			ids = append(ids, id)
+			content, _ := json.Marshal(m["$user_operations"])
+			ops[id] = string(content)
+		}
+	}
+	return ids, ops
+}
+
+func TestUserImportCSV(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	src := "user_id,email,$timezone,properties,plan,tags,signup,created_at,notes\n" +
+		`u1,a@example.com; b@example.com,Asia/Kolkata,"{""city"": ""Pune"", ""age"": 30}",pro,vip,2024-05-01,2024-01-01T00:00:00Z,skip me` + "\n" +
+		"u2,,,,free,,,,\n"
+	report, err := c.Users.Import(context.Background(), strings.NewReader(src), &UserImportOptions{
+		Format: UserImportFormatCSV,
+		Mapping: UserImportMapping{
+			DistinctId: "user_id",
+			Columns: map[string]UserImportOp{
+				"tags": UserImportOpAppend, "signup": UserImportOpSetOnce, "notes": UserImportOpIgnore,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserImportReport{Total: 2, Success: 2}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	ids, ops := sentUserEdits(t, ts)
+	if !reflect.DeepEqual(ids, []string{"u1", "u2"}) {
+		t.Fatalf("sent = %v", ids)
+	}
+	for _, want := range []string{
+		`"$email":"a@example.com"`, `"$email":"b@example.com"`, `"$timezone":"Asia/Kolkata"`,
+		`"city":"Pune"`, `"age":30`, `"plan":"pro"`, `"$append":{"tags":"vip"}`, `"$set_once":{"signup":"2024-05-01"}`,
+	} {
+		if !strings.Contains(ops["u1"], want) {
+			t.Errorf("operations of u1 = %s, want %s", ops["u1"], want)
+		}
+	}
+	for _, notWant := range []string{"created_at", "notes", "skip me", "user_id"} {
+		if strings.Contains(ops["u1"], notWant) {
+			t.Errorf("operations of u1 = %s, must not have %s", ops["u1"], notWant)
+		}
+	}
+	// empty cells are not imported
+	if ops["u2"] != `[{"$set":{"plan":"free"}}]` {
+		t.Errorf("operations of u2 = %s", ops["u2"])
+	}
+}
+
+func TestUserImportJSONLFromExport(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	// line as written by Users.Export
+	src := `{"distinct_id": "u1", "$email": [{"value": "a@example.com", "status": "active"}], "$sms": ["+911234567890"],` +
+		` "$preferred_language": "en", "properties": {"plan": "pro"}, "ss_internal": 1, "updated_at": "2024-01-01T00:00:00Z"}`
+	path := filepath.Join(t.TempDir(), "users.ndjson")
+	os.WriteFile(path, []byte(src), 0o644)
+	if _, err := c.Users.ImportFile(context.Background(), path, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, ops := sentUserEdits(t, ts)
+	for _, want := range []string{`"$email":"a@example.com"`, `"$sms":"+911234567890"`, `"$preferred_language":"en"`, `"plan":"pro"`} {
+		if !strings.Contains(ops["u1"], want) {
+			t.Errorf("operations = %s, want %s", ops["u1"], want)
+		}
+	}
+	if strings.Contains(ops["u1"], "ss_internal") || strings.Contains(ops["u1"], "updated_at") {
+		t.Errorf("operations = %s, reserved keys must be ignored", ops["u1"])
+	}
+}
+
+func TestUserImportReportFile(t *testing.T) {
+	// bulk user api accepts or rejects a chunk as a whole
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if strings.Contains(string(body), `"u3"`) {
+			writeJson(w, 400, map[string]any{"code": 400, "message": "bad user"})
+			return
+		}
+		acceptAllBulkHandler(w, r, body)
+	})
+	c := newTestClient(t, ts.URL)
+	reportFile := filepath.Join(t.TempDir(), "report.jsonl")
+	src := strings.Join([]string{
+		`{"distinct_id": "u1", "plan": "pro"}`,
+		`{"plan": "free"}`,
+		`{broken`,
+		`{"distinct_id": "u3", "plan": "pro"}`,
+		`{"distinct_id": "u4", "properties": {" ": "x", "plan": "pro"}}`,
+		`{"distinct_id": "u5", "properties": "oops"}`,
+	}, "\n")
+	report, err := c.Users.Import(context.Background(), strings.NewReader(src), &UserImportOptions{BatchSize: 2, ReportFile: reportFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserImportReport{Total: 6, Success: 2, Failure: 4, Warnings: 1}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	type line struct {
+		row        string
+		status     string
+		errorType  string
+		statusCode string
+	}
+	got := []line{}
+	for _, l := range readJsonLines(t, reportFile) {
+		got = append(got, line{l["row"].(json.Number).String(), l["status"].(string),
+			stringField(l["error_type"]), stringField(l["status_code"])})
+	}
+	want := []line{
+		{"2", "error", BULK_RECORD_ERROR_TYPE_VALIDATION, ""},
+		{"3", "error", BULK_RECORD_ERROR_TYPE_VALIDATION, ""},
+		{"4", "error", "", "400"},
+		{"5", "warning", "", ""},
+		{"6", "error", BULK_RECORD_ERROR_TYPE_VALIDATION, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report lines = %v, want %v", got, want)
+	}
+}
+
+func TestUserImportDryRun(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	checkpointFile := filepath.Join(t.TempDir(), "import.json")
+	src := "distinct_id,plan\nu1,pro\n,free\nu3,free\n"
+	report, err := c.Users.Import(context.Background(), strings.NewReader(src), &UserImportOptions{
+		Format: UserImportFormatCSV, DryRun: true, CheckpointFile: checkpointFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserImportReport{Total: 3, Success: 2, Failure: 1}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	if n := len(ts.Requests()); n != 0 {
+		t.Errorf("requests = %d, want none in dry run", n)
+	}
+	if _, err = os.Stat(checkpointFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint file must not be written in dry run: %v", err)
+	}
+}
+
+func TestUserImportResumesFromCheckpoint(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	checkpointFile := filepath.Join(t.TempDir(), "import.json")
+	lines := []string{}
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, `{"distinct_id": "u`+itoa(i)+`", "plan": "pro"}`)
+	}
+	src := strings.Join(lines, "\n")
+	opts := &UserImportOptions{BatchSize: 2, CheckpointFile: checkpointFile}
+
+	// first run is interrupted after first batch
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.OnProgress = func(UserImportReport) { cancel() }
+	report, err := c.Users.Import(ctx, strings.NewReader(src), opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if report.Success != 2 {
+		t.Errorf("report = %v", report)
+	}
+
+	// second run skips rows done as per checkpoint, and carries over counts
+	opts.OnProgress = nil
+	report, err = c.Users.Import(context.Background(), strings.NewReader(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserImportReport{Total: 5, Skipped: 2, Success: 5}); *report != want {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	ids, _ := sentUserEdits(t, ts)
+	if !reflect.DeepEqual(ids, []string{"u1", "u2", "u3", "u4", "u5"}) {
+		t.Errorf("sent = %v, want each user once", ids)
+	}
+}
+
+func TestUserImportStopsWhenContextDone(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := c.Users.Import(ctx, strings.NewReader(`{"distinct_id": "u1", "plan": "pro"}`), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if report.Total != 0 || len(ts.Requests()) != 0 {
+		t.Errorf("report = %v, requests = %d, want nothing read or sent", report, len(ts.Requests()))
+	}
+	// batch is not saved if ctx is done while it's being read
+	o := UserImportOptions{}
+	o.cleanParams(c)
+	im := &userImport{service: c.Users, opts: &o, report: &UserImportReport{}}
+	batch := []*sourceRecord{{line: 1, fields: map[string]any{"distinct_id": "u1", "plan": "pro"}}}
+	err = im.importBatch(ctx, batch)
+	if !errors.Is(err, context.Canceled) || im.report.Success != 0 || len(ts.Requests()) != 0 {
+		t.Errorf("report = %v, err = %v, requests = %d", im.report, err, len(ts.Requests()))
+	}
+}
+
+func TestUserImportRoundTripFromExport(t *testing.T) {
+	userJson := `{"distinct_id": "u1", "$email": [{"value": "a@example.com", "status": "active"}],
+		"$androidpush": [{"value": "tok-a", "provider": "fcm"}], "$iospush": [{"value": "tok-i", "provider": "apns"}],
+		"$webpush": [{"value": {"endpoint": "https://push.example.com/1", "keys": {"auth": "x"}}, "provider": "vapid"}],
+		"$slack": [{"value": {"email": "a@example.com", "access_token": "xoxb"}}],
+		"$ms_teams": [{"value": {"tenant_id": "t1", "user_id": "m1"}}],
+		"$preferred_language": "en", "$timezone": "Asia/Kolkata", "$locale": "en_IN",
+		"properties": {"plan": "pro"}, "created_at": "2024-05-01T10:30:00Z"}`
+	exportTs := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/user/":
+			w.Write([]byte(`{"meta": {"has_next": false}, "results": [` + userJson + `]}`))
+		case strings.HasSuffix(r.URL.Path, "/subscribed_to/list/"):
+			w.Write([]byte(`{"meta": {"has_next": false}, "results": [{"list_id": "news"}]}`))
+		default:
+			w.Write([]byte(`{"sections": [], "channel_preferences": [{"channel": "email", "is_restricted": true}]}`))
+		}
+	})
+	exportClient := newTestClient(t, exportTs.URL)
+	want := []string{
+		`{"$email":"a@example.com"}`,
+		`{"$androidpush":"tok-a","$id_provider":"fcm"}`,
+		`{"$id_provider":"apns","$iospush":"tok-i"}`,
+		`{"$id_provider":"vapid","$webpush":{"endpoint":"https://push.example.com/1","keys":{"auth":"x"}}}`,
+		`{"$slack":{"access_token":"xoxb","email":"a@example.com"}}`,
+		`{"$ms_teams":{"tenant_id":"t1","user_id":"m1"}}`,
+		`"$preferred_language":"en"`, `"$timezone":"Asia/Kolkata"`, `"$locale":"en_IN"`, `"plan":"pro"`,
+	}
+	for _, format := range []UserExportFormat{UserExportFormatCSV, UserExportFormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var out bytes.Buffer
+			_, err := exportClient.Users.Export(context.Background(), &out, format,
+				&UserExportOptions{IncludeLists: true, IncludePreferences: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts := newTestServer(t, acceptAllBulkHandler)
+			c := newTestClient(t, ts.URL)
+			report, err := c.Users.Import(context.Background(), &out, &UserImportOptions{Format: UserImportFormat(format)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *report != (UserImportReport{Total: 1, Success: 1}) {
+				t.Errorf("report = %v", report)
+			}
+			_, ops := sentUserEdits(t, ts)
+			for _, w := range want {
+				if !strings.Contains(ops["u1"], w) {
+					t.Errorf("operations = %s, want %s", ops["u1"], w)
+				}
+			}
+			// lists/preferences/timestamps can't be imported, and channels must not end up as properties
+			for _, notWant := range []string{"news", "is_restricted", "created_at", `"androidpush"`, `"slack"`, `"$set":{"email"`} {
+				if strings.Contains(ops["u1"], notWant) {
+					t.Errorf("operations = %s, must not have %s", ops["u1"], notWant)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package suprsend
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -15,104 +16,65 @@ func CurrentTimeFormatted() string {
 	return t.Format(HEADER_DATE_FMT)
 }
 
-func validateWorkflowBodySchema(body map[string]any) (map[string]any, error) {
+func validateWorkflowBodySchema(body map[string]any, mode ValidationMode) (map[string]any, error) {
 	// In case data is not provided, set it to empty dict
 	if d, found := body["data"]; !found || d == nil {
 		body["data"] = map[string]any{}
 	}
-	schema, err := GetSchema("workflow")
-	if err != nil {
-		return body, err
-	}
-	// validate body
-	loadedBody := gojsonschema.NewGoLoader(body)
-	result, err := schema.Validate(loadedBody)
-	if err != nil {
-		return body, &Error{Err: err}
-	}
-	if !result.Valid() {
-		errList := []string{}
-		for _, err := range result.Errors() {
-			errList = append(errList, fmt.Sprintf(" - %v", err))
-		}
-		return body, &Error{Message: fmt.Sprintf("SuprsendValidationError: error in workflow body \n%v", strings.Join(errList, "\n"))}
-	}
-	return body, nil
+	return body, validateBodySchema("workflow", body, mode, "SuprsendValidationError: error in workflow body")
 }
 
-func validateWorkflowTriggerBodySchema(body map[string]any) (map[string]any, error) {
+func validateWorkflowTriggerBodySchema(body map[string]any, mode ValidationMode) (map[string]any, error) {
 	// In case data is not provided, set it to empty dict
 	if d, found := body["data"]; !found || d == nil {
 		body["data"] = map[string]any{}
 	}
-	schema, err := GetSchema("workflow_trigger")
-	if err != nil {
-		return body, err
-	}
-	// validate body
-	loadedBody := gojsonschema.NewGoLoader(body)
-	result, err := schema.Validate(loadedBody)
-	if err != nil {
-		return body, &Error{Err: err}
-	}
-	if !result.Valid() {
-		errList := []string{}
-		for _, err := range result.Errors() {
-			errList = append(errList, fmt.Sprintf(" - %v", err))
-		}
-		return body, &Error{Message: fmt.Sprintf("SuprsendValidationError: error in workflow body \n%v", strings.Join(errList, "\n"))}
-	}
-	return body, nil
+	return body, validateBodySchema("workflow_trigger", body, mode, "SuprsendValidationError: error in workflow body")
 }
 
-func validateTrackEventSchema(body map[string]any) (map[string]any, error) {
+func validateTrackEventSchema(body map[string]any, mode ValidationMode) (map[string]any, error) {
 	// In case props is not provided, set it to empty dict
 	if d, found := body["properties"]; !found || d == nil {
 		body["properties"] = map[string]any{}
 	}
-	schema, err := GetSchema("event")
-	if err != nil {
-		return body, err
-	}
-	// validate body
-	loadedBody := gojsonschema.NewGoLoader(body)
-	result, err := schema.Validate(loadedBody)
-	if err != nil {
-		return body, &Error{Err: err}
-	}
-	if !result.Valid() {
-		errList := []string{}
-		for _, err := range result.Errors() {
-			errList = append(errList, fmt.Sprintf(" - %v", err))
-		}
-		return body, &Error{Message: fmt.Sprintf("SuprsendValidationError: \n%v", strings.Join(errList, "\n"))}
-	}
-	return body, nil
+	return body, validateBodySchema("event", body, mode, "SuprsendValidationError:")
 }
 
-func validateListBroadcastBodySchema(body map[string]any) (map[string]any, error) {
+func validateListBroadcastBodySchema(body map[string]any, mode ValidationMode) (map[string]any, error) {
 	// In case props is not provided, set it to empty dict
 	if d, found := body["data"]; !found || d == nil {
 		body["data"] = map[string]any{}
 	}
-	schema, err := GetSchema("list_broadcast")
-	if err != nil {
-		return body, err
-	}
-	// validate body
-	loadedBody := gojsonschema.NewGoLoader(body)
-	result, err := schema.Validate(loadedBody)
-	if err != nil {
-		return body, &Error{Err: err}
-	}
-	if !result.Valid() {
-		errList := []string{}
+	return body, validateBodySchema("list_broadcast", body, mode, "SuprsendValidationError:")
+}
+
+// validates body against embedded schema, either fully (jsonschema) or only its envelope (fast), as per mode
+func validateBodySchema(schemaName string, body map[string]any, mode ValidationMode, errPrefix string) error {
+	var errList []string
+	switch mode {
+	case ValidationOff:
+		return nil
+	case ValidationFast:
+		errList = fastSchemas[schemaName].validate(body)
+	default:
+		schema, err := GetSchema(schemaName)
+		if err != nil {
+			return err
+		}
+		// validate body
+		loadedBody := gojsonschema.NewGoLoader(body)
+		result, err := schema.Validate(loadedBody)
+		if err != nil {
+			return &Error{Err: err}
+		}
 		for _, err := range result.Errors() {
 			errList = append(errList, fmt.Sprintf(" - %v", err))
 		}
-		return body, &Error{Message: fmt.Sprintf("SuprsendValidationError: \n%v", strings.Join(errList, "\n"))}
 	}
-	return body, nil
+	if len(errList) > 0 {
+		return &Error{Message: fmt.Sprintf("%s \n%v", errPrefix, strings.Join(errList, "\n"))}
+	}
+	return nil
 }
 
 func getAttachmentCountInWorkflowBody(body map[string]any) int {
@@ -127,15 +89,19 @@ func getAttachmentCountInWorkflowBody(body map[string]any) int {
 	return numAttachments
 }
 
-func getApparentWorkflowBodySize(body map[string]any, isPartOfBulk bool) (int, error) {
+/*
+Returns apparent size and, if size was computed on body itself (not on a modified copy),
+its json encoding as well, which is then sent as-is instead of encoding body again.
+*/
+func getApparentWorkflowBodySize(c *Client, body map[string]any, isPartOfBulk bool) (int, encodedJson, error) {
 	extraBytes := WORKFLOW_RUNTIME_KEYS_POTENTIAL_SIZE_IN_BYTES
-	apparentBody := body
+	apparentBody, isCopy := body, false
 	numAttachments := getAttachmentCountInWorkflowBody(body)
 	if numAttachments > 0 {
 		if isPartOfBulk {
 			if ALLOW_ATTACHMENTS_IN_BULK_API {
 				// if attachment is allowed in bulk api, then calculate size based on whether auto Upload is enabled
-				if ATTACHMENT_UPLOAD_ENABLED {
+				if c.attachmentUploadEnabled() {
 					// If auto upload enabled, To calculate size, replace attachment size with equivalent url size
 					extraBytes += numAttachments * ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES
 					// -- remove attachments->data key to calculate data size
@@ -145,7 +111,7 @@ func getApparentWorkflowBodySize(body map[string]any, isPartOfBulk bool) (int, e
 					for _, a := range attachs {
 						delete(a, "data")
 					}
-					apparentBody = bodyCopy
+					apparentBody, isCopy = bodyCopy, true
 
 				} else {
 					// if auto upload is not enabled, attachment data will be passed as it is.
@@ -156,10 +122,10 @@ func getApparentWorkflowBodySize(body map[string]any, isPartOfBulk bool) (int, e
 				copier.CopyWithOption(&bodyCopy, &body, copier.Option{DeepCopy: true})
 
 				delete(bodyCopy["data"].(map[string]any), "$attachments")
-				apparentBody = bodyCopy
+				apparentBody, isCopy = bodyCopy, true
 			}
 		} else {
-			if ATTACHMENT_UPLOAD_ENABLED {
+			if c.attachmentUploadEnabled() {
 				// if auto upload enabled, to calculate size, replace attachment size with equivalent url size
 				extraBytes += numAttachments * ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES
 				// -- remove attachments->data key to calculate data size
@@ -169,7 +135,7 @@ func getApparentWorkflowBodySize(body map[string]any, isPartOfBulk bool) (int, e
 				for _, a := range attachs {
 					delete(a, "data")
 				}
-				apparentBody = bodyCopy
+				apparentBody, isCopy = bodyCopy, true
 
 			} else {
 				// if auto upload is not enabled, attachment data will be passed as it is.
@@ -177,13 +143,16 @@ func getApparentWorkflowBodySize(body map[string]any, isPartOfBulk bool) (int, e
 		}
 	}
 	// ------
-	bodyBytes, err := json.Marshal(apparentBody)
+	bodyBytes, err := c.marshal(apparentBody)
 	if err != nil {
-		return 0, &Error{Err: err}
+		return 0, nil, err
 	}
 	apparentSize := len(bodyBytes) + extraBytes
 	// ------
-	return apparentSize, nil
+	if isCopy {
+		return apparentSize, nil, nil
+	}
+	return apparentSize, bodyBytes, nil
 }
 
 func getAttachmentCountInEventProperties(event map[string]any) int {
@@ -198,15 +167,19 @@ func getAttachmentCountInEventProperties(event map[string]any) int {
 	return numAttachments
 }
 
-func getApparentEventSize(event map[string]any, isPartOfBulk bool) (int, error) {
+/*
+Returns apparent size and, if size was computed on body itself (not on a modified copy),
+its json encoding as well, which is then sent as-is instead of encoding body again.
+*/
+func getApparentEventSize(c *Client, event map[string]any, isPartOfBulk bool) (int, encodedJson, error) {
 	extraBytes := 0
-	apparentBody := event
+	apparentBody, isCopy := event, false
 	numAttachments := getAttachmentCountInEventProperties(event)
 	if numAttachments > 0 {
 		if isPartOfBulk {
 			if ALLOW_ATTACHMENTS_IN_BULK_API {
 				// if attachment is allowed in bulk api, then calculate size based on whether auto Upload is enabled
-				if ATTACHMENT_UPLOAD_ENABLED {
+				if c.attachmentUploadEnabled() {
 					// If auto upload enabled, To calculate size, replace attachment size with equivalent url size
 					extraBytes += numAttachments * ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES
 					// -- remove attachments->data key to calculate data size
@@ -216,7 +189,7 @@ func getApparentEventSize(event map[string]any, isPartOfBulk bool) (int, error)
 					for _, a := range attachs {
 						delete(a, "data")
 					}
-					apparentBody = eventCopy
+					apparentBody, isCopy = eventCopy, true
 				} else {
 					// if auto upload is not enabled, attachment data will be passed as it is.
 				}
@@ -226,10 +199,10 @@ func getApparentEventSize(event map[string]any, isPartOfBulk bool) (int, error)
 				copier.CopyWithOption(&eventCopy, &event, copier.Option{DeepCopy: true})
 
 				delete(eventCopy["properties"].(map[string]any), "$attachments")
-				apparentBody = eventCopy
+				apparentBody, isCopy = eventCopy, true
 			}
 		} else {
-			if ATTACHMENT_UPLOAD_ENABLED {
+			if c.attachmentUploadEnabled() {
 				// if auto upload enabled, to calculate size, replace attachment size with equivalent url size
 				extraBytes += numAttachments * ATTACHMENT_URL_POTENTIAL_SIZE_IN_BYTES
 				// -- remove attachments->data key to calculate data size
@@ -239,20 +212,23 @@ func getApparentEventSize(event map[string]any, isPartOfBulk bool) (int, error)
 				for _, a := range attachs {
 					delete(a, "data")
 				}
-				apparentBody = eventCopy
+				apparentBody, isCopy = eventCopy, true
 			} else {
 				// if auto upload is not enabled, attachment data will be passed as it is.
 			}
 		}
 	}
 	// ------
-	bodyBytes, err := json.Marshal(apparentBody)
+	bodyBytes, err := c.marshal(apparentBody)
 	if err != nil {
-		return 0, &Error{Err: err}
+		return 0, nil, err
 	}
 	apparentSize := len(bodyBytes) + extraBytes
 	// ------
-	return apparentSize, nil
+	if isCopy {
+		return apparentSize, nil, nil
+	}
+	return apparentSize, bodyBytes, nil
 }
 
 func getApparentIdentityEventSize(event map[string]any) (int, error) {
@@ -286,3 +262,15 @@ func appendQueryParamPart(url string, qp string) string {
 		return fmt.Sprintf("%s?%s", url, qp)
 	}
 }
+
+// content is written to a temp file and renamed, so that file is never left half-written (used for checkpoints)
+func writeFileAtomic(path string, content []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return &Error{Err: err}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return &Error{Err: err}
+	}
+	return nil
+}
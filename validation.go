@@ -0,0 +1,310 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ValidationMode decides how request bodies are validated before they are sent. See WithValidation
+type ValidationMode int
+
+const (
+	// validates full body against embedded jsonschema (request_json/). Default
+	ValidationStrict ValidationMode = iota
+	// validates only top-level keys of body (required keys, types, lengths) with hand-written checks.
+	// Nested values (e.g recipient channels) are left to be validated by SuprSend
+	ValidationFast
+	// no client-side schema validation. Size limits are still checked
+	ValidationOff
+)
+
+func (m ValidationMode) String() string {
+	switch m {
+	case ValidationStrict:
+		return "strict"
+	case ValidationFast:
+		return "fast"
+	case ValidationOff:
+		return "off"
+	}
+	return fmt.Sprintf("ValidationMode(%d)", int(m))
+}
+
+var allChannels = []string{
+	"androidpush", "iospush", "webpush", "email", "sms", "whatsapp", "slack", "inbox", "messenger", "ms_teams",
+}
+
+// hand-written equivalents of top-level rules in request_json/*.json, used by ValidationFast
+var fastSchemas = map[string]*fastSchema{
+	"workflow_trigger": {
+		fields: []fastField{
+			{key: "$idempotency_key", check: nullableString(255)},
+			{key: "tenant_id", check: nullableString(64)},
+			{key: "workflow", required: true, check: nonEmptyString(1)},
+			{key: "actor", check: stringOrObject(1)},
+			{key: "recipients", required: true, check: arrayOf(1, stringOrObject(1))},
+			{key: "data", required: true, check: object},
+			{key: "metadata", check: object},
+			{key: "cancellation_key", check: nullableString(255)},
+		},
+		noAdditionalProperties: true,
+	},
+	"workflow": {
+		fields: []fastField{
+			{key: "$idempotency_key", check: nullableString(255)},
+			{key: "tenant_id", check: nullableString(64)},
+			{key: "brand_id", check: nullableString(64)},
+			{key: "name", required: true, check: nonEmptyString(2)},
+			{key: "template", required: true, check: nonEmptyString(2)},
+			{key: "notification_category", required: true, check: nonEmptyString(2)},
+			{key: "delay", check: stringOrNonNegativeInteger},
+			{key: "trigger_at", check: nonEmptyString(2)},
+			{key: "delivery", check: object},
+			{key: "users", required: true, check: arrayOf(1, object)},
+			{key: "data", required: true, check: object},
+		},
+	},
+	"event": {
+		fields: []fastField{
+			{key: "$idempotency_key", check: nullableString(255)},
+			{key: "tenant_id", check: nullableString(64)},
+			{key: "brand_id", check: nullableString(64)},
+			{key: "$insert_id", required: true, check: nonEmptyString(36)},
+			{key: "$time", required: true, check: integerMin(EVENT_MIN_TIMESTAMP_MILLIS)},
+			{key: "event", required: true, check: nonEmptyString(2)},
+			{key: "env", required: true, check: nonEmptyString(20)},
+			{key: "distinct_id", required: true, check: nonEmptyString(1)},
+			{key: "properties", required: true, check: object},
+		},
+	},
+	"list_broadcast": {
+		fields: []fastField{
+			{key: "$idempotency_key", check: nullableString(255)},
+			{key: "tenant_id", check: nullableString(64)},
+			{key: "brand_id", check: nullableString(64)},
+			{key: "$insert_id", required: true, check: nonEmptyString(36)},
+			{key: "$time", required: true, check: integerMin(EVENT_MIN_TIMESTAMP_MILLIS)},
+			{key: "list_id", required: true, check: nonEmptyString(1)},
+			{key: "channels", check: arrayOf(0, enumString(allChannels))},
+			{key: "template", required: true, check: nonEmptyString(2)},
+			{key: "notification_category", required: true, check: nonEmptyString(2)},
+			{key: "delay", check: stringOrNonNegativeInteger},
+			{key: "trigger_at", check: stringType},
+			{key: "data", required: true, check: object},
+		},
+	},
+}
+
+type fastField struct {
+	key      string
+	required bool
+	// returns description of error, empty if value is valid
+	check func(v any) string
+}
+
+type fastSchema struct {
+	fields                 []fastField
+	noAdditionalProperties bool
+}
+
+// returns list of errors, formatted same as errors of jsonschema validation
+func (s *fastSchema) validate(body map[string]any) []string {
+	errList := []string{}
+	for _, f := range s.fields {
+		v, found := body[f.key]
+		if !found {
+			if f.required {
+				errList = append(errList, fmt.Sprintf(" - (root): %s is required", f.key))
+			}
+			continue
+		}
+		if desc := f.check(v); desc != "" {
+			errList = append(errList, fmt.Sprintf(" - %s: %s", f.key, desc))
+		}
+	}
+	if s.noAdditionalProperties {
+		for k := range body {
+			if !slices.ContainsFunc(s.fields, func(f fastField) bool { return f.key == k }) {
+				errList = append(errList, fmt.Sprintf(" - (root): Additional property %s is not allowed", k))
+			}
+		}
+	}
+	return errList
+}
+
+func stringType(v any) string {
+	if _, ok := v.(string); !ok {
+		return fmt.Sprintf("Invalid type. Expected: string, given: %s", jsonTypeName(v))
+	}
+	return ""
+}
+
+func nonEmptyString(minLen int) func(any) string {
+	return func(v any) string {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Sprintf("Invalid type. Expected: string, given: %s", jsonTypeName(v))
+		}
+		if len([]rune(s)) < minLen {
+			return fmt.Sprintf("String length must be greater than or equal to %d", minLen)
+		}
+		return ""
+	}
+}
+
+func nullableString(maxLen int) func(any) string {
+	return func(v any) string {
+		if v == nil {
+			return ""
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Sprintf("Invalid type. Expected: string/null, given: %s", jsonTypeName(v))
+		}
+		if len([]rune(s)) > maxLen {
+			return fmt.Sprintf("String length must be less than or equal to %d", maxLen)
+		}
+		return ""
+	}
+}
+
+func object(v any) string {
+	if _, ok := v.(map[string]any); ok {
+		return ""
+	}
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.Map {
+		return ""
+	}
+	return fmt.Sprintf("Invalid type. Expected: object, given: %s", jsonTypeName(v))
+}
+
+func stringOrObject(minLen int) func(any) string {
+	return func(v any) string {
+		if _, ok := v.(string); ok {
+			return nonEmptyString(minLen)(v)
+		}
+		if object(v) == "" {
+			return ""
+		}
+		return fmt.Sprintf("Invalid type. Expected: string/object, given: %s", jsonTypeName(v))
+	}
+}
+
+func enumString(values []string) func(any) string {
+	return func(v any) string {
+		s, ok := v.(string)
+		if !ok || !slices.Contains(values, s) {
+			return fmt.Sprintf("must be one of the following: %s", strings.Join(values, ", "))
+		}
+		return ""
+	}
+}
+
+func arrayOf(minItems int, itemCheck func(any) string) func(any) string {
+	return func(v any) string {
+		var items []any
+		switch av := v.(type) {
+		case []any:
+			items = av
+		case []string:
+			for _, s := range av {
+				items = append(items, s)
+			}
+		default:
+			rv := reflect.ValueOf(v)
+			if v == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+				return fmt.Sprintf("Invalid type. Expected: array, given: %s", jsonTypeName(v))
+			}
+			for i := 0; i < rv.Len(); i++ {
+				items = append(items, rv.Index(i).Interface())
+			}
+		}
+		if len(items) < minItems {
+			return fmt.Sprintf("Array must have at least %d items", minItems)
+		}
+		for i, item := range items {
+			if desc := itemCheck(item); desc != "" {
+				return fmt.Sprintf("item %d: %s", i, desc)
+			}
+		}
+		return ""
+	}
+}
+
+func integerMin(minimum int64) func(any) string {
+	return func(v any) string {
+		n, ok := asInteger(v)
+		if !ok {
+			return fmt.Sprintf("Invalid type. Expected: integer, given: %s", jsonTypeName(v))
+		}
+		if n < minimum {
+			return fmt.Sprintf("Must be greater than or equal to %d", minimum)
+		}
+		return ""
+	}
+}
+
+func stringOrNonNegativeInteger(v any) string {
+	if _, ok := v.(string); ok {
+		return ""
+	}
+	n, ok := asInteger(v)
+	if !ok {
+		return fmt.Sprintf("Invalid type. Expected: string/integer, given: %s", jsonTypeName(v))
+	}
+	if n < 0 {
+		return "Must be greater than or equal to 0"
+	}
+	return ""
+}
+
+func asInteger(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		if n == math.Trunc(n) {
+			return int64(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64, json.Number:
+		return "number"
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return reflect.TypeOf(v).String()
+}
@@ -0,0 +1,164 @@
+package suprsend
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func validEventBody() map[string]any {
+	return map[string]any{
+		"$insert_id": "0b0b0b0b-0b0b-0b0b-0b0b-0b0b0b0b0b0b", "$time": int64(1700000000000),
+		"event": "order_placed", "env": testWorkspaceKey, "distinct_id": "u1",
+		"properties": map[string]any{"amount": 10},
+	}
+}
+
+func validTriggerBody() map[string]any {
+	return map[string]any{
+		"workflow": "order_placed", "recipients": []any{"u1", map[string]any{"distinct_id": "u2"}},
+		"data": map[string]any{}, "tenant_id": "t1",
+	}
+}
+
+func validBroadcastBody() map[string]any {
+	return map[string]any{
+		"$insert_id": "0b0b0b0b-0b0b-0b0b-0b0b-0b0b0b0b0b0b", "$time": int64(1700000000000),
+		"list_id": "l1", "template": "tmpl", "notification_category": "transactional", "data": map[string]any{},
+		"channels": []any{"email", "sms"}, "delay": "1h",
+	}
+}
+
+// returns copy of body with key set to value, or removed if value is deleteKey
+func withKey(body map[string]any, key string, value any) map[string]any {
+	b := maps.Clone(body)
+	if value == deleteKey {
+		delete(b, key)
+	} else {
+		b[key] = value
+	}
+	return b
+}
+
+var deleteKey = &struct{}{}
+
+// fast schemas must reject same top-level payloads as the jsonschema they are derived from
+func TestFastSchemasMatchJsonSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		body   map[string]any
+		valid  bool
+	}{
+		{"event valid", "event", validEventBody(), true},
+		{"event time as float", "event", withKey(validEventBody(), "$time", 1700000000000.0), true},
+		{"event time as json.Number", "event", withKey(validEventBody(), "$time", json.Number("1700000000000")), true},
+		{"event time before 2022", "event", withKey(validEventBody(), "$time", int64(1600000000000)), false},
+		{"event time as string", "event", withKey(validEventBody(), "$time", "1700000000000"), false},
+		{"event name too short", "event", withKey(validEventBody(), "event", "e"), false},
+		{"event without distinct_id", "event", withKey(validEventBody(), "distinct_id", deleteKey), false},
+		{"event empty distinct_id", "event", withKey(validEventBody(), "distinct_id", ""), false},
+		{"event short env", "event", withKey(validEventBody(), "env", "short"), false},
+		{"event short insert_id", "event", withKey(validEventBody(), "$insert_id", "abc"), false},
+		{"event properties not object", "event", withKey(validEventBody(), "properties", []any{}), false},
+		{"event null tenant", "event", withKey(validEventBody(), "tenant_id", nil), true},
+		{"event long tenant", "event", withKey(validEventBody(), "tenant_id", strings.Repeat("t", 65)), false},
+		{"event long idempotency key", "event", withKey(validEventBody(), "$idempotency_key", strings.Repeat("k", 256)), false},
+		{"event unknown key", "event", withKey(validEventBody(), "extra", 1), true},
+		//
+		{"trigger valid", "workflow_trigger", validTriggerBody(), true},
+		{"trigger string recipients", "workflow_trigger", withKey(validTriggerBody(), "recipients", []string{"u1"}), true},
+		{"trigger no recipients", "workflow_trigger", withKey(validTriggerBody(), "recipients", []any{}), false},
+		{"trigger empty recipient", "workflow_trigger", withKey(validTriggerBody(), "recipients", []any{""}), false},
+		{"trigger numeric recipient", "workflow_trigger", withKey(validTriggerBody(), "recipients", []any{1}), false},
+		{"trigger recipients not array", "workflow_trigger", withKey(validTriggerBody(), "recipients", "u1"), false},
+		{"trigger without workflow", "workflow_trigger", withKey(validTriggerBody(), "workflow", deleteKey), false},
+		{"trigger empty workflow", "workflow_trigger", withKey(validTriggerBody(), "workflow", ""), false},
+		{"trigger actor object", "workflow_trigger", withKey(validTriggerBody(), "actor", map[string]any{"distinct_id": "a"}), true},
+		{"trigger actor number", "workflow_trigger", withKey(validTriggerBody(), "actor", 1), false},
+		{"trigger data not object", "workflow_trigger", withKey(validTriggerBody(), "data", "x"), false},
+		{"trigger metadata not object", "workflow_trigger", withKey(validTriggerBody(), "metadata", 1), false},
+		{"trigger unknown key", "workflow_trigger", withKey(validTriggerBody(), "extra", 1), false},
+		//
+		{"broadcast valid", "list_broadcast", validBroadcastBody(), true},
+		{"broadcast integer delay", "list_broadcast", withKey(validBroadcastBody(), "delay", 60), true},
+		{"broadcast negative delay", "list_broadcast", withKey(validBroadcastBody(), "delay", -1), false},
+		{"broadcast unknown channel", "list_broadcast", withKey(validBroadcastBody(), "channels", []any{"fax"}), false},
+		{"broadcast without list", "list_broadcast", withKey(validBroadcastBody(), "list_id", deleteKey), false},
+		{"broadcast trigger_at number", "list_broadcast", withKey(validBroadcastBody(), "trigger_at", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strictErr := validateBodySchema(tt.schema, tt.body, ValidationStrict, "error")
+			fastErr := validateBodySchema(tt.schema, tt.body, ValidationFast, "error")
+			if (strictErr == nil) != tt.valid {
+				t.Errorf("strict: valid = %v, want %v (%v)", strictErr == nil, tt.valid, strictErr)
+			}
+			if (fastErr == nil) != tt.valid {
+				t.Errorf("fast: valid = %v, want %v (%v)", fastErr == nil, tt.valid, fastErr)
+			}
+		})
+	}
+	if err := validateBodySchema("event", map[string]any{}, ValidationOff, "error"); err != nil {
+		t.Errorf("ValidationOff must not validate: %v", err)
+	}
+}
+
+// server for benchmarks, which (unlike testServer) doesn't keep requests
+func newBenchServer(b *testing.B, handler testHandler) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handler(w, r, body)
+	}))
+	b.Cleanup(ts.Close)
+	return ts
+}
+
+func benchmarkTrackEvent(b *testing.B, mode ValidationMode) {
+	ts := newBenchServer(b, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		writeJson(w, 202, map[string]any{"status": "success", "message_id": "m"})
+	})
+	c := newTestClient(b, ts.URL, WithValidation(mode))
+	props := map[string]any{"amount": 10, "currency": "INR", "items": []any{map[string]any{"sku": "s1", "qty": 2}}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.TrackEvent(testEvent("u1", props)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrackEvent_Strict(b *testing.B) { benchmarkTrackEvent(b, ValidationStrict) }
+func BenchmarkTrackEvent_Fast(b *testing.B)   { benchmarkTrackEvent(b, ValidationFast) }
+func BenchmarkTrackEvent_Off(b *testing.B)    { benchmarkTrackEvent(b, ValidationOff) }
+
+func benchmarkBulkEventsAppendSave(b *testing.B, mode ValidationMode) {
+	ts := newBenchServer(b, acceptAllBulkHandler)
+	c := newTestClient(b, ts.URL, WithValidation(mode))
+	props := map[string]any{"amount": 10, "currency": "INR"}
+	events := make([]*Event, 10000)
+	for i := range events {
+		events[i] = testEvent("u"+itoa(i), props)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bulkIns := c.BulkEvents.NewInstance()
+		bulkIns.Append(events...)
+		resp, _ := bulkIns.Trigger()
+		if resp.Success != len(events) {
+			b.Fatalf("unexpected response %v", resp)
+		}
+	}
+}
+
+// 10k events per iteration
+func BenchmarkBulkEventsAppendSave(b *testing.B) { benchmarkBulkEventsAppendSave(b, ValidationStrict) }
+func BenchmarkBulkEventsAppendSave_Fast(b *testing.B) {
+	benchmarkBulkEventsAppendSave(b, ValidationFast)
+}
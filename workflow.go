@@ -1,8 +1,8 @@
 package suprsend
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/jinzhu/copier"
@@ -51,13 +51,13 @@ func (w *Workflow) getFinalJson(client *Client, isPartOfBulk bool) (map[string]a
 	if w.BrandId != "" {
 		w.Body["brand_id"] = w.BrandId
 	}
-	body, err := validateWorkflowBodySchema(w.Body)
+	body, err := validateWorkflowBodySchema(w.Body, client.validationMode)
 	if err != nil {
 		return nil, 0, err
 	}
 	w.Body = body
 	// Check request size
-	apparentSize, err := getApparentWorkflowBodySize(body, isPartOfBulk)
+	apparentSize, _, err := getApparentWorkflowBodySize(client, body, isPartOfBulk)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -106,6 +106,10 @@ func (w *workflowTrigger) Trigger(workflow *Workflow) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	wfBody, _, err = w.client.uploadAttachmentsInBody(context.Background(), wfBody, "data", nil)
+	if err != nil {
+		return nil, err
+	}
 	suprResp, err := w.send(wfBody)
 	if err != nil {
 		return nil, err
@@ -119,7 +123,7 @@ func (w *workflowTrigger) send(wfBody map[string]any) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := w.client.httpClient.Do(request)
+	httpResponse, err := w.client.send(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -132,7 +136,7 @@ func (w *workflowTrigger) send(wfBody map[string]any) (*Response, error) {
 }
 
 func (w *workflowTrigger) formatAPIResponse(httpRes *http.Response) (*Response, error) {
-	respBody, err := io.ReadAll(httpRes.Body)
+	respBody, err := readResponseBody(httpRes)
 	if err != nil {
 		return nil, &Error{Err: err}
 	}
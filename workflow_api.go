@@ -5,7 +5,7 @@ import (
 )
 
 type WorkflowsService interface {
-	Trigger(*WorkflowTriggerRequest) (*Response, error)
+	Trigger(*WorkflowTriggerRequest, ...RequestOption) (*Response, error)
 	BulkTriggerInstance() BulkWorkflowsTrigger
 }
 
@@ -22,18 +22,35 @@ func newWorkflowService(client *Client) *workflowsService {
 	return ws
 }
 
-func (w *workflowsService) Trigger(workflow *WorkflowTriggerRequest) (*Response, error) {
-	wfBody, _, err := workflow.getFinalJson(w.client, false)
+func (w *workflowsService) Trigger(workflow *WorkflowTriggerRequest, reqOpts ...RequestOption) (*Response, error) {
+	wfBody, _, encoded, err := workflow.getFinalJson(w.client, false)
 	if err != nil {
 		return nil, err
 	}
+	// skip if same workflow has already been triggered
+	dedupKey := w.client.dedupKey(dedupKindWorkflow, wfBody)
+	if dedupKey != "" {
+		if cachedEntry, found := w.client.dedupCache.Get(dedupKey); found {
+			return cachedEntry.response(), nil
+		}
+	}
 	url := fmt.Sprintf("%strigger/", w.client.baseUrl)
+	ctx := newRequestOptions(reqOpts).callContext()
 	// prepare http.Request object
-	request, err := w.client.prepareHttpRequest("POST", url, wfBody)
+	var reqBody any = wfBody
+	if encoded != nil {
+		reqBody = encoded
+	}
+	if uploadedBody, uploaded, err := w.client.uploadAttachmentsInBody(ctx, wfBody, "data", nil); err != nil {
+		return nil, err
+	} else if uploaded {
+		reqBody = uploadedBody
+	}
+	request, err := w.client.prepareHttpRequest("POST", url, reqBody, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, err := w.client.httpClient.Do(request)
+	httpResponse, err := w.client.send(ctx, request, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -42,12 +59,12 @@ func (w *workflowsService) Trigger(workflow *WorkflowTriggerRequest) (*Response,
 	if err != nil {
 		return nil, err
 	}
+	if dedupKey != "" {
+		w.client.dedupCache.Set(dedupKey, dedupEntryOfResponse(suprResponse))
+	}
 	return suprResponse, nil
 }
 
 func (w *workflowsService) BulkTriggerInstance() BulkWorkflowsTrigger {
-	return &bulkWorkflowsTrigger{
-		client:   w.client,
-		response: &BulkResponse{},
-	}
+	return newBulkWorkflowsTrigger(w.client)
 }
@@ -2,6 +2,8 @@ package suprsend
 
 import (
 	"fmt"
+	"io"
+	"maps"
 
 	"github.com/jinzhu/copier"
 )
@@ -14,30 +16,55 @@ type WorkflowTriggerRequest struct {
 }
 
 func (w *WorkflowTriggerRequest) AddAttachment(filePath string, ao *AttachmentOption) error {
-	if d, found := w.Body["data"]; !found || d == nil {
-		w.Body["data"] = map[string]any{}
-	}
 	attachment, err := GetAttachmentJson(filePath, ao)
 	if err != nil {
 		return err
 	}
-	if attachment == nil {
-		return nil
+	return w.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentReader adds attachment with content read from r. contentType is detected if empty.
+func (w *WorkflowTriggerRequest) AddAttachmentReader(fileName string, contentType string, r io.Reader, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromReader(fileName, contentType, r, ao)
+	if err != nil {
+		return err
+	}
+	return w.addAttachmentJson(attachment, ao)
+}
+
+// AddAttachmentBytes adds attachment with in-memory content. contentType is detected if empty.
+func (w *WorkflowTriggerRequest) AddAttachmentBytes(fileName string, contentType string, content []byte, ao *AttachmentOption) error {
+	attachment, err := GetAttachmentJsonFromBytes(fileName, contentType, content, ao)
+	if err != nil {
+		return err
+	}
+	return w.addAttachmentJson(attachment, ao)
+}
+
+// adds attachment to data->$attachments. nil attachment (ignored due to error) is skipped
+func (w *WorkflowTriggerRequest) addAttachmentJson(attachment map[string]any, ao *AttachmentOption) error {
+	if d, found := w.Body["data"]; !found || d == nil {
+		w.Body["data"] = map[string]any{}
 	}
 	data := w.Body["data"].(map[string]any)
-	if a, found := data["$attachments"]; !found || a == nil {
-		data["$attachments"] = []map[string]any{}
+	allAttachments, _ := data["$attachments"].([]map[string]any)
+	attachment, err := applyAttachmentOptionPolicy(attachment, allAttachments, ao)
+	if err != nil || attachment == nil {
+		return err
 	}
-	allAttachments := data["$attachments"].([]map[string]any)
-	allAttachments = append(allAttachments, attachment)
-	data["$attachments"] = allAttachments
+	data["$attachments"] = append(allAttachments, attachment)
 	return nil
 }
 
-func (w *WorkflowTriggerRequest) getFinalJson(client *Client, isPartOfBulk bool) (map[string]any, int, error) {
+// returns final body, its apparent size and its json encoding (nil if it couldn't be reused as request body)
+func (w *WorkflowTriggerRequest) getFinalJson(client *Client, isPartOfBulk bool) (map[string]any, int, encodedJson, error) {
 	// Add idempotency_key if present
+	autoKey := ""
 	if w.IdempotencyKey != "" {
 		w.Body["$idempotency_key"] = w.IdempotencyKey
+	} else if k, _ := w.Body["$idempotency_key"].(string); k == "" {
+		// derived key is set only on final body, as it must change when caller reuses request with other data
+		autoKey = client.autoIdempotencyKey(w.GenerateIdempotencyKey)
 	}
 	// Add tenant_id if present
 	if w.TenantId != "" {
@@ -46,22 +73,46 @@ func (w *WorkflowTriggerRequest) getFinalJson(client *Client, isPartOfBulk bool)
 	if w.CancellationKey != "" {
 		w.Body["cancellation_key"] = w.CancellationKey
 	}
-	body, err := validateWorkflowTriggerBodySchema(w.Body)
+	body, err := validateWorkflowTriggerBodySchema(w.Body, client.validationMode)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	// check attachments against client's attachment policy
+	body, err = client.applyAttachmentPolicy(body, "data")
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	w.Body = body
+	if autoKey != "" {
+		body = maps.Clone(body)
+		body["$idempotency_key"] = autoKey
+	}
+	// validate data against schema registered for workflow (if any)
+	err = client.validateWorkflowData(body)
+	if err != nil {
+		return nil, 0, nil, err
+	}
 	// Check request size
-	apparentSize, err := getApparentWorkflowBodySize(body, isPartOfBulk)
+	apparentSize, encoded, err := getApparentWorkflowBodySize(client, body, isPartOfBulk)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	if apparentSize > BODY_MAX_APPARENT_SIZE_IN_BYTES {
 		errStr := fmt.Sprintf("workflow body too big - %d Bytes, must not cross %s", apparentSize,
 			BODY_MAX_APPARENT_SIZE_IN_BYTES_READABLE)
-		return nil, 0, &Error{Code: 413, Message: errStr}
+		return nil, 0, nil, &Error{Code: 413, Message: errStr}
 	}
-	return w.Body, apparentSize, nil
+	return body, apparentSize, encoded, nil
+}
+
+/*
+EstimateSize returns apparent size (in bytes) of request, as checked against BODY_MAX_APPARENT_SIZE_IN_BYTES
+before it's sent. Request is validated as well. Request itself is not modified.
+*/
+func (w *WorkflowTriggerRequest) EstimateSize(client *Client) (int, error) {
+	wCopy := &WorkflowTriggerRequest{Body: w.asJson()}
+	_, apparentSize, _, err := wCopy.getFinalJson(client, false)
+	return apparentSize, err
 }
 
 func (w *WorkflowTriggerRequest) asJson() map[string]any {
@@ -0,0 +1,142 @@
+package suprsend
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Field level error in workflow data, as reported by registered workflow schema
+type FieldError struct {
+	// path of field in data e.g "order.id"
+	Field string
+	// type of failed check e.g required, invalid_type
+	Type    string
+	Message string
+}
+
+// Returned when data of a workflow trigger doesn't match schema registered for its workflow slug
+type WorkflowDataValidationError struct {
+	Workflow string
+	Errors   []FieldError
+}
+
+func (e *WorkflowDataValidationError) Error() string {
+	errList := []string{}
+	for _, fe := range e.Errors {
+		errList = append(errList, fmt.Sprintf(" - %s: %s", fe.Field, fe.Message))
+	}
+	return fmt.Sprintf("SuprsendValidationError: error in data of workflow '%s' \n%v", e.Workflow, strings.Join(errList, "\n"))
+}
+
+type workflowSchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+func newWorkflowSchemaRegistry() *workflowSchemaRegistry {
+	return &workflowSchemaRegistry{schemas: map[string]*gojsonschema.Schema{}}
+}
+
+func (r *workflowSchemaRegistry) get(slug string) *gojsonschema.Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.schemas[slug]
+}
+
+/*
+RegisterWorkflowSchema registers jsonschema for `data` of workflow with given slug.
+All triggers (single as well as bulk) of this workflow are then validated against it before sending.
+Registering again for same slug replaces the earlier schema.
+*/
+func (c *Client) RegisterWorkflowSchema(slug string, jsonSchema []byte) error {
+	slug = strings.TrimSpace(slug)
+	if slug == "" {
+		return &Error{Message: "workflow slug missing"}
+	}
+	if len(jsonSchema) == 0 {
+		return &Error{Message: fmt.Sprintf("SuprsendMissingSchema: %s. %v", slug, "empty content")}
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(jsonSchema))
+	if err != nil {
+		return &Error{Message: fmt.Sprintf("SuprsendInvalidSchema: %s. %v", slug, err), Err: err}
+	}
+	c.workflowSchemas.mu.Lock()
+	defer c.workflowSchemas.mu.Unlock()
+	c.workflowSchemas.schemas[slug] = schema
+	return nil
+}
+
+/*
+LoadWorkflowSchemas registers every <slug>.json file present in dir of fsys (e.g embed.FS) as schema of workflow <slug>.
+Use "." as dir for root of fsys.
+*/
+func (c *Client) LoadWorkflowSchemas(fsys iofs.FS, dir string) error {
+	entries, err := iofs.ReadDir(fsys, dir)
+	if err != nil {
+		return &Error{Message: fmt.Sprintf("SuprsendMissingSchema: %s. %v", dir, err), Err: err}
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		content, err := iofs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return &Error{Message: fmt.Sprintf("SuprsendMissingSchema: %s. %v", entry.Name(), err), Err: err}
+		}
+		slug := strings.TrimSuffix(entry.Name(), ".json")
+		if err = c.RegisterWorkflowSchema(slug, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadWorkflowSchemasFromDir registers every <slug>.json file present in dir (on disk) as schema of workflow <slug>.
+func (c *Client) LoadWorkflowSchemasFromDir(dir string) error {
+	return c.LoadWorkflowSchemas(os.DirFS(dir), ".")
+}
+
+// validates data of workflow body against schema registered for its slug (if any)
+func (c *Client) validateWorkflowData(body map[string]any) error {
+	slug, _ := body["workflow"].(string)
+	schema := c.workflowSchemas.get(slug)
+	if schema == nil {
+		return nil
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(body["data"]))
+	if err != nil {
+		return &Error{Err: err}
+	}
+	if result.Valid() {
+		return nil
+	}
+	vErr := &WorkflowDataValidationError{Workflow: slug}
+	for _, re := range result.Errors() {
+		vErr.Errors = append(vErr.Errors, FieldError{
+			Field:   resultErrorField(re),
+			Type:    re.Type(),
+			Message: re.Description(),
+		})
+	}
+	return vErr
+}
+
+func resultErrorField(re gojsonschema.ResultError) string {
+	field := re.Field()
+	if re.Type() == "required" {
+		// field of required error is its parent, property is in details
+		if prop, ok := re.Details()["property"].(string); ok {
+			if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+				return prop
+			}
+			return field + "." + prop
+		}
+	}
+	return field
+}
@@ -0,0 +1,105 @@
+package suprsend
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["order"],
+	"properties": {
+		"order": {
+			"type": "object",
+			"required": ["id"],
+			"properties": {"id": {"type": "string"}, "amount": {"type": "number"}}
+		}
+	}
+}`
+
+func orderWorkflow(data map[string]any) *WorkflowTriggerRequest {
+	return &WorkflowTriggerRequest{Body: map[string]any{
+		"workflow": "order_placed", "recipients": []any{"u1"}, "data": data,
+	}}
+}
+
+func TestWorkflowSchemaValidation(t *testing.T) {
+	c := newTestClient(t, "http://localhost/")
+	if err := c.RegisterWorkflowSchema("order_placed", []byte(orderSchema)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		data       map[string]any
+		wantFields []string
+	}{
+		{"valid", map[string]any{"order": map[string]any{"id": "o1", "amount": 10}}, nil},
+		{"missing nested field", map[string]any{"order": map[string]any{"amount": 10}}, []string{"order.id"}},
+		{"missing top level field", map[string]any{}, []string{"order"}},
+		{"wrong type", map[string]any{"order": map[string]any{"id": 1, "amount": "ten"}}, []string{"order.amount", "order.id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := orderWorkflow(tt.data).getFinalJson(c, false)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var vErr *WorkflowDataValidationError
+			if !errors.As(err, &vErr) {
+				t.Fatalf("error = %v, want WorkflowDataValidationError", err)
+			}
+			fields := []string{}
+			for _, fe := range vErr.Errors {
+				fields = append(fields, fe.Field)
+			}
+			slices.Sort(fields)
+			if vErr.Workflow != "order_placed" || !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("error fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+	// workflows without a registered schema are not validated
+	other := orderWorkflow(map[string]any{})
+	other.Body["workflow"] = "other"
+	if _, _, _, err := other.getFinalJson(c, false); err != nil {
+		t.Errorf("unregistered workflow: %v", err)
+	}
+}
+
+func TestWorkflowSchemaInBulk(t *testing.T) {
+	ts := newTestServer(t, acceptAllBulkHandler)
+	c := newTestClient(t, ts.URL, WithValidation(ValidationOff))
+	c.RegisterWorkflowSchema("order_placed", []byte(orderSchema))
+	bulkIns := c.Workflows.BulkTriggerInstance()
+	bulkIns.Append(orderWorkflow(map[string]any{"order": map[string]any{"id": "o1"}}), orderWorkflow(map[string]any{}))
+	resp, _ := bulkIns.Trigger()
+	if resp.Success != 1 || resp.Failure != 1 || resp.Results[1].ErrorType != BULK_RECORD_ERROR_TYPE_VALIDATION {
+		t.Errorf("unexpected response %v", resp)
+	}
+}
+
+func TestLoadWorkflowSchemas(t *testing.T) {
+	c := newTestClient(t, "http://localhost/")
+	fsys := fstest.MapFS{
+		"schemas/order_placed.json": {Data: []byte(orderSchema)},
+		"schemas/README.md":         {Data: []byte("not a schema")},
+	}
+	if err := c.LoadWorkflowSchemas(fsys, "schemas"); err != nil {
+		t.Fatal(err)
+	}
+	if c.workflowSchemas.get("order_placed") == nil || c.workflowSchemas.get("README") != nil {
+		t.Error("only .json files must be registered, by file name")
+	}
+	fsys["schemas/broken.json"] = &fstest.MapFile{Data: []byte(`{"type": 1}`)}
+	if err := c.LoadWorkflowSchemas(fsys, "schemas"); err == nil {
+		t.Error("invalid schema must be reported")
+	}
+	if err := c.RegisterWorkflowSchema(" ", []byte(orderSchema)); err == nil {
+		t.Error("empty slug must be rejected")
+	}
+}